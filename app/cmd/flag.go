@@ -36,3 +36,8 @@ var ListenFlag = &cli.StringFlag{
 	Usage:   "监听地址，格式为 ip:port，例如 :8080",
 	Sources: cli.EnvVars("LISTEN"),
 }
+
+var DisableTotpFlag = &cli.BoolFlag{
+	Name:  "disable-totp",
+	Usage: "同时关闭全部账号的两步验证，认证器与恢复码均丢失时使用",
+}
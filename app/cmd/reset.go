@@ -15,7 +15,7 @@ import (
 var Reset = &cli.Command{
 	Name:  "reset",
 	Usage: "忘记密码时，此命令可重置账号密码登录入口",
-	Flags: []cli.Flag{SQLiteFlag, MySQLDSNFlag, PostgresDSNFlag},
+	Flags: []cli.Flag{SQLiteFlag, MySQLDSNFlag, PostgresDSNFlag, DisableTotpFlag},
 	Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
 		mysql := c.String("mysql")
 		postgres := c.String("postgres")
@@ -42,6 +42,22 @@ var Reset = &cli.Command{
 		model.SetK(model.AdminSecure, entrance)
 		model.SetK(model.AdminUsername, username)
 		model.SetK(model.AdminPassword, string(encrypt))
+		model.SetK(model.AdminAllowIps, "")
+		model.RevokeAllAdminSessions(0)
+		model.RevokeAllWebSessions()
+		model.ClearLoginGuards()
+		if err := model.ResetOwner(username, password); err != nil {
+
+			return fmt.Errorf("管理员账号重置失败 %w", err)
+		}
+		if cmd.Bool(DisableTotpFlag.Name) {
+			if err := model.DisableAllTotp(); err != nil {
+
+				return fmt.Errorf("两步验证关闭失败 %w", err)
+			}
+
+			fmt.Println("已关闭全部账号的两步验证")
+		}
 
 		fmt.Println("重置成功，对应信息如下：")
 		fmt.Printf("管理员账号：%s\n管理员密码：%s\n后台管理入口：%s\n", username, password, entrance)
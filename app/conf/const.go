@@ -24,6 +24,8 @@ const (
 	Arbitrum = "arbitrum"
 	Ethereum = "ethereum"
 	Base     = "base"
+	Bitcoin  = "bitcoin"
+	Litecoin = "litecoin"
 )
 
 const (
@@ -36,6 +38,7 @@ const (
 	UsdtArbitrum = "0xfd086bc7cd5c481dcc9c85ebe478a1c0b69fcbb9"
 	UsdtSolana   = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
 	SolSplToken  = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	SolSystem    = "11111111111111111111111111111111"
 	UsdtAptos    = "0x357b0b74bc833e95a115ad22604854d6b0fca151cecd94111770e5d6ffc9dc2b"
 	UsdcErc20    = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
 	UsdcPolygon  = "0x3c499c542cef5e3811e1192ce70d8cc03d5c3359"
@@ -45,6 +48,8 @@ const (
 	UsdcBase     = "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"
 	UsdcSolana   = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
 	UsdcAptos    = "0xbae207659db88bea0cbead6da0ed00aac12edcdda169e591cd41c94180b46f3b"
+	AptCoinType  = "0x1::aptos_coin::AptosCoin" // APT Coin 标准类型
+	AptFaAddress = "0xa"                        // APT 迁移至 Fungible Asset 后的 metadata 地址
 )
 
 const (
@@ -72,4 +77,13 @@ const (
 	TonTonDecimals      = -9  // TON Ton小数位数
 	BscBnbDecimals      = -18 // BSC BNB 小数位数
 	EthereumEthDecimals = -18 // Ethereum ETH 小数位数
+	ArbitrumEthDecimals = -18 // Arbitrum ETH 小数位数
+	BaseEthDecimals     = -18 // Base ETH 小数位数
+	PolygonPolDecimals  = -18 // Polygon POL 小数位数
+	XlayerOkbDecimals   = -18 // X Layer OKB 小数位数
+	PlasmaXplDecimals   = -18 // Plasma XPL 小数位数
+	SolanaSolDecimals   = -9  // Solana SOL 小数位数
+	AptosAptDecimals    = -8  // Aptos APT 小数位数
+	BitcoinBtcDecimals  = -8  // Bitcoin BTC 小数位数
+	LitecoinLtcDecimals = -8  // Litecoin LTC 小数位数
 )
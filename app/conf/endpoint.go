@@ -0,0 +1,161 @@
+package conf
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	endpointFailLimit = 3                // 连续失败次数达到此值后切换到下一个节点
+	endpointCooldown  = time.Second * 60 // 故障节点冷却时长，期间只通过后台探测恢复
+)
+
+type endpoint struct {
+	mu        sync.Mutex
+	fails     int // 连续失败次数
+	downAt    time.Time
+	latency   time.Duration
+	checkedAt time.Time
+	lastErr   string
+}
+
+type EndpointInfo struct {
+	Network   string `json:"network"`
+	Url       string `json:"url"`
+	Active    bool   `json:"active"`
+	Healthy   bool   `json:"healthy"`
+	Latency   int64  `json:"latency"` // 毫秒
+	Succ      string `json:"succ"`
+	Fails     int    `json:"fails"`
+	LastError string `json:"last_error"`
+	CheckedAt int64  `json:"checked_at"`
+}
+
+var (
+	endpoints      sync.Map // map[string]*endpoint
+	activeEndpoint sync.Map // map[network]url
+)
+
+func endpointKey(net, url string) string {
+
+	return net + "|" + url
+}
+
+func getEndpoint(net, url string) *endpoint {
+	val, _ := endpoints.LoadOrStore(endpointKey(net, url), &endpoint{})
+
+	return val.(*endpoint)
+}
+
+func (e *endpoint) healthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.fails < endpointFailLimit {
+		return true
+	}
+
+	return time.Since(e.downAt) > endpointCooldown
+}
+
+func (e *endpoint) mark(ok bool, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if ok {
+		e.fails = 0
+		e.lastErr = ""
+
+		return
+	}
+
+	e.fails++
+	if reason != "" {
+		e.lastErr = reason
+	}
+	if e.fails >= endpointFailLimit {
+		e.downAt = time.Now()
+	}
+}
+
+// PickEndpoint 按配置顺序选择首个健康节点；全部故障时选择故障最久的节点，避免扫描彻底停滞
+func PickEndpoint(net string, urls []string) string {
+	if len(urls) == 0 {
+		return ""
+	}
+
+	var pick = ""
+	var oldest time.Time
+	for _, url := range urls {
+		e := getEndpoint(net, url)
+		if e.healthy() {
+			pick = url
+
+			break
+		}
+
+		e.mu.Lock()
+		if oldest.IsZero() || e.downAt.Before(oldest) {
+			oldest = e.downAt
+			pick = url
+		}
+		e.mu.Unlock()
+	}
+
+	activeEndpoint.Store(net, pick)
+
+	return pick
+}
+
+// endpointRecord 扫描请求结果计入该请求实际使用的节点，而非记录时的当前节点，
+// 避免切换后仍在途的慢请求失败时误记到新节点上导致来回切换
+func endpointRecord(net, url string, ok bool) {
+	if url == "" {
+		return
+	}
+
+	getStat(endpointKey(net, url)).add(ok)
+	getEndpoint(net, url).mark(ok, "")
+}
+
+// ProbeEndpoint 记录后台探测结果
+func ProbeEndpoint(net, url string, latency time.Duration, err error) {
+	var e = getEndpoint(net, url)
+	var reason = ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	getStat(endpointKey(net, url)).add(err == nil)
+	e.mark(err == nil, reason)
+
+	e.mu.Lock()
+	e.latency = latency
+	e.checkedAt = time.Now()
+	e.mu.Unlock()
+}
+
+func GetEndpointInfo(net, url string) EndpointInfo {
+	var e = getEndpoint(net, url)
+	var active, _ = activeEndpoint.Load(net)
+	var healthy = e.healthy()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var info = EndpointInfo{
+		Network:   net,
+		Url:       url,
+		Active:    active == url,
+		Healthy:   healthy,
+		Latency:   e.latency.Milliseconds(),
+		Succ:      getStat(endpointKey(net, url)).rate(),
+		Fails:     e.fails,
+		LastError: e.lastErr,
+	}
+	if !e.checkedAt.IsZero() {
+		info.CheckedAt = e.checkedAt.Unix()
+	}
+
+	return info
+}
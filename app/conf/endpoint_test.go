@@ -0,0 +1,37 @@
+package conf
+
+import "testing"
+
+func TestEndpointRecordUsedUrl(t *testing.T) {
+	var net = "test-endpoint-record"
+	var urls = []string{"http://a", "http://b"}
+	t.Cleanup(func() {
+		for _, url := range urls {
+			endpoints.Delete(endpointKey(net, url))
+		}
+		activeEndpoint.Delete(net)
+	})
+
+	for i := 0; i < endpointFailLimit; i++ {
+		RecordFailure(net, PickEndpoint(net, urls))
+	}
+	if pick := PickEndpoint(net, urls); pick != "http://b" {
+		t.Fatalf("failover pick = %s, want http://b", pick)
+	}
+
+	// 切换前发出的慢请求失败，应计入旧节点而不是当前节点
+	for i := 0; i < endpointFailLimit; i++ {
+		RecordFailure(net, "http://a")
+	}
+	if !getEndpoint(net, "http://b").healthy() {
+		t.Fatal("in-flight failure of old endpoint was charged to the new one")
+	}
+	if pick := PickEndpoint(net, urls); pick != "http://b" {
+		t.Fatalf("pick after stale failures = %s, want http://b", pick)
+	}
+
+	RecordSuccess(net, "", "1") // 未指定节点时不计入节点统计
+	if getEndpoint(net, "http://a").healthy() {
+		t.Fatal("empty url should not reset endpoint state")
+	}
+}
@@ -1,6 +1,5 @@
 package conf
 
 const (
-	AdminTokenK  = "admin_token"
 	AdminSecureK = "admin_secure"
 )
@@ -34,35 +34,34 @@ func getStat(net string) *stat {
 	return val.(*stat)
 }
 
-func RecordSuccess(net, block string) {
+// RecordSuccess 记录扫描成功，url 为本次请求实际使用的节点（PickEndpoint 的返回值），为空时不计入节点统计
+func RecordSuccess(net, url, block string) {
 	last.Store(net, info{Block: block, Succ: GetSuccessRate(net), Time: time.Now().Unix()})
-	s := getStat(net)
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.total >= maxRecords && !s.records[s.index] {
-		s.succ++
-	} else if s.total < maxRecords {
-		s.succ++
-	}
+	getStat(net).add(true)
+	endpointRecord(net, url, true)
+}
 
-	s.records[s.index] = true
-	s.index = (s.index + 1) % maxRecords
-	if s.total < maxRecords {
-		s.total++
-	}
+// RecordFailure 记录扫描失败，url 为本次请求实际使用的节点
+func RecordFailure(net, url string) {
+	getStat(net).add(false)
+	endpointRecord(net, url, false)
 }
 
-func RecordFailure(net string) {
-	s := getStat(net)
+func (s *stat) add(ok bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.total >= maxRecords && s.records[s.index] {
+	if ok {
+		if s.total >= maxRecords && !s.records[s.index] {
+			s.succ++
+		} else if s.total < maxRecords {
+			s.succ++
+		}
+	} else if s.total >= maxRecords && s.records[s.index] {
 		s.succ--
 	}
 
-	s.records[s.index] = false
+	s.records[s.index] = ok
 	s.index = (s.index + 1) % maxRecords
 	if s.total < maxRecords {
 		s.total++
@@ -81,7 +80,11 @@ func GetStats() map[string]info {
 }
 
 func GetSuccessRate(net string) string {
-	s := getStat(net)
+
+	return getStat(net).rate()
+}
+
+func (s *stat) rate() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
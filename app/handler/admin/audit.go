@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+	"gorm.io/gorm"
+)
+
+const auditExportMax = 10000
+
+type Audit struct {
+}
+
+type aQuery struct {
+	Username string `json:"username"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	TargetId string `json:"target_id"`
+	StartAt  string `json:"start_at"` // 格式 2006-01-02 15:04:05
+	EndAt    string `json:"end_at"`
+}
+
+type aListReq struct {
+	base.ListRequest
+	aQuery
+}
+
+type aExportReq struct {
+	aQuery
+	Keyword string `json:"keyword"`
+}
+
+// List 审计日志检索，关键词匹配对象ID、标签与变更内容
+func (Audit) List(ctx *gin.Context) {
+	var req aListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	db, err := req.aQuery.build(req.Keyword)
+	if err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var data []model.Audit
+	var total int64
+
+	db.Model(&model.Audit{}).Count(&total)
+
+	err = db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+// Export 按检索条件导出 CSV，最多导出最近 10000 条
+func (Audit) Export(ctx *gin.Context) {
+	var req aExportReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	db, err := req.aQuery.build(req.Keyword)
+	if err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var data []model.Audit
+	if err = db.Order("id desc").Limit(auditExportMax).Find(&data).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	var buf = bytes.NewBufferString("\xEF\xBB\xBF") // BOM，Excel 直接打开不乱码
+	var w = csv.NewWriter(buf)
+
+	_ = w.Write([]string{"ID", "时间", "账号", "IP", "路由", "操作", "对象", "对象ID", "对象标签", "变更前", "变更后"})
+	for _, a := range data {
+		_ = w.Write([]string{
+			fmt.Sprint(a.ID), a.CreatedAt.Format(time.DateTime), a.Username, a.Ip, a.Route,
+			a.Action, a.Target, a.TargetId, a.Label, a.Before, a.After,
+		})
+	}
+
+	w.Flush()
+
+	var name = fmt.Sprintf("audit_%s.csv", time.Now().Format("20060102150405"))
+
+	ctx.Header("Content-Disposition", "attachment; filename="+name)
+	ctx.Data(200, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func (q aQuery) build(keyword string) (*gorm.DB, error) {
+	var db = model.Db
+
+	if q.Username != "" {
+		db = db.Where("username = ?", q.Username)
+	}
+	if q.Action != "" {
+		db = db.Where("action = ?", q.Action)
+	}
+	if q.Target != "" {
+		db = db.Where("target = ?", q.Target)
+	}
+	if q.TargetId != "" {
+		db = db.Where("target_id = ?", q.TargetId)
+	}
+	if keyword != "" {
+		var kw = "%" + keyword + "%"
+		db = db.Where("target_id LIKE ? OR label LIKE ? OR before_data LIKE ? OR after_data LIKE ?", kw, kw, kw, kw)
+	}
+	if q.StartAt != "" {
+		at, err := time.ParseInLocation(time.DateTime, q.StartAt, time.Local)
+		if err != nil {
+
+			return nil, fmt.Errorf("开始时间格式错误：%s", q.StartAt)
+		}
+
+		db = db.Where("created_at >= ?", at)
+	}
+	if q.EndAt != "" {
+		at, err := time.ParseInLocation(time.DateTime, q.EndAt, time.Local)
+		if err != nil {
+
+			return nil, fmt.Errorf("结束时间格式错误：%s", q.EndAt)
+		}
+
+		db = db.Where("created_at <= ?", at)
+	}
+
+	return db, nil
+}
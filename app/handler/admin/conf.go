@@ -27,6 +27,15 @@ type confGetsReq struct {
 
 type confSetsReq []confReq
 
+// totpConfKeys 通过通用配置接口修改时同样需要动态验证码的敏感配置
+var totpConfKeys = map[model.ConfKey]struct{}{
+	model.ApiAuthToken:    {},
+	model.NotifierChannel: {},
+	model.NotifierParams:  {},
+	model.AdminAllowIps:   {},
+	model.TrustedProxies:  {},
+}
+
 type notifierConf struct {
 	Channel string          `json:"channel" binding:"required"`
 	Params  json.RawMessage `json:"params" binding:"required"`
@@ -40,7 +49,19 @@ func (Conf) Set(ctx *gin.Context) {
 		return
 	}
 
-	model.SetK(model.ConfKey(strings.TrimSpace(req.Key)), strings.TrimSpace(req.Value))
+	if !checkConfTotp(ctx, req.Key) || !checkConfValue(ctx, req.Key, req.Value) {
+
+		return
+	}
+
+	var k = model.ConfKey(strings.TrimSpace(req.Key))
+	var v = strings.TrimSpace(req.Value)
+	var old = model.GetK(k)
+
+	model.SetK(k, v)
+	if old != v {
+		base.Audit(ctx, model.AuditActionSet, model.AuditTargetConf, k, gin.H{"value": old}, gin.H{"value": v})
+	}
 
 	defer model.RefreshC()
 
@@ -66,7 +87,15 @@ func (Conf) Del(ctx *gin.Context) {
 		return
 	}
 
+	if !checkConfTotp(ctx, req.Key) {
+
+		return
+	}
+
+	var old = model.GetK(model.ConfKey(req.Key))
+
 	model.Db.Where("k = ?", req.Key).Delete(&model.Conf{})
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetConf, req.Key, gin.H{"value": old}, nil)
 
 	base.Ok(ctx, "删除成功")
 }
@@ -99,16 +128,39 @@ func (Conf) Sets(ctx *gin.Context) {
 	}
 
 	keys := make([]string, 0)
+	changed := make([]string, 0)
+	olds := make(map[string]string)
 	data := make([]model.Conf, 0)
 	for _, item := range req {
 		var k = strings.TrimSpace(item.Key)
 		var v = strings.TrimSpace(item.Value)
 		keys = append(keys, k)
 		data = append(data, model.Conf{K: model.ConfKey(k), V: v})
+		if old := model.GetK(model.ConfKey(k)); old != v {
+			changed = append(changed, k)
+			olds[k] = old
+		}
+	}
+
+	// 表单整体提交时，未改动的敏感配置不要求动态验证码
+	if !checkConfTotp(ctx, changed...) {
+
+		return
+	}
+	for _, item := range data {
+		if !checkConfValue(ctx, string(item.K), item.V) {
+
+			return
+		}
 	}
 
 	model.Db.Where("k IN ?", keys).Delete(&model.Conf{})
 	model.Db.Create(&data)
+	for _, item := range data {
+		if old, ok := olds[string(item.K)]; ok {
+			base.Audit(ctx, model.AuditActionSet, model.AuditTargetConf, item.K, gin.H{"value": old}, gin.H{"value": item.V})
+		}
+	}
 
 	defer model.RefreshC()
 
@@ -129,6 +181,8 @@ func (Conf) Rpc(ctx *gin.Context) {
 		model.RpcEndpointTron,
 		model.RpcEndpointTronGridApiKey,
 		model.RpcGlobalConfigUrlTon,
+		model.RpcEndpointBitcoin,
+		model.RpcEndpointLitecoin,
 	}
 
 	var rpc = make(map[model.ConfKey]string)
@@ -138,9 +192,17 @@ func (Conf) Rpc(ctx *gin.Context) {
 		rpc[item.K] = item.V
 	}
 
+	var endpoints = make([]conf.EndpointInfo, 0)
+	for _, net := range model.GetEndpointNetworks() {
+		for _, url := range model.Endpoints(net) {
+			endpoints = append(endpoints, conf.GetEndpointInfo(string(net), url))
+		}
+	}
+
 	base.Ok(ctx, gin.H{
-		"rpc":   rpc,
-		"stats": conf.GetStats(),
+		"rpc":       rpc,
+		"stats":     conf.GetStats(),
+		"endpoints": endpoints,
 	})
 }
 
@@ -152,12 +214,21 @@ func (Conf) Notifier(ctx *gin.Context) {
 		return
 	}
 
+	var before = gin.H{
+		string(model.NotifierChannel): model.GetK(model.NotifierChannel),
+		string(model.NotifierParams):  model.GetK(model.NotifierParams),
+	}
+
 	var keys = []string{string(model.NotifierChannel), string(model.NotifierParams)}
 	model.Db.Where("k IN ?", keys).Delete(&model.Conf{})
 	model.Db.Create(&[]model.Conf{
 		{K: model.NotifierChannel, V: req.Channel},
 		{K: model.NotifierParams, V: string(req.Params)},
 	})
+	base.Audit(ctx, model.AuditActionSet, model.AuditTargetConf, "notifier", before, gin.H{
+		string(model.NotifierChannel): req.Channel,
+		string(model.NotifierParams):  string(req.Params),
+	})
 
 	base.Ok(ctx, "配置成功")
 }
@@ -178,7 +249,40 @@ func (Conf) CheckoutList(ctx *gin.Context) {
 }
 
 func (Conf) ResetApiAuthToken(ctx *gin.Context) {
-	model.SetK(model.ApiAuthToken, strings.ToUpper(utils.Md5String(utils.StrSha256(time.Now().String()))))
+	var old = model.GetK(model.ApiAuthToken)
+	var token = strings.ToUpper(utils.Md5String(utils.StrSha256(time.Now().String())))
+
+	model.SetK(model.ApiAuthToken, token)
+	base.Audit(ctx, model.AuditActionReset, model.AuditTargetConf, model.ApiAuthToken, gin.H{"value": old}, gin.H{"value": token})
 
 	base.Ok(ctx, "重置成功")
 }
+
+// checkConfValue 保存前校验个别配置项，避免写入后导致后台无法访问
+func checkConfValue(ctx *gin.Context, key, value string) bool {
+	var err error
+	switch model.ConfKey(strings.TrimSpace(key)) {
+	case model.AdminAllowIps:
+		err = model.CheckAdminAllowIps(strings.TrimSpace(value), ctx.ClientIP())
+	case model.TrustedProxies:
+		_, err = utils.ParseIpAllowList(strings.TrimSpace(value))
+	}
+	if err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return false
+	}
+
+	return true
+}
+
+func checkConfTotp(ctx *gin.Context, keys ...string) bool {
+	for _, k := range keys {
+		if _, ok := totpConfKeys[model.ConfKey(strings.TrimSpace(k))]; ok {
+
+			return base.CheckTotp(ctx)
+		}
+	}
+
+	return true
+}
@@ -17,12 +17,13 @@ type Dashboard struct {
 }
 
 type homeReq struct {
-	Fiat  string `json:"fiat" binding:"required"`
-	Range string `json:"range"`
-	TZ    string `json:"tz"`
-	From  string `json:"from"`
-	To    string `json:"to"`
-	Force bool   `json:"force"`
+	Fiat       string `json:"fiat" binding:"required"`
+	MerchantId *int64 `json:"merchant_id"` // 为空时统计全部商户
+	Range      string `json:"range"`
+	TZ         string `json:"tz"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Force      bool   `json:"force"`
 }
 
 type dashboardPoint struct {
@@ -49,7 +50,7 @@ func (Dashboard) Home(ctx *gin.Context) {
 		return
 	}
 
-	cacheKey := dashboardCacheKey(req.Fiat, rangeKey, loc.String(), from, to)
+	cacheKey := dashboardCacheKey(req.Fiat, req.merchantScope(), rangeKey, loc.String(), from, to)
 	if !req.Force {
 		if data, ok := cache.Get(cacheKey); ok {
 			base.Ok(ctx, data)
@@ -65,7 +66,12 @@ func (Dashboard) Home(ctx *gin.Context) {
 
 func buildDashboardHome(req homeReq, rangeKey string, from, to time.Time, loc *time.Location) gin.H {
 	var rows = make([]model.Order, 0)
-	model.Db.Where("fiat = ? and created_at >= ? and created_at <= ?", req.Fiat, from, to).Find(&rows)
+	var db = model.Db.Where("fiat = ? and created_at >= ? and created_at <= ?", req.Fiat, from, to)
+	if req.MerchantId != nil {
+		db = db.Where("merchant_id = ?", *req.MerchantId)
+	}
+
+	db.Find(&rows)
 
 	var totalCount, pendingCount, confirmingCount, successCount, expiredCount, failedCount, notifyFailedCount int64
 	gmvPaid := decimal.Zero
@@ -75,6 +81,13 @@ func buildDashboardHome(req homeReq, rangeKey string, from, to time.Time, loc *t
 		model.TRX:  0,
 		model.BNB:  0,
 		model.ETH:  0,
+		model.POL:  0,
+		model.OKB:  0,
+		model.XPL:  0,
+		model.SOL:  0,
+		model.APT:  0,
+		model.BTC:  0,
+		model.LTC:  0,
 	}
 	points := make([]dashboardPoint, 0)
 	pointMap := make(map[string]int)
@@ -110,7 +123,7 @@ func buildDashboardHome(req homeReq, rangeKey string, from, to time.Time, loc *t
 			confirmingCount++
 		case model.OrderStatusExpired:
 			expiredCount++
-		case model.OrderStatusSuccess:
+		case model.OrderStatusSuccess, model.OrderStatusUnderpaid, model.OrderStatusOverpaid:
 			successCount++
 			point.OrdersSuccess++
 			point.OrdersPaid++
@@ -172,8 +185,16 @@ func buildDashboardHome(req homeReq, rangeKey string, from, to time.Time, loc *t
 	}
 }
 
-func dashboardCacheKey(fiat, rangeKey, timezone string, from, to time.Time) string {
-	return fmt.Sprintf("dashboard:home:v1:%s:%s:%s:%d:%d", fiat, rangeKey, timezone, from.Unix(), to.Unix())
+func dashboardCacheKey(fiat, merchant, rangeKey, timezone string, from, to time.Time) string {
+	return fmt.Sprintf("dashboard:home:v1:%s:%s:%s:%s:%d:%d", fiat, merchant, rangeKey, timezone, from.Unix(), to.Unix())
+}
+
+func (r homeReq) merchantScope() string {
+	if r.MerchantId == nil {
+		return "all"
+	}
+
+	return cast.ToString(*r.MerchantId)
 }
 
 func dashboardCacheTTL(rangeKey string, to time.Time, loc *time.Location) time.Duration {
@@ -0,0 +1,225 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type Merchant struct {
+}
+
+type mAddReq struct {
+	Name       string     `json:"name" binding:"required"`
+	Pid        string     `json:"pid" binding:"required"`
+	Secret     string     `json:"secret"`
+	Fiat       model.Fiat `json:"fiat"`
+	TradeTypes string     `json:"trade_types"`
+	Remark     string     `json:"remark"`
+}
+
+type mModReq struct {
+	base.IDRequest
+	Name       *string     `json:"name"`
+	Fiat       *model.Fiat `json:"fiat"`
+	TradeTypes *string     `json:"trade_types"`
+	Status     *uint8      `json:"status"`
+	Remark     *string     `json:"remark"`
+}
+
+type mListReq struct {
+	base.ListRequest
+	Name string `json:"name"`
+	Pid  string `json:"pid"`
+}
+
+func (Merchant) Add(ctx *gin.Context) {
+	var req mAddReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var m = model.Merchant{
+		Name:       req.Name,
+		Pid:        req.Pid,
+		Secret:     strings.TrimSpace(req.Secret),
+		Fiat:       req.Fiat,
+		TradeTypes: req.TradeTypes,
+		Remark:     req.Remark,
+		Status:     model.MerchantStatusEnable,
+	}
+	if m.Fiat == "" {
+		m.Fiat = model.CNY
+	}
+
+	if err := m.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var count int64
+	model.Db.Model(&model.Merchant{}).Where("pid = ?", m.Pid).Count(&count)
+	if count > 0 {
+		base.BadRequest(ctx, "商户号已存在")
+
+		return
+	}
+
+	if err := model.Db.Create(&m).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionAdd, model.AuditTargetMerchant, m.ID, nil, m)
+	base.Response(ctx, 200, m)
+}
+
+func (Merchant) List(ctx *gin.Context) {
+	var req mListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.Merchant
+	var db = model.Db
+
+	if req.Name != "" {
+		db = db.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+	if req.Pid != "" {
+		db = db.Where("pid = ?", req.Pid)
+	}
+
+	var total int64
+
+	db.Model(&model.Merchant{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+func (Merchant) Mod(ctx *gin.Context) {
+	var req mModReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var m model.Merchant
+	model.Db.Where("id = ?", req.ID).Find(&m)
+	if m.ID == 0 {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
+	var before = m
+	if req.Name != nil {
+		m.Name = *req.Name
+	}
+	if req.Fiat != nil {
+		m.Fiat = *req.Fiat
+	}
+	if req.TradeTypes != nil {
+		m.TradeTypes = *req.TradeTypes
+	}
+	if req.Status != nil {
+		m.Status = *req.Status
+	}
+	if req.Remark != nil {
+		m.Remark = *req.Remark
+	}
+
+	if err := m.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if err := model.Db.Save(&m).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetMerchant, m.ID, before, m)
+	base.Response(ctx, 200, "修改成功")
+}
+
+// ResetSecret 重置商户签名密钥，旧密钥立即失效
+func (Merchant) ResetSecret(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var m model.Merchant
+	model.Db.Where("id = ?", req.ID).Find(&m)
+	if m.ID == 0 {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
+	var before = m
+
+	m.Secret = model.NewMerchantSecret()
+	if err := model.Db.Save(&m).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionReset, model.AuditTargetMerchant, m.ID, before, m)
+
+	base.Response(ctx, 200, gin.H{"secret": m.Secret})
+}
+
+// Del 仍有订单或收款地址的商户只能停用，避免历史订单回调无法签名
+func (Merchant) Del(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var m model.Merchant
+	model.Db.Where("id = ?", req.ID).Find(&m)
+	if m.ID == 0 {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
+	var orders, wallets int64
+	model.Db.Model(&model.Order{}).Where("merchant_id = ?", m.ID).Count(&orders)
+	model.Db.Model(&model.Wallet{}).Where("merchant_id = ?", m.ID).Count(&wallets)
+	if orders > 0 || wallets > 0 {
+		base.BadRequest(ctx, "该商户下仍有订单或钱包，请改为停用")
+
+		return
+	}
+
+	model.Db.Delete(&m)
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetMerchant, m.ID, m, nil)
+
+	base.Response(ctx, 200, "删除成功")
+}
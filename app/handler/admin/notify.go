@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type Notify struct {
+}
+
+type nListReq struct {
+	base.ListRequest
+	State   *uint8 `json:"state"`
+	TradeId string `json:"trade_id"`
+	Kind    string `json:"kind"`
+}
+
+// List 回调发件箱列表，默认按死信筛选时用于人工处理
+func (Notify) List(ctx *gin.Context) {
+	var req nListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.NotifyOutbox
+	var db = model.Db
+
+	if req.State != nil {
+		db = db.Where("state = ?", *req.State)
+	}
+	if req.TradeId != "" {
+		db = db.Where("trade_id = ?", req.TradeId)
+	}
+	if req.Kind != "" {
+		db = db.Where("kind = ?", req.Kind)
+	}
+
+	var total int64
+
+	db.Model(&model.NotifyOutbox{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+// Redrive 死信重新投递
+func (Notify) Redrive(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	e, ok := model.GetNotifyOutbox(int64(req.ID))
+	if !ok {
+		base.BadRequest(ctx, "回调事件不存在")
+
+		return
+	}
+
+	var before = e
+	if err := e.Redrive(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionRedrive, model.AuditTargetNotify, e.ID, before, e)
+
+	base.Ok(ctx, "已重新加入投递队列")
+}
+
+// Del 删除不再需要投递的事件，投递中的事件不允许删除
+func (Notify) Del(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	e, ok := model.GetNotifyOutbox(int64(req.ID))
+	if !ok {
+		base.BadRequest(ctx, "回调事件不存在")
+
+		return
+	}
+
+	if e.State == model.OutboxStatePending {
+		base.BadRequest(ctx, "事件等待投递中，无法删除")
+
+		return
+	}
+
+	if err := model.Db.Delete(&e).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetNotify, e.ID, e, nil)
+
+	base.Ok(ctx, "删除成功")
+}
@@ -1,8 +1,6 @@
 package admin
 
 import (
-	"time"
-
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/v03413/bepusdt/app/handler/base"
@@ -16,16 +14,17 @@ type Order struct {
 
 type oListReq struct {
 	base.ListRequest
-	Name      string `json:"name"`
-	Money     string `json:"money"`
-	Amount    string `json:"amount"`
-	OrderId   string `json:"order_id"`
-	TradeId   string `json:"trade_id"`
-	Status    *uint8 `json:"status"`
-	Address   string `json:"address"`
-	TradeType string `json:"trade_type"`
-	StartAt   string `json:"start_at"`
-	EndAt     string `json:"end_at"`
+	MerchantId *int64 `json:"merchant_id"`
+	Name       string `json:"name"`
+	Money      string `json:"money"`
+	Amount     string `json:"amount"`
+	OrderId    string `json:"order_id"`
+	TradeId    string `json:"trade_id"`
+	Status     *uint8 `json:"status"`
+	Address    string `json:"address"`
+	TradeType  string `json:"trade_type"`
+	StartAt    string `json:"start_at"`
+	EndAt      string `json:"end_at"`
 }
 
 type paidReq struct {
@@ -34,6 +33,7 @@ type paidReq struct {
 }
 
 type createReq struct {
+	MerchantId int64      `json:"merchant_id"`
 	Amount     float64    `json:"amount" binding:"required"`
 	OrderID    string     `json:"order_id" binding:"required"`
 	Name       string     `json:"name"`
@@ -50,18 +50,26 @@ func (Order) Create(ctx *gin.Context) {
 		return
 	}
 
+	if !model.MerchantExists(req.MerchantId) {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
+	merchant := model.GetMerchant(req.MerchantId)
 	host := utils.GetRequestHost(ctx.Request)
 
 	tradeTypeReselect := model.OrderTradeTypeReselectEnabled()
 
 	// 创建待付款订单
 	order, err := model.BuildPendingOrder(model.OrderParams{
+		MerchantId:        req.MerchantId,
 		Money:             decimal.NewFromFloat(req.Amount),
 		ApiType:           model.OrderApiTypeAdmin, // 使用 Admin 类型
 		OrderId:           req.OrderID,
 		Name:              req.Name,
 		Timeout:           req.Timeout,
-		Fiat:              req.Fiat,
+		Fiat:              merchant.DefaultFiat(req.Fiat),
 		CurrencyLimit:     req.Currencies,
 		TradeTypeReselect: tradeTypeReselect,
 	})
@@ -87,7 +95,7 @@ func (Order) Create(ctx *gin.Context) {
 		order.TradeTypeReselect = tradeTypeReselect
 	}
 
-	if err := model.Db.Save(&order).Error; err != nil {
+	if err := model.Db.Model(&order).Select("notify_url", "return_url", "trade_type_reselect").Updates(&order).Error; err != nil {
 		base.Response(ctx, 400, gin.H{
 			"status":  "failed",
 			"message": "failed to update order urls: " + err.Error(),
@@ -119,6 +127,9 @@ func (Order) List(ctx *gin.Context) {
 	var data []order
 	var db = model.Db
 
+	if req.MerchantId != nil {
+		db = db.Where("merchant_id = ?", *req.MerchantId)
+	}
 	if req.Name != "" {
 		db = db.Where("name LIKE ?", "%"+req.Name+"%")
 	}
@@ -174,7 +185,9 @@ func (Order) Detail(ctx *gin.Context) {
 
 	type detail struct {
 		model.Order
-		TxUrl string `gorm:"-" json:"tx_url"`
+		TxUrl    string               `gorm:"-" json:"tx_url"`
+		Payments []model.OrderPayment `gorm:"-" json:"payments"`
+		Events   []model.OrderEvent   `gorm:"-" json:"events"`
 	}
 
 	var o model.Order
@@ -186,8 +199,10 @@ func (Order) Detail(ctx *gin.Context) {
 	}
 
 	base.Ok(ctx, detail{
-		Order: o,
-		TxUrl: o.GetTxUrl(),
+		Order:    o,
+		TxUrl:    o.GetTxUrl(),
+		Payments: o.GetPayments(),
+		Events:   o.GetEvents(),
 	})
 }
 
@@ -213,25 +228,14 @@ func (Order) Paid(ctx *gin.Context) {
 		return
 	}
 
-	confirmedAt := time.Now()
-	var update = map[string]interface{}{
-		"ref_hash":     req.RefHash,
-		"status":       model.OrderStatusSuccess,
-		"confirmed_at": model.Datetime(confirmedAt),
-	}
-
-	err := model.Db.Model(&order).Updates(update).Error
-	if err != nil {
-		base.Error(ctx, err)
+	var before = order
+	if err := order.SetManualPaid(base.Operator(ctx), req.RefHash); err != nil {
+		base.BadRequest(ctx, err.Error())
 
 		return
 	}
 
-	order.RefHash = req.RefHash
-	order.Status = model.OrderStatusSuccess
-	order.ConfirmedAt = &confirmedAt
-
-	go notify.Handle(order)
+	base.Audit(ctx, model.AuditActionPaid, model.AuditTargetOrder, order.TradeId, before, order)
 
 	base.Ok(ctx, "操作成功")
 }
@@ -252,13 +256,15 @@ func (Order) ManualNotify(ctx *gin.Context) {
 		return
 	}
 
-	if order.Status != model.OrderStatusSuccess {
+	if !order.IsPaid() {
 		base.BadRequest(ctx, "订单状态不是交易成功,无法手动回调")
 
 		return
 	}
 
-	if err := notify.Handle(order); err != nil {
+	err := notify.Handle(order)
+	base.Audit(ctx, model.AuditActionNotify, model.AuditTargetOrder, order.TradeId, nil, gin.H{"notify_url": order.NotifyUrl, "result": auditResult(err)})
+	if err != nil {
 		base.BadRequest(ctx, err.Error())
 
 		return
@@ -267,6 +273,46 @@ func (Order) ManualNotify(ctx *gin.Context) {
 	base.Ok(ctx, "订单回调成功！")
 }
 
+func (Order) NotifyAttempts(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var o model.Order
+	model.Db.Where("id = ?", req.ID).Find(&o)
+	if o.ID == 0 {
+		base.BadRequest(ctx, "订单不存在")
+
+		return
+	}
+
+	base.Ok(ctx, o.GetNotifyAttempts())
+}
+
+// NotifyReplay 按投递记录原样重新发送一次回调，请求参数 id 为投递记录ID
+func (Order) NotifyReplay(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	a, err := notify.Replay(int64(req.ID))
+	if err != nil && a.ID == 0 {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionNotify, model.AuditTargetOrder, a.TradeId, nil, gin.H{"replay_of": req.ID, "notify_url": a.Url, "result": auditResult(err)})
+
+	base.Ok(ctx, a)
+}
+
 func (Order) Cancel(ctx *gin.Context) {
 	var req base.IDRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -289,12 +335,15 @@ func (Order) Cancel(ctx *gin.Context) {
 		return
 	}
 
-	if err := order.SetCanceled(); err != nil {
+	var before = order
+	if err := order.SetCanceled(base.Operator(ctx)); err != nil {
 		base.Error(ctx, err)
 
 		return
 	}
 
+	base.Audit(ctx, model.AuditActionCancel, model.AuditTargetOrder, order.TradeId, before, order)
+
 	base.Ok(ctx, "订单取消成功")
 }
 
@@ -306,6 +355,9 @@ func (Order) Del(ctx *gin.Context) {
 		return
 	}
 
+	var orders = make([]model.Order, 0)
+	model.Db.Where("id IN ?", req.IDList).Find(&orders)
+
 	err := model.Db.Delete(&model.Order{}, req.IDList).Error
 	if err != nil {
 		base.Error(ctx, err)
@@ -313,5 +365,18 @@ func (Order) Del(ctx *gin.Context) {
 		return
 	}
 
+	for _, o := range orders {
+		base.Audit(ctx, model.AuditActionDel, model.AuditTargetOrder, o.TradeId, o, nil)
+	}
+
 	base.Ok(ctx, "删除成功")
 }
+
+func auditResult(err error) string {
+	if err != nil {
+
+		return err.Error()
+	}
+
+	return "success"
+}
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type Session struct {
+}
+
+type sessionItem struct {
+	model.AdminSession
+	Username string `json:"username"`
+	Current  bool   `json:"current"`
+}
+
+// List 当前有效的后台登录会话
+func (Session) List(ctx *gin.Context) {
+	var current = base.CurrentSessionId(ctx)
+	var users = make(map[int64]string)
+	var data = make([]sessionItem, 0)
+	for _, s := range model.GetActiveAdminSessions() {
+		if _, ok := users[s.UserId]; !ok {
+			u, _ := model.GetUser(s.UserId)
+			users[s.UserId] = u.Username
+		}
+
+		data = append(data, sessionItem{AdminSession: s, Username: users[s.UserId], Current: s.ID == current})
+	}
+
+	base.Response(ctx, 200, data, int64(len(data)))
+}
+
+// Revoke 注销指定会话，对应设备需重新登录
+func (Session) Revoke(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if int64(req.ID) == base.CurrentSessionId(ctx) {
+		base.BadRequest(ctx, "不能注销当前会话，请使用退出登录")
+
+		return
+	}
+
+	var sess model.AdminSession
+	model.Db.Where("id = ?", req.ID).Limit(1).Find(&sess)
+	if model.RevokeAdminSession(int64(req.ID)) == 0 {
+		base.BadRequest(ctx, "会话不存在或已失效")
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionRevoke, model.AuditTargetSession, req.ID, sess, nil)
+	base.Ok(ctx, "注销成功")
+}
+
+// RevokeAll 注销除当前会话外的全部登录会话
+func (Session) RevokeAll(ctx *gin.Context) {
+	var num = model.RevokeAllAdminSessions(base.CurrentSessionId(ctx))
+
+	base.Audit(ctx, model.AuditActionRevoke, model.AuditTargetSession, "all", nil, gin.H{"revoked": num})
+
+	base.Ok(ctx, fmt.Sprintf("已注销 %d 个会话", num))
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type Token struct {
+}
+
+type tAddReq struct {
+	Network     string `json:"network" binding:"required"`
+	Contract    string `json:"contract" binding:"required"`
+	Crypto      string `json:"crypto" binding:"required"`
+	Decimals    int32  `json:"decimals"`
+	CoinId      string `json:"coin_id"`
+	ExplorerFmt string `json:"explorer_fmt"`
+	MinAmount   string `json:"min_amount"`
+}
+
+type tModReq struct {
+	base.IDRequest
+	Status      *uint8  `json:"status"`
+	Decimals    *int32  `json:"decimals"`
+	CoinId      *string `json:"coin_id"`
+	ExplorerFmt *string `json:"explorer_fmt"`
+	MinAmount   *string `json:"min_amount"`
+}
+
+type tListReq struct {
+	base.ListRequest
+	Network string `json:"network"`
+	Crypto  string `json:"crypto"`
+}
+
+func (Token) Add(ctx *gin.Context) {
+	var req tAddReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var token = model.Token{
+		Network:     req.Network,
+		Contract:    req.Contract,
+		Crypto:      req.Crypto,
+		Decimals:    req.Decimals,
+		CoinId:      req.CoinId,
+		ExplorerFmt: req.ExplorerFmt,
+		MinAmount:   req.MinAmount,
+		Status:      model.TokenStatusEnable,
+	}
+
+	if err := token.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if err := model.Db.Create(&token).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionAdd, model.AuditTargetToken, token.ID, nil, token)
+
+	model.RefreshTokens()
+
+	base.Response(ctx, 200, "success")
+}
+
+func (Token) List(ctx *gin.Context) {
+	var req tListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.Token
+	var db = model.Db
+
+	if req.Network != "" {
+		db = db.Where("network = ?", req.Network)
+	}
+	if req.Crypto != "" {
+		db = db.Where("crypto LIKE ?", "%"+req.Crypto+"%")
+	}
+
+	var total int64
+
+	db.Model(&model.Token{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+func (Token) Mod(ctx *gin.Context) {
+	var req tModReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var t model.Token
+	model.Db.Where("id = ?", req.ID).Find(&t)
+	if t.ID == 0 {
+		base.BadRequest(ctx, "代币不存在")
+
+		return
+	}
+
+	var before = t
+	if req.Status != nil {
+		t.Status = *req.Status
+	}
+	if req.Decimals != nil {
+		t.Decimals = *req.Decimals
+	}
+	if req.CoinId != nil {
+		t.CoinId = *req.CoinId
+	}
+	if req.ExplorerFmt != nil {
+		t.ExplorerFmt = *req.ExplorerFmt
+	}
+	if req.MinAmount != nil {
+		t.MinAmount = *req.MinAmount
+	}
+
+	if err := t.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if err := model.Db.Save(&t).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetToken, t.ID, before, t)
+
+	model.RefreshTokens()
+
+	base.Response(ctx, 200, "修改成功")
+}
+
+func (Token) Del(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var t model.Token
+	model.Db.Where("id = ?", req.ID).Find(&t)
+	if t.ID == 0 {
+		base.BadRequest(ctx, "代币不存在")
+
+		return
+	}
+
+	var count int64
+	model.Db.Model(&model.Wallet{}).Where("trade_type = ?", t.TradeType).Count(&count)
+	if count > 0 {
+		base.BadRequest(ctx, "该代币仍有钱包在使用，请先删除相关钱包")
+
+		return
+	}
+
+	model.Db.Delete(&t)
+	model.RefreshTokens()
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetToken, t.ID, t, nil)
+
+	base.Response(ctx, 200, "删除成功")
+}
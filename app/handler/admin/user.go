@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type User struct {
+}
+
+type uAddReq struct {
+	Username string     `json:"username" binding:"required"`
+	Password string     `json:"password" binding:"required"`
+	Role     model.Role `json:"role" binding:"required"`
+	Remark   string     `json:"remark"`
+}
+
+type uModReq struct {
+	base.IDRequest
+	Role     *model.Role `json:"role"`
+	Status   *uint8      `json:"status"`
+	Password *string     `json:"password"`
+	Remark   *string     `json:"remark"`
+}
+
+type uListReq struct {
+	base.ListRequest
+	Username string     `json:"username"`
+	Role     model.Role `json:"role"`
+}
+
+func (User) Add(ctx *gin.Context) {
+	var req uAddReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var u = model.User{Username: req.Username, Role: req.Role, Remark: req.Remark, Status: model.UserStatusEnable}
+	if err := u.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+	if err := u.SetPassword(req.Password); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if _, ok := model.GetUserByName(u.Username); ok {
+		base.BadRequest(ctx, "登录账号已存在")
+
+		return
+	}
+
+	if err := model.Db.Create(&u).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionAdd, model.AuditTargetUser, u.ID, nil, userAudit(u))
+
+	base.Response(ctx, 200, u)
+}
+
+func (User) List(ctx *gin.Context) {
+	var req uListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.User
+	var db = model.Db
+
+	if req.Username != "" {
+		db = db.Where("username LIKE ?", "%"+req.Username+"%")
+	}
+	if req.Role != "" {
+		db = db.Where("role = ?", req.Role)
+	}
+
+	var total int64
+
+	db.Model(&model.User{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+// Mod 修改角色、状态或重置密码；重置密码或停用后该账号的登录会话立即失效
+func (User) Mod(ctx *gin.Context) {
+	var req uModReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	u, ok := model.GetUser(int64(req.ID))
+	if !ok {
+		base.BadRequest(ctx, "账号不存在")
+
+		return
+	}
+
+	var before = userAudit(u)
+	var wasOwner = u.Role == model.RoleOwner && u.Status == model.UserStatusEnable
+	if req.Role != nil {
+		u.Role = *req.Role
+	}
+	if req.Status != nil {
+		u.Status = *req.Status
+	}
+	if req.Remark != nil {
+		u.Remark = *req.Remark
+	}
+	if req.Password != nil && *req.Password != "" {
+		if err := u.SetPassword(*req.Password); err != nil {
+			base.BadRequest(ctx, err.Error())
+
+			return
+		}
+	}
+
+	if err := u.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if wasOwner && (u.Role != model.RoleOwner || u.Status != model.UserStatusEnable) && model.CountOwners() <= 1 {
+		base.BadRequest(ctx, "至少需要保留一个启用的所有者账号")
+
+		return
+	}
+
+	if err := model.Db.Save(&u).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetUser, u.ID, before, userAudit(u))
+	base.Ok(ctx, "修改成功")
+}
+
+func (User) Del(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	u, ok := model.GetUser(int64(req.ID))
+	if !ok {
+		base.BadRequest(ctx, "账号不存在")
+
+		return
+	}
+
+	if cur, ok := base.CurrentUser(ctx); ok && cur.ID == u.ID {
+		base.BadRequest(ctx, "不能删除当前登录的账号")
+
+		return
+	}
+
+	if u.Role == model.RoleOwner && u.Status == model.UserStatusEnable && model.CountOwners() <= 1 {
+		base.BadRequest(ctx, "至少需要保留一个启用的所有者账号")
+
+		return
+	}
+
+	if err := model.Db.Delete(&u).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetUser, u.ID, userAudit(u), nil)
+
+	base.Ok(ctx, "删除成功")
+}
+
+// userAudit 账号审计快照，密码摘要仅用于判断是否修改，写入时会被脱敏
+func userAudit(u model.User) gin.H {
+
+	return gin.H{
+		"username": u.Username,
+		"role":     u.Role,
+		"status":   u.Status,
+		"remark":   u.Remark,
+		"password": u.Password,
+	}
+}
@@ -13,6 +13,7 @@ type Wallet struct {
 }
 
 type wAddReq struct {
+	MerchantId  int64  `json:"merchant_id"`
 	Name        string `json:"name"`
 	Remark      string `json:"remark"`
 	Address     string `json:"address" binding:"required"`
@@ -22,6 +23,7 @@ type wAddReq struct {
 
 type wModReq struct {
 	base.IDRequest
+	MerchantId  *int64  `json:"merchant_id"`
 	Name        *string `json:"name"`
 	Status      *uint8  `json:"status"`
 	Address     *string `json:"address"`
@@ -32,9 +34,10 @@ type wModReq struct {
 
 type wListReq struct {
 	base.ListRequest
-	Name    string `json:"name"`
-	Address string `json:"address"`
-	Trade   string `json:"trade_type"`
+	MerchantId *int64 `json:"merchant_id"`
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	Trade      string `json:"trade_type"`
 }
 
 func (Wallet) Add(ctx *gin.Context) {
@@ -51,7 +54,14 @@ func (Wallet) Add(ctx *gin.Context) {
 		return
 	}
 
+	if !model.MerchantExists(req.MerchantId) {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
 	var wallet = model.Wallet{
+		MerchantId:  req.MerchantId,
 		Name:        strings.TrimSpace(req.Name),
 		Remark:      req.Remark,
 		Address:     strings.TrimSpace(req.Address),
@@ -73,6 +83,8 @@ func (Wallet) Add(ctx *gin.Context) {
 		return
 	}
 
+	base.Audit(ctx, model.AuditActionAdd, model.AuditTargetWallet, wallet.ID, nil, wallet)
+
 	base.Response(ctx, 200, "success")
 }
 
@@ -87,6 +99,9 @@ func (Wallet) List(ctx *gin.Context) {
 	var data []model.Wallet
 	var db = model.Db
 
+	if req.MerchantId != nil {
+		db = db.Where("merchant_id = ?", *req.MerchantId)
+	}
 	if req.Name != "" {
 		db = db.Where("name LIKE ?", "%"+req.Name+"%")
 	}
@@ -127,6 +142,16 @@ func (Wallet) Mod(ctx *gin.Context) {
 		return
 	}
 
+	var before = w
+	if req.MerchantId != nil {
+		if !model.MerchantExists(*req.MerchantId) {
+			base.BadRequest(ctx, "商户不存在")
+
+			return
+		}
+
+		w.MerchantId = *req.MerchantId
+	}
 	if req.Name != nil {
 		w.Name = strings.TrimSpace(*req.Name)
 	}
@@ -165,6 +190,8 @@ func (Wallet) Mod(ctx *gin.Context) {
 		return
 	}
 
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetWallet, w.ID, before, w)
+
 	base.Response(ctx, 200, "修改成功")
 }
 
@@ -176,7 +203,12 @@ func (Wallet) Del(ctx *gin.Context) {
 		return
 	}
 
+	var w model.Wallet
+	model.Db.Where("id = ?", req.ID).Find(&w)
 	model.Db.Where("id = ?", req.ID).Delete(&model.Wallet{})
+	if w.ID > 0 {
+		base.Audit(ctx, model.AuditActionDel, model.AuditTargetWallet, w.ID, w, nil)
+	}
 
 	base.Response(ctx, 200, "删除成功")
 }
@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+type Xpub struct {
+}
+
+type xAddReq struct {
+	MerchantId int64  `json:"merchant_id"`
+	Name       string `json:"name"`
+	TradeType  string `json:"trade_type" binding:"required"`
+	Xpub       string `json:"xpub" binding:"required"`
+	Remark     string `json:"remark"`
+}
+
+type xModReq struct {
+	base.IDRequest
+	Name   *string `json:"name"`
+	Status *uint8  `json:"status"`
+	Remark *string `json:"remark"`
+}
+
+type xListReq struct {
+	base.ListRequest
+	MerchantId *int64 `json:"merchant_id"`
+	TradeType  string `json:"trade_type"`
+}
+
+type xAddressReq struct {
+	base.ListRequest
+	XpubId int64 `json:"xpub_id" binding:"required"`
+}
+
+func (Xpub) Add(ctx *gin.Context) {
+	var req xAddReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if !model.MerchantExists(req.MerchantId) {
+		base.BadRequest(ctx, "商户不存在")
+
+		return
+	}
+
+	var x = model.Xpub{
+		MerchantId: req.MerchantId,
+		Name:       req.Name,
+		TradeType:  req.TradeType,
+		Xpub:       req.Xpub,
+		Remark:     req.Remark,
+		Status:     model.XpubStatusEnable,
+	}
+
+	if err := x.Validate(); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if err := model.Db.Create(&x).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionAdd, model.AuditTargetXpub, x.ID, nil, x)
+
+	base.Response(ctx, 200, "success")
+}
+
+func (Xpub) List(ctx *gin.Context) {
+	var req xListReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.Xpub
+	var db = model.Db
+
+	if req.MerchantId != nil {
+		db = db.Where("merchant_id = ?", *req.MerchantId)
+	}
+	if req.TradeType != "" {
+		db = db.Where("trade_type = ?", req.TradeType)
+	}
+
+	var total int64
+
+	db.Model(&model.Xpub{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("id " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
+
+func (Xpub) Mod(ctx *gin.Context) {
+	var req xModReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var x model.Xpub
+	model.Db.Where("id = ?", req.ID).Find(&x)
+	if x.ID == 0 {
+		base.BadRequest(ctx, "扩展公钥不存在")
+
+		return
+	}
+
+	var before = x
+	if req.Name != nil {
+		x.Name = *req.Name
+	}
+	if req.Status != nil {
+		x.Status = *req.Status
+	}
+	if req.Remark != nil {
+		x.Remark = *req.Remark
+	}
+
+	if err := model.Db.Save(&x).Error; err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetXpub, x.ID, before, x)
+
+	base.Response(ctx, 200, "修改成功")
+}
+
+// Del 删除扩展公钥只停止后续推导，已推导地址保留，便于资金归集与对账
+func (Xpub) Del(ctx *gin.Context) {
+	var req base.IDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	var x model.Xpub
+	model.Db.Where("id = ?", req.ID).Find(&x)
+	if x.ID == 0 {
+		base.BadRequest(ctx, "扩展公钥不存在")
+
+		return
+	}
+
+	model.Db.Delete(&x)
+	base.Audit(ctx, model.AuditActionDel, model.AuditTargetXpub, x.ID, x, nil)
+
+	base.Response(ctx, 200, "删除成功")
+}
+
+func (Xpub) Addresses(ctx *gin.Context) {
+	var req xAddressReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	var data []model.XpubAddress
+	var db = model.Db.Where("xpub_id = ?", req.XpubId)
+
+	var total int64
+
+	db.Model(&model.XpubAddress{}).Count(&total)
+
+	err := db.Limit(req.Size).Offset((req.Page - 1) * req.Size).Order("derive_index " + req.Sort).Find(&data).Error
+	if err != nil {
+		base.Response(ctx, 400, err.Error())
+
+		return
+	}
+
+	base.Response(ctx, 200, data, total)
+}
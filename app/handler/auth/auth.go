@@ -1,7 +1,8 @@
 package auth
 
 import (
-	"strings"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -10,9 +11,6 @@ import (
 	"github.com/v03413/bepusdt/app/conf"
 	"github.com/v03413/bepusdt/app/handler/base"
 	"github.com/v03413/bepusdt/app/model"
-	"github.com/v03413/bepusdt/app/utils"
-	"github.com/v03413/go-cache"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
@@ -21,6 +19,16 @@ type Auth struct {
 type authLoginReq struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // 动态验证码或恢复码，启用两步验证时必填
+}
+
+type authTotpReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type authTotpDisableReq struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
 }
 
 type authPasswordReq struct {
@@ -29,9 +37,81 @@ type authPasswordReq struct {
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
 }
 
+type meta struct {
+	Title     string   `json:"title"`
+	Hide      bool     `json:"hide"`
+	Disable   bool     `json:"disable"`
+	KeepAlive bool     `json:"keepAlive"`
+	Affix     bool     `json:"affix"`
+	Link      string   `json:"link"`
+	Iframe    bool     `json:"iframe"`
+	IsFull    bool     `json:"isFull"`
+	Roles     []string `json:"roles"`
+	SvgIcon   string   `json:"svgIcon"`
+	Icon      string   `json:"icon"`
+	Sort      int      `json:"sort"`
+	Type      int      `json:"type"`
+}
+
+type menu struct {
+	Id        string `json:"id"`
+	ParentId  string `json:"parentId"`
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	Meta      meta   `json:"meta"`
+	Children  []menu `json:"children"`
+}
+
+// menuPermissions 菜单所需权限，未列出的菜单登录即可访问
+var menuPermissions = map[string]model.Permission{
+	"home":            model.PermView,
+	"wallet":          model.PermView,
+	"order":           model.PermView,
+	"rate-list":       model.PermView,
+	"rate-syntax":     model.PermView,
+	"system-base":     model.PermSystem,
+	"system-rpc":      model.PermSystem,
+	"system-token":    model.PermSystem,
+	"system-xpub":     model.PermView,
+	"system-merchant": model.PermSystem,
+	"system-notify":   model.PermView,
+	"system-user":     model.PermSystem,
+	"system-session":  model.PermSystem,
+	"system-audit":    model.PermSystem,
+	"create-order":    model.PermOrder,
+}
+
+// filterMenu 按权限过滤菜单，子菜单全部不可见时父菜单一并隐藏
+func filterMenu(list []menu, allow func(name string) bool) []menu {
+	var result = make([]menu, 0, len(list))
+	for _, m := range list {
+		if !allow(m.Name) {
+			continue
+		}
+		if m.Children != nil {
+			m.Children = filterMenu(m.Children, allow)
+			if len(m.Children) == 0 {
+				continue
+			}
+		}
+
+		result = append(result, m)
+	}
+
+	return result
+}
+
 func (Auth) Info(ctx *gin.Context) {
+	u, _ := base.CurrentUser(ctx)
+
 	base.Ok(ctx, gin.H{
-		"admin_username": model.GetK(model.AdminUsername),
+		"admin_username": u.Username,
+		"role":           u.Role,
+		"role_text":      model.RoleText(u.Role),
+		"permissions":    u.Permissions(),
+		"totp_enabled":   u.TotpEnabled,
+		"recovery_left":  u.RecoveryCodesLeft(),
 		"trade_type":     model.GetAllAlias(),
 		"trade_fiat":     model.GetSupportFiat(),
 		"trade_crypto":   model.GetSupportCrypto(),
@@ -39,31 +119,6 @@ func (Auth) Info(ctx *gin.Context) {
 }
 
 func (Auth) Menu(ctx *gin.Context) {
-	type meta struct {
-		Title     string   `json:"title"`
-		Hide      bool     `json:"hide"`
-		Disable   bool     `json:"disable"`
-		KeepAlive bool     `json:"keepAlive"`
-		Affix     bool     `json:"affix"`
-		Link      string   `json:"link"`
-		Iframe    bool     `json:"iframe"`
-		IsFull    bool     `json:"isFull"`
-		Roles     []string `json:"roles"`
-		SvgIcon   string   `json:"svgIcon"`
-		Icon      string   `json:"icon"`
-		Sort      int      `json:"sort"`
-		Type      int      `json:"type"`
-	}
-	type menu struct {
-		Id        string `json:"id"`
-		ParentId  string `json:"parentId"`
-		Path      string `json:"path"`
-		Name      string `json:"name"`
-		Component string `json:"component"`
-		Meta      meta   `json:"meta"`
-		Children  []menu `json:"children"`
-	}
-
 	var data = []menu{
 		{
 			Id:        "01",
@@ -267,6 +322,167 @@ func (Auth) Menu(ctx *gin.Context) {
 					},
 					Children: nil,
 				},
+				{
+					Id:        "0503",
+					ParentId:  "05",
+					Path:      "/system/token/token",
+					Name:      "system-token",
+					Component: "system/token/token",
+					Meta: meta{
+						Title:     "system-token",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-apps",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0504",
+					ParentId:  "05",
+					Path:      "/system/xpub/xpub",
+					Name:      "system-xpub",
+					Component: "system/xpub/xpub",
+					Meta: meta{
+						Title:     "system-xpub",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-branch",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0505",
+					ParentId:  "05",
+					Path:      "/system/merchant/merchant",
+					Name:      "system-merchant",
+					Component: "system/merchant/merchant",
+					Meta: meta{
+						Title:     "system-merchant",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-user-group",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0506",
+					ParentId:  "05",
+					Path:      "/system/notify/notify",
+					Name:      "system-notify",
+					Component: "system/notify/notify",
+					Meta: meta{
+						Title:     "system-notify",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-notification",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0507",
+					ParentId:  "05",
+					Path:      "/system/user/user",
+					Name:      "system-user",
+					Component: "system/user/user",
+					Meta: meta{
+						Title:     "system-user",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-user",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0508",
+					ParentId:  "05",
+					Path:      "/system/session/session",
+					Name:      "system-session",
+					Component: "system/session/session",
+					Meta: meta{
+						Title:     "system-session",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-desktop",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
+				{
+					Id:        "0509",
+					ParentId:  "05",
+					Path:      "/system/audit/audit",
+					Name:      "system-audit",
+					Component: "system/audit/audit",
+					Meta: meta{
+						Title:     "system-audit",
+						Hide:      false,
+						Disable:   false,
+						KeepAlive: true,
+						Affix:     false,
+						Link:      "",
+						Iframe:    false,
+						IsFull:    false,
+						Roles:     []string{"admin"},
+						SvgIcon:   "",
+						Icon:      "icon-history",
+						Sort:      1,
+						Type:      2,
+					},
+					Children: nil,
+				},
 			},
 		},
 		{
@@ -314,7 +530,14 @@ func (Auth) Menu(ctx *gin.Context) {
 		},
 	}
 
-	base.Ok(ctx, data)
+	// 调试模式跳过登录校验，此时不过滤菜单
+	u, login := base.CurrentUser(ctx)
+
+	base.Ok(ctx, filterMenu(data, func(name string) bool {
+		perm, ok := menuPermissions[name]
+
+		return !login || !ok || u.Can(perm)
+	}))
 }
 
 func (Auth) Login(ctx *gin.Context) {
@@ -325,34 +548,58 @@ func (Auth) Login(ctx *gin.Context) {
 		return
 	}
 
-	var username = model.GetK(model.AdminUsername)
-	if req.Username != username {
-		base.Response(ctx, 400, "用户名或密码错误")
+	var ip = ctx.ClientIP()
+	if d := model.LoginLocked(ip, req.Username); d > 0 {
+		base.Response(ctx, 400, fmt.Sprintf("登录失败次数过多，请 %d 分钟后再试", int(math.Ceil(d.Minutes()))))
 
 		return
 	}
 
-	var password = model.GetK(model.AdminPassword)
-	if bcrypt.CompareHashAndPassword([]byte(password), []byte(req.Password)) != nil {
+	u, ok := model.GetUserByName(req.Username)
+	if !ok || !u.CheckPassword(req.Password) {
+		model.LoginFailed(ip, req.Username)
 		base.Response(ctx, 400, "用户名或密码错误")
 
 		return
 	}
 
-	rand, _ := utils.GenerateTradeId()
+	if u.Status != model.UserStatusEnable {
+		base.Response(ctx, 400, "账号已停用")
+
+		return
+	}
+
+	if u.TotpEnabled {
+		if req.Code == "" {
+			base.Response(ctx, 200, gin.H{"totp_required": true})
+
+			return
+		}
+		if err := u.VerifySecondFactor(req.Code); err != nil {
+			model.LoginFailed(ip, req.Username)
+			base.Response(ctx, 400, err.Error())
 
-	var token = utils.StrSha256(rand + ctx.ClientIP())
+			return
+		}
+	}
 
-	cache.Set(conf.AdminTokenK, token, time.Hour*24)
+	token, err := base.NewSession(u, ip, ctx.Request.UserAgent())
+	if err != nil {
+		base.Response(ctx, 400, "登录会话创建失败："+err.Error())
 
-	model.SetK(model.AdminLoginIP, ctx.ClientIP())
+		return
+	}
+
+	model.LoginSucceeded(ip, req.Username)
+	u.SetLogin(ip)
+	model.SetK(model.AdminLoginIP, ip)
 	model.SetK(model.AdminLoginAt, cast.ToString(time.Now().Format(time.DateTime)))
 
 	base.Response(ctx, 200, gin.H{"token": token, "types": model.GetAllAlias()})
 }
 
 func (Auth) Logout(ctx *gin.Context) {
-	cache.Set(conf.AdminTokenK, "", -1)
+	base.DelSession(ctx.GetHeader("Authorization"))
 
 	sess := sessions.Default(ctx)
 	sess.Delete(conf.AdminSecureK)
@@ -369,8 +616,8 @@ func (Auth) SetPassword(ctx *gin.Context) {
 		return
 	}
 
-	var password = model.GetK(model.AdminPassword)
-	if bcrypt.CompareHashAndPassword([]byte(password), []byte(req.Password)) != nil {
+	u, ok := base.CurrentUser(ctx)
+	if !ok || !u.CheckPassword(req.Password) {
 		base.BadRequest(ctx, "原密码错误")
 
 		return
@@ -382,17 +629,110 @@ func (Auth) SetPassword(ctx *gin.Context) {
 		return
 	}
 
-	var newPassword = strings.TrimSpace(req.NewPassword)
-	if len(newPassword) < 6 {
-		base.BadRequest(ctx, "新密码长度不能少于6位")
+	var before = gin.H{"password": u.Password}
+	if err := u.SetPassword(req.NewPassword); err != nil {
+		base.BadRequest(ctx, err.Error())
 
 		return
 	}
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	// 密码摘要变更后该账号的全部会话随之失效
+	if err := model.Db.Model(&u).Update("password", u.Password).Error; err != nil {
+		base.Error(ctx, err)
 
-	model.SetK(model.AdminPassword, string(hash))
-	cache.Set(conf.AdminTokenK, "", -1)
+		return
+	}
 
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetUser, u.ID, before, gin.H{"password": u.Password})
 	base.Ok(ctx, "修改成功，请重新登录")
 }
+
+// TotpSetup 生成两步验证密钥与扫码绑定地址
+func (Auth) TotpSetup(ctx *gin.Context) {
+	u, ok := base.CurrentUser(ctx)
+	if !ok {
+		base.BadRequest(ctx, "当前会话未关联账号")
+
+		return
+	}
+
+	secret, uri, err := u.SetupTotp()
+	if err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	base.Ok(ctx, gin.H{"secret": secret, "uri": uri})
+}
+
+// TotpEnable 校验动态验证码后启用两步验证，恢复码仅在此时返回一次
+func (Auth) TotpEnable(ctx *gin.Context) {
+	var req authTotpReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	u, ok := base.CurrentUser(ctx)
+	if !ok {
+		base.BadRequest(ctx, "当前会话未关联账号")
+
+		return
+	}
+
+	codes, err := u.EnableTotp(req.Code)
+	if err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetUser, u.ID, gin.H{"totp_enabled": false}, gin.H{"totp_enabled": true})
+
+	base.Ok(ctx, gin.H{"recovery_codes": codes})
+}
+
+// TotpDisable 关闭两步验证需同时校验登录密码与动态验证码
+func (Auth) TotpDisable(ctx *gin.Context) {
+	var req authTotpDisableReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	u, ok := base.CurrentUser(ctx)
+	if !ok || !u.TotpEnabled {
+		base.BadRequest(ctx, "两步验证未启用")
+
+		return
+	}
+	if d := model.LoginLocked(ctx.ClientIP(), u.Username); d > 0 {
+		base.BadRequest(ctx, fmt.Sprintf("验证失败次数过多，请 %d 分钟后再试", int(math.Ceil(d.Minutes()))))
+
+		return
+	}
+	if !u.CheckPassword(req.Password) {
+		model.LoginFailed(ctx.ClientIP(), u.Username)
+		base.BadRequest(ctx, "登录密码错误")
+
+		return
+	}
+	if err := base.VerifySecondFactor(ctx, u, req.Code); err != nil {
+		base.BadRequest(ctx, err.Error())
+
+		return
+	}
+
+	if err := u.DisableTotp(); err != nil {
+		base.Error(ctx, err)
+
+		return
+	}
+
+	base.Audit(ctx, model.AuditActionMod, model.AuditTargetUser, u.ID, gin.H{"totp_enabled": true}, gin.H{"totp_enabled": false})
+
+	base.Ok(ctx, "两步验证已关闭")
+}
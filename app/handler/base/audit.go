@@ -0,0 +1,27 @@
+package base
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// Audit 记录后台操作审计日志，新增时 before 传 nil，删除时 after 传 nil；写入失败只记日志，不影响业务操作
+func Audit(ctx *gin.Context, action, target string, targetId any, before, after any) {
+	u, _ := CurrentUser(ctx)
+
+	var a = model.Audit{
+		UserId:   u.ID,
+		Username: u.Username,
+		Ip:       ctx.ClientIP(),
+		Route:    ctx.Request.Method + " " + ctx.FullPath(),
+		Action:   action,
+		Target:   target,
+		TargetId: fmt.Sprint(targetId),
+	}
+	if err := model.AddAudit(a, before, after); err != nil {
+		log.Warn(fmt.Sprintf("审计日志写入失败 %s %s：%s", action, a.TargetId, err.Error()))
+	}
+}
@@ -0,0 +1,16 @@
+package base
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// Operator 当前登录的管理员，用于记录订单状态变更来源
+func Operator(ctx *gin.Context) string {
+	if u, ok := CurrentUser(ctx); ok {
+
+		return model.EventSource(model.EventSourceAdmin, u.Username)
+	}
+
+	return model.EventSource(model.EventSourceAdmin, "")
+}
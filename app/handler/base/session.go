@@ -0,0 +1,126 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+const (
+	userCtxKey    = "admin_user"
+	sessionCtxKey = "admin_session_id"
+)
+
+// NewSession 创建登录会话并返回令牌，会话持久化在数据库中，重启或多实例部署均可共用
+func NewSession(u model.User, ip, ua string) (string, error) {
+
+	return model.NewAdminSession(u, ip, ua)
+}
+
+func DelSession(token string) {
+	model.DelAdminSession(token)
+}
+
+// CheckSession 校验令牌对应的会话与账号状态，账号停用或密码变更后会话失效
+func CheckSession(token string) (model.User, model.AdminSession, error) {
+	sess, ok := model.GetAdminSession(token)
+	if !ok {
+
+		return model.User{}, sess, errors.New("token expired, please login again")
+	}
+
+	u, ok := model.GetUser(sess.UserId)
+	if !ok || !sess.Valid(u) {
+		DelSession(token)
+
+		return model.User{}, sess, errors.New("token expired, please login again")
+	}
+
+	sess.Touch()
+
+	return u, sess, nil
+}
+
+func SetSession(ctx *gin.Context, sess model.AdminSession) {
+	ctx.Set(sessionCtxKey, sess.ID)
+}
+
+// CurrentSessionId 当前请求所属的登录会话
+func CurrentSessionId(ctx *gin.Context) int64 {
+
+	return ctx.GetInt64(sessionCtxKey)
+}
+
+func SetUser(ctx *gin.Context, u model.User) {
+	ctx.Set(userCtxKey, u)
+}
+
+// CurrentUser 当前请求的登录账号
+func CurrentUser(ctx *gin.Context) (model.User, bool) {
+	if v, ok := ctx.Get(userCtxKey); ok {
+		if u, ok := v.(model.User); ok {
+
+			return u, true
+		}
+	}
+
+	return model.User{}, false
+}
+
+// TotpHeader 敏感操作通过请求头传递动态验证码
+const TotpHeader = "X-Totp-Code"
+
+// TotpGuard 敏感操作中间件，已启用两步验证的账号需在请求头携带新的动态验证码或恢复码
+func TotpGuard(ctx *gin.Context) {
+	if !CheckTotp(ctx) {
+		ctx.Abort()
+
+		return
+	}
+
+	ctx.Next()
+}
+
+// CheckTotp 校验请求头中的动态验证码，未通过时已写入响应
+func CheckTotp(ctx *gin.Context) bool {
+	u, ok := CurrentUser(ctx)
+	if !ok || !u.TotpEnabled {
+		return true
+	}
+
+	var code = ctx.GetHeader(TotpHeader)
+	if code == "" {
+		Response(ctx, 428, "该操作需要输入动态验证码")
+
+		return false
+	}
+
+	if err := VerifySecondFactor(ctx, u, code); err != nil {
+		BadRequest(ctx, err.Error())
+
+		return false
+	}
+
+	return true
+}
+
+// VerifySecondFactor 登录后的动态验证码校验同样计入登录失败次数，锁定期间直接拒绝，避免借已登录会话穷举验证码
+func VerifySecondFactor(ctx *gin.Context, u model.User, code string) error {
+	var ip = ctx.ClientIP()
+	if d := model.LoginLocked(ip, u.Username); d > 0 {
+
+		return fmt.Errorf("验证失败次数过多，请 %d 分钟后再试", int(math.Ceil(d.Minutes())))
+	}
+	if err := u.VerifySecondFactor(code); err != nil {
+		model.LoginFailed(ip, u.Username)
+
+		return err
+	}
+
+	model.LoginSucceeded(ip, u.Username)
+
+	return nil
+}
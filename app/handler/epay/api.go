@@ -0,0 +1,163 @@
+package epay
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// Api 【兼容】易支付 api.php 接口，支持订单查询（act=order）；退款（act=refund）校验订单后返回拒绝原因
+func (e Epay) Api(ctx *gin.Context) {
+	_ = ctx.Request.ParseForm()
+
+	var dataMap = make(map[string]string)
+	for key, values := range ctx.Request.Form {
+		if len(values) > 0 && key != "act" {
+			dataMap[key] = values[0]
+		}
+	}
+
+	merchant, err := e.auth(dataMap)
+	if err != nil {
+		ctx.JSON(200, respFail(err.Error()))
+
+		return
+	}
+
+	switch ctx.Request.Form.Get("act") {
+	case "order":
+		e.order(ctx, merchant, dataMap)
+	case "refund":
+		e.refund(ctx, merchant, dataMap)
+	default:
+		ctx.JSON(200, respFail("不支持的操作类型"))
+	}
+}
+
+// auth 兼容两种鉴权方式：易支付标准的 key 参数直接传递商户密钥，或与下单相同的 sign 参数签名
+func (e Epay) auth(data map[string]string) (model.Merchant, error) {
+	if data["pid"] == "" {
+
+		return model.Merchant{}, errors.New("参数 pid 缺失或为空")
+	}
+
+	merchant, ok := model.GetMerchantByPid(data["pid"])
+	if !ok {
+
+		return merchant, errors.New("商户号【PID】不存在或已停用")
+	}
+
+	if key := data["key"]; key != "" {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(merchant.Secret)) != 1 {
+
+			return merchant, errors.New("商户密钥错误")
+		}
+
+		return merchant, nil
+	}
+
+	if data["sign"] == "" {
+
+		return merchant, errors.New("参数 sign 缺失或为空")
+	}
+
+	return merchant, e.verifySign(data, merchant.Secret)
+}
+
+// findOrder 按平台订单号 trade_no 或商户订单号 out_trade_no 查找商户自己的订单
+func (e Epay) findOrder(merchant model.Merchant, data map[string]string) (model.Order, error) {
+	var order model.Order
+	if data["trade_no"] == "" && data["out_trade_no"] == "" {
+
+		return order, errors.New("trade_no 与 out_trade_no 不能同时为空")
+	}
+
+	var db = model.Db.Where("merchant_id = ?", merchant.ID)
+	if data["trade_no"] != "" {
+		db = db.Where("trade_id = ?", data["trade_no"])
+	}
+	if data["out_trade_no"] != "" {
+		db = db.Where("order_id = ?", data["out_trade_no"])
+	}
+
+	db.Order("id desc").Limit(1).Find(&order)
+	if order.ID == 0 {
+
+		return order, errors.New("订单号不存在")
+	}
+
+	return order, nil
+}
+
+// refund 链上转账无法由系统原路退回，校验订单后返回拒绝原因，退款需商户自行向付款地址转账
+func (e Epay) refund(ctx *gin.Context, merchant model.Merchant, data map[string]string) {
+	order, err := e.findOrder(merchant, data)
+	if err != nil {
+		ctx.JSON(200, respFail(err.Error()))
+
+		return
+	}
+
+	var msg = "订单未支付，无需退款"
+	if order.IsPaid() {
+		var amount = order.PaidAmount
+		if cast.ToFloat64(amount) <= 0 {
+			amount = order.Amount
+		}
+
+		msg = fmt.Sprintf("链上收款不支持自动原路退款，请自行向付款地址 %s 转账退还 %s %s", order.FromAddress, amount, order.Crypto)
+	}
+
+	ctx.JSON(200, gin.H{
+		"code":         -1,
+		"msg":          msg,
+		"trade_no":     order.TradeId,
+		"out_trade_no": order.OrderId,
+	})
+}
+
+// order 按平台订单号 trade_no 或商户订单号 out_trade_no 查询订单
+func (e Epay) order(ctx *gin.Context, merchant model.Merchant, data map[string]string) {
+	order, err := e.findOrder(merchant, data)
+	if err != nil {
+		ctx.JSON(200, respFail(err.Error()))
+
+		return
+	}
+
+	var status, endTime, hash = 0, "", ""
+	if order.IsPaid() {
+		status = 1
+		hash = order.RefHash
+		if order.ConfirmedAt != nil {
+			endTime = order.ConfirmedAt.Format(time.DateTime)
+		}
+	}
+
+	ctx.JSON(200, gin.H{
+		"code":         1,
+		"msg":          "查询订单号成功！",
+		"trade_no":     order.TradeId,
+		"out_trade_no": order.OrderId,
+		"api_trade_no": hash,
+		"type":         order.TradeType,
+		"pid":          merchant.Pid,
+		"addtime":      order.CreatedAt.Time().Format(time.DateTime),
+		"endtime":      endTime,
+		"name":         order.Name,
+		"money":        order.Money,
+		"status":       status,
+		"param":        "",
+		"buyer":        order.FromAddress,
+	})
+}
+
+func respFail(msg string) gin.H {
+
+	return gin.H{"code": -1, "msg": msg}
+}
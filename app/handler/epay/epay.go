@@ -1,11 +1,14 @@
 package epay
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
@@ -14,7 +17,7 @@ import (
 	"github.com/v03413/bepusdt/app/utils"
 )
 
-const Pid = "1000" // 固定商户号
+const signTypeHmac = "HMAC-SHA256"
 
 type Epay struct {
 }
@@ -36,57 +39,100 @@ type submit struct {
 
 // Submit 【兼容】易支付提交
 func (e Epay) Submit(ctx *gin.Context) {
-	var err error
-	var data submit
+	order, err := e.build(e.params(ctx))
+	if err != nil {
+		ctx.String(200, err.Error())
 
-	var dataMap = make(map[string]string)
-	if ctx.Request.Method != http.MethodPost {
-		for key, values := range ctx.Request.URL.Query() {
-			if len(values) > 0 {
-				dataMap[key] = values[0]
-			}
-		}
-	} else {
+		return
+	}
+
+	// 解析请求地址
+	var host = "http://" + ctx.Request.Host
+	if ctx.Request.TLS != nil {
+		host = "https://" + ctx.Request.Host
+	}
+
+	ctx.Redirect(http.StatusFound, model.CheckoutUrl(host, order.TradeId))
+}
+
+// Mapi 【兼容】易支付 API 接口支付，服务端调用并返回支付链接
+func (e Epay) Mapi(ctx *gin.Context) {
+	order, err := e.build(e.params(ctx))
+	if err != nil {
+		ctx.JSON(200, respFail(err.Error()))
+
+		return
+	}
+
+	var payUrl = model.CheckoutUrl(utils.GetRequestHost(ctx.Request), order.TradeId)
+
+	ctx.JSON(200, gin.H{
+		"code":       1,
+		"msg":        "success",
+		"trade_no":   order.TradeId,
+		"payurl":     payUrl,
+		"qrcode":     payUrl, // 二维码内容，扫码打开收银台，页面展示精确的应付数额
+		"money":      order.Money,
+		"amount":     order.Amount,
+		"address":    order.Address,
+		"trade_type": order.TradeType,
+	})
+}
+
+// params GET 请求读取查询参数，POST 请求读取表单参数
+func (e Epay) params(ctx *gin.Context) map[string]string {
+	var values = ctx.Request.URL.Query()
+	if ctx.Request.Method == http.MethodPost {
 		_ = ctx.Request.ParseForm()
-		for key, values := range ctx.Request.PostForm {
-			if len(values) > 0 {
-				dataMap[key] = values[0]
-			}
+		values = ctx.Request.PostForm
+	}
+
+	var dataMap = make(map[string]string)
+	for key, v := range values {
+		if len(v) > 0 {
+			dataMap[key] = v[0]
 		}
 	}
 
-	data, err = e.verify(dataMap)
+	return dataMap
+}
+
+// build 校验参数与签名后创建订单，页面跳转支付与 API 接口支付共用
+func (e Epay) build(dataMap map[string]string) (model.Order, error) {
+	data, err := e.verify(dataMap)
 	if err != nil {
-		ctx.String(200, err.Error())
 
-		return
+		return model.Order{}, err
 	}
 
-	if e.sign(dataMap, model.AuthToken()) != data.Sign {
-		ctx.String(200, "签名错误")
+	merchant, ok := model.GetMerchantByPid(data.Pid)
+	if !ok {
 
-		return
+		return model.Order{}, errors.New("商户号【PID】不存在或已停用")
+	}
+
+	if err = e.verifySign(dataMap, merchant.Secret); err != nil {
+
+		return model.Order{}, err
 	}
 
 	if !utils.IsAllowedCallbackURL(data.NotifyURL) {
-		ctx.String(200, "notify_url 地址不合法")
 
-		return
+		return model.Order{}, errors.New("notify_url 地址不合法")
 	}
 	if !utils.IsAllowedCallbackURL(data.ReturnURL) {
-		ctx.String(200, "return_url 地址不合法")
 
-		return
+		return model.Order{}, errors.New("return_url 地址不合法")
 	}
 
 	money, err := decimal.NewFromString(data.Money)
 	if err != nil {
-		ctx.String(200, "参数 money 解析错误，"+err.Error())
 
-		return
+		return model.Order{}, errors.New("参数 money 解析错误，" + err.Error())
 	}
 
-	var order, err2 = model.StartBuildOrder(model.OrderParams{
+	order, err := model.StartBuildOrder(model.OrderParams{
+		MerchantId:  merchant.ID,
 		Money:       money,
 		ApiType:     model.OrderApiTypeEpay,
 		Address:     data.Address,
@@ -97,33 +143,21 @@ func (e Epay) Submit(ctx *gin.Context) {
 		Name:        data.Name,
 		Timeout:     cast.ToInt64(data.Timeout),
 		Rate:        data.Rate,
-		Fiat:        data.Fiat,
+		Fiat:        merchant.DefaultFiat(data.Fiat),
 	})
-	if err2 != nil {
-		ctx.String(200, fmt.Sprintf("订单创建失败：%v", err2))
-
-		return
-	}
+	if err != nil {
 
-	// 解析请求地址
-	var host = "http://" + ctx.Request.Host
-	if ctx.Request.TLS != nil {
-		host = "https://" + ctx.Request.Host
+		return order, fmt.Errorf("订单创建失败：%v", err)
 	}
 
-	ctx.Redirect(http.StatusFound, model.CheckoutUrl(host, order.TradeId))
+	return order, nil
 }
 
 // verify 验证请求参数
 func (e Epay) verify(data map[string]string) (submit, error) {
 	var params = submit{}
 
-	pid, ok := data["pid"]
-	if !ok || pid != Pid {
-
-		return params, fmt.Errorf("BEpusdt 易支付兼容模式，商户号【PID】必须固定为" + Pid)
-	}
-
+	var pid = data["pid"]
 	var requiredFields = []string{"pid", "type", "out_trade_no", "notify_url", "return_url", "name", "money", "sign"}
 	for _, field := range requiredFields {
 		if _, ok := data[field]; !ok || data[field] == "" {
@@ -151,17 +185,50 @@ func (e Epay) verify(data map[string]string) (submit, error) {
 		params.Timeout = timeout
 	}
 
-	fiat, ok := data["fiat"]
-	if ok && fiat != "" {
+	if fiat, ok := data["fiat"]; ok && fiat != "" {
 		params.Fiat = model.Fiat(fiat)
-	} else {
-		params.Fiat = model.CNY
 	}
 
 	return params, nil
 }
 
+// verifySign sign_type 为 HMAC-SHA256 时需携带 timestamp 与 nonce 参数（参与签名），并校验防重放；否则按 MD5 校验
+func (e Epay) verifySign(data map[string]string, token string) error {
+	var mode = model.GetSignMode()
+	if mode != model.SignMd5 && strings.EqualFold(data["sign_type"], signTypeHmac) {
+		if !hmac.Equal([]byte(utils.HmacSha256(token, e.signString(data))), []byte(strings.ToLower(data["sign"]))) {
+
+			return errors.New("签名错误")
+		}
+
+		return utils.CheckReplay(data["timestamp"], data["nonce"])
+	}
+	if mode == model.SignHmac {
+
+		return errors.New("仅支持 HMAC-SHA256 签名，请设置 sign_type=" + signTypeHmac)
+	}
+
+	if e.sign(data, token) != data["sign"] {
+
+		return errors.New("签名错误")
+	}
+
+	return nil
+}
+
 func (e Epay) sign(data map[string]string, token string) string {
+	signStr := e.signString(data) + token
+
+	// 计算 MD5
+	hash := md5.New()
+	hash.Write([]byte(signStr))
+	md5sum := hex.EncodeToString(hash.Sum(nil))
+
+	return md5sum
+}
+
+// signString 参与签名的参数按键名排序拼接，sign、sign_type 与空值不参与
+func (e Epay) signString(data map[string]string) string {
 	var keys = make([]string, 0, len(data))
 	for k := range data {
 		keys = append(keys, k)
@@ -169,19 +236,12 @@ func (e Epay) sign(data map[string]string, token string) string {
 
 	sort.Strings(keys)
 
-	signStr := ""
+	var list = make([]string, 0, len(keys))
 	for _, k := range keys {
 		if k != "sign" && k != "sign_type" && data[k] != "" {
-			signStr += fmt.Sprintf("%s=%s&", k, data[k])
+			list = append(list, fmt.Sprintf("%s=%s", k, data[k]))
 		}
 	}
-	signStr = signStr[:len(signStr)-1]
-	signStr += token
 
-	// 计算 MD5
-	hash := md5.New()
-	hash.Write([]byte(signStr))
-	md5sum := hex.EncodeToString(hash.Sum(nil))
-
-	return md5sum
+	return strings.Join(list, "&")
 }
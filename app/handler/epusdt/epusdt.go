@@ -3,6 +3,7 @@ package epusdt
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
+	"github.com/spf13/cast"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
@@ -21,7 +23,7 @@ type createReq struct {
 	OrderID     string     `json:"order_id" binding:"required"`
 	NotifyURL   string     `json:"notify_url" binding:"required"`
 	RedirectURL string     `json:"redirect_url" binding:"required"`
-	Signature   string     `json:"signature" binding:"required"`
+	Signature   string     `json:"signature"`
 	Amount      float64    `json:"amount"`
 	Name        string     `json:"name"`
 	Fiat        model.Fiat `json:"fiat"`
@@ -35,7 +37,7 @@ type createOrderReq struct {
 	OrderID     string     `json:"order_id" binding:"required"`
 	NotifyURL   string     `json:"notify_url" binding:"required"`
 	RedirectURL string     `json:"redirect_url" binding:"required"`
-	Signature   string     `json:"signature" binding:"required"`
+	Signature   string     `json:"signature"`
 	Amount      float64    `json:"amount"`
 	Name        string     `json:"name"`
 	Fiat        model.Fiat `json:"fiat"`
@@ -52,7 +54,7 @@ type updateOrderReq struct {
 
 type cancelReq struct {
 	TradeID   string `json:"trade_id" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
+	Signature string `json:"signature"`
 }
 
 type infoReq struct {
@@ -86,13 +88,8 @@ func (Epusdt) Notify(ctx *gin.Context) {
 		return
 	}
 
-	sign, ok := m["signature"]
-	if !ok {
-		ctx.String(200, "fail")
-		return
-	}
-
-	if utils.EpusdtSign(m, model.AuthToken()) != sign {
+	merchant, ok := model.GetMerchantByPid(cast.ToString(m["pid"]))
+	if !ok || verifySign(ctx, m, rawData, merchant.Secret) != nil {
 		ctx.String(200, "fail")
 		return
 	}
@@ -125,12 +122,11 @@ func (Epusdt) CreateOrder(ctx *gin.Context) {
 		host = "https://" + ctx.Request.Host
 	}
 
-	if req.Fiat == "" {
-		req.Fiat = model.CNY
-	}
+	merchant := getMerchant(ctx)
 
 	// 创建待付款订单
 	order, err := model.BuildPendingOrder(model.OrderParams{
+		MerchantId:        merchant.ID,
 		Money:             decimal.NewFromFloat(req.Amount),
 		ApiType:           model.OrderApiTypeEpusdtOrder,
 		OrderId:           req.OrderID,
@@ -138,7 +134,7 @@ func (Epusdt) CreateOrder(ctx *gin.Context) {
 		NotifyUrl:         req.NotifyURL,
 		Name:              req.Name,
 		Timeout:           req.Timeout,
-		Fiat:              req.Fiat,
+		Fiat:              merchant.DefaultFiat(req.Fiat),
 		CurrencyLimit:     req.Currencies,
 		TradeTypeReselect: req.tradeTypeReselect(),
 	})
@@ -265,14 +261,13 @@ func (Epusdt) CreateTransaction(ctx *gin.Context) {
 		return
 	}
 
-	if req.Fiat == "" {
-		req.Fiat = model.CNY
-	}
 	if req.TradeType == "" {
 		req.TradeType = string(model.UsdtTrc20)
 	}
 
+	merchant := getMerchant(ctx)
 	order, err := model.StartBuildOrder(model.OrderParams{
+		MerchantId:    merchant.ID,
 		Money:         decimal.NewFromFloat(req.Amount),
 		ApiType:       model.OrderApiTypeEpusdt,
 		Address:       req.Address,
@@ -284,7 +279,7 @@ func (Epusdt) CreateTransaction(ctx *gin.Context) {
 		Name:          req.Name,
 		Timeout:       req.Timeout,
 		Rate:          req.Rate,
-		Fiat:          req.Fiat,
+		Fiat:          merchant.DefaultFiat(req.Fiat),
 	})
 	if err != nil {
 		ctx.JSON(200, respFailJson(fmt.Sprintf("订单创建失败：%s", err.Error())))
@@ -318,7 +313,7 @@ func (Epusdt) CancelTransaction(ctx *gin.Context) {
 	}
 
 	order, ok := model.GetTradeOrder(req.TradeID)
-	if !ok {
+	if !ok || order.MerchantId != getMerchant(ctx).ID {
 		ctx.JSON(200, respFailJson("订单不存在"))
 
 		return
@@ -330,7 +325,7 @@ func (Epusdt) CancelTransaction(ctx *gin.Context) {
 		return
 	}
 
-	if err := order.SetCanceled(); err != nil {
+	if err := order.SetCanceled(model.EventSource(model.EventSourceApi, order.ApiType)); err != nil {
 		ctx.JSON(200, respFailJson(fmt.Sprintf("订单取消失败：%s", err.Error())))
 
 		return
@@ -402,6 +397,9 @@ func (Epusdt) Info(ctx *gin.Context) {
 		"status":        order.Status,                        // 订单状态
 		"money":         order.Money,                         // 订单金额
 		"actual_amount": order.Amount,                        // 实付数额
+		"paid_amount":   order.PaidAmount,                    // 累计已付
+		"diff_amount":   order.GetDiffAmount(),               // 实收差值
+		"payment_num":   order.GetPaymentNum(),               // 收款笔数，完整记录需通过签名的订单查询接口获取
 		"token":         order.Address,                       // 收款地址
 		"fiat":          order.Fiat,                          // 法币类型
 		"name":          order.Name,                          // 商品名称
@@ -431,25 +429,64 @@ func (Epusdt) SignVerify(ctx *gin.Context) {
 		return
 	}
 
-	sign, ok := m["signature"]
+	// 未传 pid 时使用默认商户，兼容原有单商户对接
+	merchant, ok := model.GetMerchantByPid(cast.ToString(m["pid"]))
 	if !ok {
-		ctx.JSON(200, respFailJson("签名丢失"))
+		ctx.JSON(200, respFailJson("商户不存在或已停用"))
 		ctx.Abort()
 
 		return
 	}
 
-	if utils.EpusdtSign(m, model.AuthToken()) != sign {
-		ctx.JSON(200, respFailJson("签名错误"))
+	if err = verifySign(ctx, m, rawData, merchant.Secret); err != nil {
+		ctx.JSON(200, respFailJson(err.Error()))
 		ctx.Abort()
 
 		return
 	}
 
+	ctx.Set("merchant", merchant)
 	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(rawData)) // 回写数据
 	ctx.Next()
 }
 
+// verifySign 携带 v2 签名请求头时按 HMAC-SHA256 校验，否则按 MD5 参数签名校验，具体由签名模式决定
+func verifySign(ctx *gin.Context, m map[string]any, rawData []byte, secret string) error {
+	var mode = model.GetSignMode()
+	if mode != model.SignMd5 && ctx.GetHeader(utils.HeaderSignature) != "" {
+
+		return utils.VerifySignHeaders(ctx.Request.Header, secret, rawData)
+	}
+	if mode == model.SignHmac {
+
+		return errors.New("仅支持 HMAC-SHA256 签名，请通过请求头传递签名")
+	}
+
+	sign, ok := m["signature"]
+	if !ok {
+
+		return errors.New("签名丢失")
+	}
+	if utils.EpusdtSign(m, secret) != sign {
+
+		return errors.New("签名错误")
+	}
+
+	return nil
+}
+
+// getMerchant 签名校验通过的商户
+func getMerchant(ctx *gin.Context) model.Merchant {
+	if v, ok := ctx.Get("merchant"); ok {
+		if m, ok := v.(model.Merchant); ok {
+
+			return m
+		}
+	}
+
+	return model.DefaultMerchant()
+}
+
 func respFailJson(message string) gin.H {
 
 	return gin.H{"status_code": 400, "message": message}
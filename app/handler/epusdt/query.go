@@ -0,0 +1,173 @@
+package epusdt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+const (
+	queryMaxSize  = 100
+	queryMaxRange = time.Hour * 24 * 31
+)
+
+type queryReq struct {
+	TradeID   string `json:"trade_id"`
+	OrderID   string `json:"order_id"`
+	Signature string `json:"signature"`
+}
+
+type listReq struct {
+	StartAt   string `json:"start_at"` // 创建时间起，格式 2006-01-02 15:04:05，默认 24 小时前
+	EndAt     string `json:"end_at"`   // 创建时间止，默认当前时间
+	Status    int    `json:"status"`   // 订单状态，0 为全部
+	Page      int    `json:"page"`
+	Size      int    `json:"size"`
+	Signature string `json:"signature"`
+}
+
+// QueryOrder 按本地交易号或商户订单号查询订单，商户订单号存在多笔时返回最新一笔
+func (Epusdt) QueryOrder(ctx *gin.Context) {
+	var req queryReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(200, respFailJson(fmt.Sprintf("请求参数错误：%s", err.Error())))
+
+		return
+	}
+
+	if req.TradeID == "" && req.OrderID == "" {
+		ctx.JSON(200, respFailJson("trade_id 与 order_id 不能同时为空"))
+
+		return
+	}
+
+	var order model.Order
+	var db = model.Db.Where("merchant_id = ?", getMerchant(ctx).ID)
+	if req.TradeID != "" {
+		db = db.Where("trade_id = ?", req.TradeID)
+	}
+	if req.OrderID != "" {
+		db = db.Where("order_id = ?", req.OrderID)
+	}
+
+	db.Order("id desc").Limit(1).Find(&order)
+	if order.ID == 0 {
+		ctx.JSON(200, respFailJson("订单不存在"))
+
+		return
+	}
+
+	ctx.JSON(200, respSuccJson(orderDetail(order, order.GetPayments())))
+}
+
+// ListOrder 按创建时间范围与状态分页查询订单，用于商户对账
+func (Epusdt) ListOrder(ctx *gin.Context) {
+	var req listReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(200, respFailJson(fmt.Sprintf("请求参数错误：%s", err.Error())))
+
+		return
+	}
+
+	var end = time.Now()
+	var start = end.Add(-time.Hour * 24)
+	var err error
+	if req.EndAt != "" {
+		if end, err = time.ParseInLocation(time.DateTime, req.EndAt, time.Local); err != nil {
+			ctx.JSON(200, respFailJson("end_at 格式错误，示例：2006-01-02 15:04:05"))
+
+			return
+		}
+		start = end.Add(-time.Hour * 24)
+	}
+	if req.StartAt != "" {
+		if start, err = time.ParseInLocation(time.DateTime, req.StartAt, time.Local); err != nil {
+			ctx.JSON(200, respFailJson("start_at 格式错误，示例：2006-01-02 15:04:05"))
+
+			return
+		}
+	}
+	if !start.Before(end) || end.Sub(start) > queryMaxRange {
+		ctx.JSON(200, respFailJson("时间范围错误，开始时间必须早于结束时间且跨度不超过 31 天"))
+
+		return
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 || req.Size > queryMaxSize {
+		req.Size = queryMaxSize
+	}
+
+	var db = model.Db.Model(&model.Order{}).Where("merchant_id = ? and created_at >= ? and created_at <= ?", getMerchant(ctx).ID, start.Format(time.DateTime), end.Format(time.DateTime))
+	if req.Status != 0 {
+		db = db.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	var orders = make([]model.Order, 0)
+
+	db.Count(&total)
+	db.Order("id desc").Limit(req.Size).Offset((req.Page - 1) * req.Size).Find(&orders)
+
+	var tradeIds = make([]string, 0, len(orders))
+	for _, o := range orders {
+		tradeIds = append(tradeIds, o.TradeId)
+	}
+
+	var payments = model.GetOrdersPayments(tradeIds)
+	var list = make([]gin.H, 0, len(orders))
+	for _, o := range orders {
+		var items = payments[o.TradeId]
+		if items == nil {
+			items = make([]model.OrderPayment, 0)
+		}
+
+		list = append(list, orderDetail(o, items))
+	}
+
+	ctx.JSON(200, respSuccJson(gin.H{
+		"total": total,
+		"page":  req.Page,
+		"size":  req.Size,
+		"list":  list,
+	}))
+}
+
+// orderDetail 商户查询接口返回的订单详情，未确认的订单不返回交易哈希与确认时间
+func orderDetail(o model.Order, payments []model.OrderPayment) gin.H {
+	var txHash, tradeUrl, confirmedAt = "", "", int64(0)
+	if o.IsPaid() || o.Status == model.OrderStatusConfirming || o.Status == model.OrderStatusFailed {
+		txHash = o.RefHash
+		tradeUrl = o.GetTxUrl()
+		if o.ConfirmedAt != nil {
+			confirmedAt = o.ConfirmedAt.Unix()
+		}
+	}
+
+	return gin.H{
+		"trade_id":      o.TradeId,                 // 交易编号
+		"order_id":      o.OrderId,                 // 商户订单
+		"trade_type":    o.TradeType,               // 交易类型
+		"status":        o.Status,                  // 订单状态
+		"fiat":          o.Fiat,                    // 法币类型
+		"money":         o.Money,                   // 订单金额
+		"actual_amount": o.Amount,                  // 应付数额
+		"paid_amount":   o.PaidAmount,              // 累计已付
+		"diff_amount":   o.GetDiffAmount(),         // 实收差值
+		"token":         o.Address,                 // 收款地址
+		"from_address":  o.FromAddress,             // 付款地址
+		"tx_hash":       txHash,                    // 交易哈希
+		"block_num":     o.RefBlockNum,             // 区块高度
+		"trade_url":     tradeUrl,                  // 链上详情
+		"payments":      payments,                  // 收款记录
+		"notify_state":  o.NotifyState,             // 回调状态 1：成功 0：失败
+		"notify_num":    o.NotifyNum,               // 回调次数
+		"created_at":    o.CreatedAt.Time().Unix(), // 创建时间
+		"expired_at":    o.ExpiredAt.Unix(),        // 截止时间
+		"confirmed_at":  confirmedAt,               // 确认时间
+	}
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/v03413/bepusdt/app/utils"
+)
+
+const (
+	AdminSessionTTL   = time.Hour * 24
+	adminSessionTouch = time.Minute // 最后活跃时间的最小刷新间隔，避免每个请求都写库
+)
+
+// AdminSession 后台登录会话，库中只保存令牌摘要；同时记录登录时的密码摘要，修改密码后旧会话随之失效
+type AdminSession struct {
+	Id
+	TokenHash  string    `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex;comment:令牌摘要" json:"-"`
+	UserId     int64     `gorm:"column:user_id;not null;index;comment:账号ID" json:"user_id"`
+	PassSum    string    `gorm:"column:pass_sum;type:varchar(64);not null;comment:登录时密码摘要" json:"-"`
+	Ip         string    `gorm:"column:ip;type:varchar(64);not null;default:'';comment:登录IP" json:"ip"`
+	UserAgent  string    `gorm:"column:user_agent;type:varchar(255);not null;default:'';comment:登录设备" json:"user_agent"`
+	LastSeenAt time.Time `gorm:"column:last_seen_at;not null;comment:最后活跃时间" json:"last_seen_at"`
+	ExpiredAt  time.Time `gorm:"column:expired_at;not null;index;comment:过期时间" json:"expired_at"`
+	AutoTimeAt
+}
+
+func (s *AdminSession) TableName() string {
+
+	return "bep_admin_session"
+}
+
+// WebSession 浏览器 Cookie 会话数据（安全入口标记等），供多实例共享
+type WebSession struct {
+	Sid       string    `gorm:"column:sid;type:varchar(64);primaryKey;comment:会话ID" json:"sid"`
+	Data      string    `gorm:"column:data;type:text;comment:会话数据（已签名编码）" json:"-"`
+	ExpiredAt time.Time `gorm:"column:expired_at;not null;index;comment:过期时间" json:"expired_at"`
+}
+
+func (s *WebSession) TableName() string {
+
+	return "bep_web_session"
+}
+
+func passSum(u User) string {
+
+	return utils.StrSha256(u.Password)
+}
+
+// NewAdminSession 创建登录会话，返回的令牌仅此一次明文可见
+func NewAdminSession(u User, ip, ua string) (string, error) {
+	var b = make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+
+		return "", err
+	}
+
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+
+	var token = hex.EncodeToString(b)
+	var now = time.Now()
+	var s = AdminSession{
+		TokenHash:  utils.StrSha256(token),
+		UserId:     u.ID,
+		PassSum:    passSum(u),
+		Ip:         ip,
+		UserAgent:  ua,
+		LastSeenAt: now,
+		ExpiredAt:  now.Add(AdminSessionTTL),
+	}
+
+	return token, Db.Create(&s).Error
+}
+
+// GetAdminSession 按令牌查找未过期的会话
+func GetAdminSession(token string) (AdminSession, bool) {
+	var s AdminSession
+	if token == "" {
+
+		return s, false
+	}
+
+	Db.Where("token_hash = ? and expired_at > ?", utils.StrSha256(token), time.Now()).Limit(1).Find(&s)
+
+	return s, s.ID > 0
+}
+
+// Valid 账号停用或密码变更后会话失效
+func (s *AdminSession) Valid(u User) bool {
+
+	return u.ID == s.UserId && u.Status == UserStatusEnable && passSum(u) == s.PassSum
+}
+
+func (s *AdminSession) Touch() {
+	if time.Since(s.LastSeenAt) < adminSessionTouch {
+
+		return
+	}
+
+	s.LastSeenAt = time.Now()
+	Db.Model(s).UpdateColumn("last_seen_at", s.LastSeenAt)
+}
+
+func DelAdminSession(token string) {
+	Db.Where("token_hash = ?", utils.StrSha256(token)).Delete(&AdminSession{})
+}
+
+func RevokeAdminSession(id int64) int64 {
+
+	return Db.Where("id = ?", id).Delete(&AdminSession{}).RowsAffected
+}
+
+// RevokeAllAdminSessions 注销全部登录会话，except 为需要保留的会话（通常是操作者自己），传 0 表示不保留
+func RevokeAllAdminSessions(except int64) int64 {
+
+	return Db.Where("id <> ?", except).Delete(&AdminSession{}).RowsAffected
+}
+
+// GetActiveAdminSessions 未过期的登录会话，按最后活跃时间倒序
+func GetActiveAdminSessions() []AdminSession {
+	var list = make([]AdminSession, 0)
+	Db.Where("expired_at > ?", time.Now()).Order("last_seen_at desc").Find(&list)
+
+	return list
+}
+
+func GetWebSession(sid string) (string, bool) {
+	var s WebSession
+	Db.Where("sid = ? and expired_at > ?", sid, time.Now()).Limit(1).Find(&s)
+
+	return s.Data, s.Sid != ""
+}
+
+func SaveWebSession(sid, data string, expiredAt time.Time) error {
+
+	return Db.Save(&WebSession{Sid: sid, Data: data, ExpiredAt: expiredAt}).Error
+}
+
+func DelWebSession(sid string) {
+	Db.Where("sid = ?", sid).Delete(&WebSession{})
+}
+
+// RevokeAllWebSessions 清空浏览器会话，所有人需重新通过安全入口进入
+func RevokeAllWebSessions() {
+	Db.Where("1 = 1").Delete(&WebSession{})
+}
+
+// CleanExpiredSessions 清理已过期的登录会话、浏览器会话与登录失败计数
+func CleanExpiredSessions() {
+	var now = time.Now()
+
+	Db.Where("expired_at <= ?", now).Delete(&AdminSession{})
+	Db.Where("expired_at <= ?", now).Delete(&WebSession{})
+	Db.Where("expired_at <= ?", now).Delete(&LoginGuard{})
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdminSession(t *testing.T) {
+	setupTestDb(t, &User{}, &AdminSession{}, &WebSession{})
+
+	u := User{Username: "admin", Role: RoleOwner, Status: UserStatusEnable}
+	_ = u.SetPassword("123456")
+	Db.Create(&u)
+
+	token, err := NewAdminSession(u, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := GetAdminSession(token)
+	if !ok || !s.Valid(u) {
+		t.Fatal("new session should be valid")
+	}
+	if _, ok = GetAdminSession(token + "x"); ok {
+		t.Fatal("unknown token should not match")
+	}
+
+	_ = u.SetPassword("654321")
+	if s.Valid(u) {
+		t.Fatal("password change should invalidate session")
+	}
+
+	other, _ := NewAdminSession(u, "127.0.0.2", "test")
+	if n := RevokeAllAdminSessions(s.ID); n != 1 {
+		t.Fatalf("revoked = %d", n)
+	}
+	if _, ok = GetAdminSession(other); ok {
+		t.Fatal("revoked session should be gone")
+	}
+	if _, ok = GetAdminSession(token); !ok {
+		t.Fatal("excepted session should be kept")
+	}
+
+	Db.Model(&AdminSession{}).Where("id = ?", s.ID).Update("expired_at", time.Now().Add(-time.Second))
+	if _, ok = GetAdminSession(token); ok {
+		t.Fatal("expired session should not match")
+	}
+
+	CleanExpiredSessions()
+
+	var count int64
+	Db.Model(&AdminSession{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("sessions left = %d", count)
+	}
+}
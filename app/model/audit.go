@@ -0,0 +1,181 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const auditRedacted = "******"
+
+const (
+	AuditActionAdd     = "add"     // 新增
+	AuditActionMod     = "mod"     // 修改
+	AuditActionDel     = "del"     // 删除
+	AuditActionSet     = "set"     // 配置变更
+	AuditActionReset   = "reset"   // 重置密钥、令牌
+	AuditActionPaid    = "paid"    // 手动补单
+	AuditActionNotify  = "notify"  // 手动回调
+	AuditActionCancel  = "cancel"  // 取消订单
+	AuditActionRevoke  = "revoke"  // 注销会话
+	AuditActionRedrive = "redrive" // 死信重新投递
+)
+
+const (
+	AuditTargetConf     = "conf"
+	AuditTargetWallet   = "wallet"
+	AuditTargetOrder    = "order"
+	AuditTargetMerchant = "merchant"
+	AuditTargetUser     = "user"
+	AuditTargetXpub     = "xpub"
+	AuditTargetToken    = "token"
+	AuditTargetSession  = "session"
+	AuditTargetNotify   = "notify"
+)
+
+// auditSecretSuffix 字段名或配置键以这些词结尾时视为敏感信息，审计记录中脱敏保存
+var auditSecretSuffix = []string{"password", "secret", "token", "pass", "api_key", "private_key", "recovery_codes"}
+
+// auditSecretConf 键名无法通过关键词识别的敏感配置
+var auditSecretConf = map[ConfKey]struct{}{
+	NotifierParams: {},
+	AdminSecure:    {},
+}
+
+// auditLabelFields 用于生成对象标签的字段，便于按交易类型、名称等检索
+var auditLabelFields = []string{"trade_type", "network", "crypto", "name", "username"}
+
+// auditIgnoreFields 每次保存都会变化的字段，不计入差异
+var auditIgnoreFields = map[string]struct{}{
+	"created_at": {},
+	"updated_at": {},
+}
+
+// Audit 后台操作审计记录
+type Audit struct {
+	Id
+	UserId   int64  `gorm:"column:user_id;not null;default:0;index;comment:操作账号ID" json:"user_id"`
+	Username string `gorm:"column:username;type:varchar(64);not null;default:'';index;comment:操作账号" json:"username"`
+	Ip       string `gorm:"column:ip;type:varchar(64);not null;default:'';comment:操作IP" json:"ip"`
+	Route    string `gorm:"column:route;type:varchar(128);not null;default:'';comment:请求路由" json:"route"`
+	Action   string `gorm:"column:action;type:varchar(32);not null;index;comment:操作类型" json:"action"`
+	Target   string `gorm:"column:target;type:varchar(32);not null;index:idx_audit_target,priority:1;comment:操作对象" json:"target"`
+	TargetId string `gorm:"column:target_id;type:varchar(128);not null;default:'';index:idx_audit_target,priority:2;comment:操作对象ID" json:"target_id"`
+	Label    string `gorm:"column:label;type:varchar(255);not null;default:'';comment:操作对象标签" json:"label"`
+	Before   string `gorm:"column:before_data;type:text;comment:变更前（仅差异字段）" json:"before"`
+	After    string `gorm:"column:after_data;type:text;comment:变更后（仅差异字段）" json:"after"`
+	AutoTimeAt
+}
+
+func (a *Audit) TableName() string {
+
+	return "bep_audit"
+}
+
+// AddAudit 写入审计记录，before/after 为变更前后的实体（结构体或 map），新增时 before 为 nil，删除时 after 为 nil；
+// 只保存发生变化的字段，敏感字段脱敏
+func AddAudit(a Audit, before, after any) error {
+	b, f := AuditDiff(a.Target, a.TargetId, before, after)
+
+	a.Label = auditLabel(auditMap(before), auditMap(after))
+	a.Before = auditJson(b)
+	a.After = auditJson(f)
+
+	return Db.Create(&a).Error
+}
+
+// AuditDiff 计算变更前后的差异字段并脱敏
+func AuditDiff(target, targetId string, before, after any) (map[string]any, map[string]any) {
+	var b, f = auditMap(before), auditMap(after)
+	var db, df = make(map[string]any), make(map[string]any)
+	for k, v := range b {
+		if _, ok := auditIgnoreFields[k]; ok {
+			continue
+		}
+		if nv, ok := f[k]; !ok || !reflect.DeepEqual(v, nv) {
+			db[k] = v
+		}
+	}
+	for k, v := range f {
+		if _, ok := auditIgnoreFields[k]; ok {
+			continue
+		}
+		if ov, ok := b[k]; !ok || !reflect.DeepEqual(ov, v) {
+			df[k] = v
+		}
+	}
+
+	// 配置项以键名判断是否敏感，其余实体按字段名判断
+	var confSecret = target == AuditTargetConf && isAuditSecret(targetId)
+	for _, m := range []map[string]any{db, df} {
+		for k := range m {
+			if confSecret || isAuditSecret(k) {
+				m[k] = auditRedacted
+			}
+		}
+	}
+
+	return db, df
+}
+
+// auditLabel 优先取变更后的值，删除时取变更前的值
+func auditLabel(before, after map[string]any) string {
+	var parts = make([]string, 0)
+	for _, k := range auditLabelFields {
+		var v, ok = after[k]
+		if !ok {
+			v, ok = before[k]
+		}
+		if s, _ := v.(string); ok && s != "" {
+			parts = append(parts, s)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func isAuditSecret(name string) bool {
+	if _, ok := auditSecretConf[ConfKey(name)]; ok {
+
+		return true
+	}
+
+	name = strings.ToLower(name)
+	for _, w := range auditSecretSuffix {
+		if strings.HasSuffix(name, w) {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func auditMap(v any) map[string]any {
+	var m = make(map[string]any)
+	if v == nil {
+
+		return m
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+
+		return m
+	}
+
+	_ = json.Unmarshal(data, &m)
+
+	return m
+}
+
+func auditJson(m map[string]any) string {
+	if len(m) == 0 {
+
+		return ""
+	}
+
+	data, _ := json.Marshal(m)
+
+	return string(data)
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditDiff(t *testing.T) {
+	before := Wallet{Name: "main", Address: "TOld", MatchAddr: "TOld", TradeType: string(UsdtTrc20), Status: WaStatusEnable}
+	after := before
+	after.Address = "TNew"
+	after.MatchAddr = "TNew"
+
+	b, f := AuditDiff(AuditTargetWallet, "1", before, after)
+	if len(b) != 2 || b["address"] != "TOld" || f["address"] != "TNew" || f["match_addr"] != "TNew" {
+		t.Fatalf("diff = %v -> %v", b, f)
+	}
+
+	b, f = AuditDiff(AuditTargetMerchant, "1", Merchant{Name: "m", Secret: "old"}, Merchant{Name: "m", Secret: "new"})
+	if b["secret"] != auditRedacted || f["secret"] != auditRedacted || len(f) != 1 {
+		t.Fatalf("secret should be redacted: %v -> %v", b, f)
+	}
+
+	b, f = AuditDiff(AuditTargetConf, string(ApiAuthToken), map[string]any{"value": "a"}, map[string]any{"value": "b"})
+	if b["value"] != auditRedacted || f["value"] != auditRedacted {
+		t.Fatalf("secret conf should be redacted: %v -> %v", b, f)
+	}
+
+	b, f = AuditDiff(AuditTargetConf, string(PaymentTimeout), map[string]any{"value": "600"}, map[string]any{"value": "1200"})
+	if b["value"] != "600" || f["value"] != "1200" {
+		t.Fatalf("plain conf = %v -> %v", b, f)
+	}
+
+	b, f = AuditDiff(AuditTargetOrder, "t1", Order{TradeId: "t1"}, nil)
+	if b["trade_id"] != "t1" || len(f) != 0 {
+		t.Fatalf("delete diff = %v -> %v", b, f)
+	}
+}
+
+func TestAddAudit(t *testing.T) {
+	setupTestDb(t, &Audit{})
+
+	var err error
+
+	before := Wallet{Name: "main", Address: "TOld", TradeType: string(UsdtTrc20)}
+	after := before
+	after.Address = "TNew"
+
+	if err = AddAudit(Audit{Username: "admin", Action: AuditActionMod, Target: AuditTargetWallet, TargetId: "1"}, before, after); err != nil {
+		t.Fatal(err)
+	}
+
+	var a Audit
+	Db.Where("label LIKE ?", "%"+string(UsdtTrc20)+"%").Find(&a)
+	if a.ID == 0 || !strings.Contains(a.After, "TNew") || strings.Contains(a.After, "main") {
+		t.Fatalf("audit = %+v", a)
+	}
+}
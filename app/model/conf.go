@@ -25,6 +25,13 @@ var defaultConf = map[ConfKey]string{
 	AtomBNB:                 "0.00001",
 	AtomETH:                 "0.000001",
 	AtomGRAM:                "0.01",
+	AtomPOL:                 "0.0001",
+	AtomOKB:                 "0.00001",
+	AtomXPL:                 "0.0001",
+	AtomSOL:                 "0.00001",
+	AtomAPT:                 "0.0001",
+	AtomBTC:                 "0.000001",
+	AtomLTC:                 "0.0001",
 	MonitorMinAmount:        "0.01",
 	PaymentMinAmount:        "0.01",
 	PaymentMaxAmount:        "99999",
@@ -38,15 +45,30 @@ var defaultConf = map[ConfKey]string{
 	RpcEndpointBase:         "https://base-public.nodies.app/",
 	RpcEndpointAptos:        "https://aptos-rest.publicnode.com/",
 	RpcEndpointPlasma:       "https://rpc.plasma.to/",
+	RpcEndpointBitcoin:      "https://blockstream.info/api/",
+	RpcEndpointLitecoin:     "https://litecoinspace.org/api/",
+	UtxoChain:               "mainnet",
 	RpcGlobalConfigUrlTon:   "https://ton.org/global-config.json",
 	NotifyMaxRetry:          "10",
+	NotifyBackoff:           "1m,2m,4m,8m,16m,32m,1h,2h,4h,8h",
+	NotifyJitter:            "0.2",
+	NotifyMaxAge:            "72h",
+	NotifyWorkers:           "8",
+	LoginFailLimit:          "5",
+	LoginLockTime:           "60",
+	TrustedProxies:          "",
 	BlockHeightMaxDiff:      "1000",
 	BlockOffsetConfirm:      "0",
+	EvmLogFilterNetworks:    "",
 	PaymentTimeout:          "1200",     // 20分钟
 	PaymentCheckout:         "official", // 官方模板
 	PaymentMatchMode:        string(Classic),
+	ApiSignMode:             string(SignAuto),
+	PaymentAccumulate:       "0",
+	PaymentTolerance:        "",
 	PaymentSupportUrl:       "",
 	PaymentLookbackHour:     "3",
+	LookbackApiFallback:     "0",
 	OrderTradeTypeReselect:  "1",
 	SystemInstallLock:       "0",
 	RateSyncCoingeckoApiUrl: "https://api.coingecko.com",
@@ -208,6 +230,16 @@ func AuthToken() string {
 	return GetK(ApiAuthToken)
 }
 
+func GetSignMode() SignMode {
+	switch m := SignMode(GetC(ApiSignMode)); m {
+	case SignMd5, SignHmac:
+
+		return m
+	}
+
+	return SignAuto
+}
+
 func OrderTradeTypeReselectEnabled() bool {
 	return cast.ToBool(GetC(OrderTradeTypeReselect))
 }
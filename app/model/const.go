@@ -8,6 +8,7 @@ type CoinId string
 type Crypto string
 type TradeType string
 type MatchMode string
+type SignMode string
 type Network string
 type Range struct {
 	MinAmount decimal.Decimal
@@ -35,8 +36,18 @@ const (
 	AdminLoginIP  ConfKey = "admin_login_ip"
 	AdminLoginAt  ConfKey = "admin_login_at"
 
+	AdminLoginFailIP   ConfKey = "admin_login_fail_ip"   // 最近一次登录失败 IP
+	AdminLoginFailAt   ConfKey = "admin_login_fail_at"   // 最近一次登录失败时间
+	AdminLoginFailUser ConfKey = "admin_login_fail_user" // 最近一次登录失败使用的账号
+	AdminLoginFailNum  ConfKey = "admin_login_fail_num"  // 登录失败累计次数
+	AdminAllowIps      ConfKey = "admin_allow_ips"       // 后台接口访问 IP 白名单，逗号或换行分隔，支持 CIDR，为空不限制
+	LoginFailLimit     ConfKey = "login_fail_limit"      // 连续登录失败多少次后锁定
+	LoginLockTime      ConfKey = "login_lock_time"       // 首次锁定时长（秒），此后每次锁定翻倍
+	TrustedProxies     ConfKey = "trusted_proxies"       // 受信任的反向代理 IP，支持 CIDR，为空不信任 X-Forwarded-For，重启后生效
+
 	ApiAuthToken ConfKey = "api_auth_token" // API 对接令牌
 	ApiAppUri    ConfKey = "api_app_uri"    // API 对接地址（收银台地址）
+	ApiSignMode  ConfKey = "api_sign_mode"  // API 签名模式
 
 	AtomUSDT ConfKey = "atom_usdt"
 	AtomUSDC ConfKey = "atom_usdc"
@@ -44,6 +55,13 @@ const (
 	AtomBNB  ConfKey = "atom_bnb"
 	AtomETH  ConfKey = "atom_eth"
 	AtomGRAM ConfKey = "atom_gram"
+	AtomPOL  ConfKey = "atom_pol"
+	AtomOKB  ConfKey = "atom_okb"
+	AtomXPL  ConfKey = "atom_xpl"
+	AtomSOL  ConfKey = "atom_sol"
+	AtomAPT  ConfKey = "atom_apt"
+	AtomBTC  ConfKey = "atom_btc"
+	AtomLTC  ConfKey = "atom_ltc"
 
 	MonitorMinAmount       ConfKey = "monitor_min_amount" // 监控最小金额，低于此金额的入账不进行通知
 	PaymentMinAmount       ConfKey = "payment_min_amount"
@@ -51,8 +69,11 @@ const (
 	PaymentTimeout         ConfKey = "payment_timeout"           // 订单支付超时时间，单位秒
 	PaymentCheckout        ConfKey = "payment_checkout"          // 收银台模板
 	PaymentMatchMode       ConfKey = "payment_match_mode"        // 订单金额匹配模式
+	PaymentAccumulate      ConfKey = "payment_accumulate"        // 累计支付，多笔转账凑齐订单数额
+	PaymentTolerance       ConfKey = "payment_tolerance"         // 支付误差策略，按交易类型配置误差百分比
 	PaymentSupportUrl      ConfKey = "payment_support_url"       // 订单支付客服链接
 	PaymentLookbackHour    ConfKey = "payment_lookback_hour"     // 订单回溯时间
+	LookbackApiFallback    ConfKey = "lookback_api_fallback"     // 回溯区块高度本地查找失败时，回退使用远程接口查询
 	OrderTradeTypeReselect ConfKey = "order_trade_type_reselect" // 订单交易类型重选
 
 	RpcEndpointPlasma         ConfKey = "rpc_endpoint_plasma"            // Plasma RPC节点
@@ -66,6 +87,9 @@ const (
 	RpcEndpointAptos          ConfKey = "rpc_endpoint_aptos"             // APTOS RPC节点
 	RpcEndpointTron           ConfKey = "rpc_endpoint_tron"              // TRON RPC节点
 	RpcEndpointTronGridApiKey ConfKey = "rpc_endpoint_tron_grid_api_key" // TRON RPC节点 TronGrid Api Key
+	RpcEndpointBitcoin        ConfKey = "rpc_endpoint_bitcoin"           // Bitcoin 节点，Bitcoin Core JSON-RPC 或 Esplora REST
+	RpcEndpointLitecoin       ConfKey = "rpc_endpoint_litecoin"          // Litecoin 节点，Litecoin Core JSON-RPC 或 Esplora REST
+	UtxoChain                 ConfKey = "utxo_chain"                     // UTXO 链地址格式：mainnet testnet regtest
 	RpcGlobalConfigUrlTon     ConfKey = "rpc_global_config_url_ton"      // Ton Global Config Url
 
 	RateSyncCoingeckoApiUrl ConfKey = "rate_sync_coingecko_api_url" // 汇率同步 Coingecko Api URL
@@ -73,9 +97,14 @@ const (
 	RateSyncInterval        ConfKey = "rate_sync_interval"          // 汇率同步间隔，单位秒
 	RateSyncHistoryDays     ConfKey = "rate_sync_history_days"      // 历史汇率保存天数
 
-	NotifyMaxRetry     ConfKey = "notify_max_retry"      // 最大重试次数，订单回调失败
-	BlockHeightMaxDiff ConfKey = "block_height_max_diff" // 区块高度最大差值，超过此值则以当前区块高度为准，重新开始扫描
-	BlockOffsetConfirm ConfKey = "block_offset_confirm"  // 区块偏移确认数，扫描时以当前区块高度减去此偏移量为准，避免重链导致的订单回调失败
+	NotifyMaxRetry       ConfKey = "notify_max_retry"        // 最大重试次数，订单回调失败
+	NotifyBackoff        ConfKey = "notify_backoff"          // 回调重试间隔曲线，逗号分隔，超出部分沿用最后一项
+	NotifyJitter         ConfKey = "notify_jitter"           // 回调重试间隔随机抖动比例，0-1
+	NotifyMaxAge         ConfKey = "notify_max_age"          // 回调事件最长重试时长，超过后进入死信
+	NotifyWorkers        ConfKey = "notify_workers"          // 回调投递并发数
+	BlockHeightMaxDiff   ConfKey = "block_height_max_diff"   // 区块高度最大差值，超过此值则以当前区块高度为准，重新开始扫描
+	BlockOffsetConfirm   ConfKey = "block_offset_confirm"    // 区块偏移确认数，扫描时以当前区块高度减去此偏移量为准，避免重链导致的订单回调失败
+	EvmLogFilterNetworks ConfKey = "evm_log_filter_networks" // 按钱包地址过滤 eth_getLogs 的 EVM 网络，多个用逗号隔开
 
 	MqttHost        ConfKey = "mqtt_host"
 	MqttPort        ConfKey = "mqtt_port"
@@ -105,12 +134,24 @@ const (
 	BNB  Crypto = "BNB"
 	ETH  Crypto = "ETH"
 	GRAM Crypto = "GRAM" // 其实就是 Ton
+	POL  Crypto = "POL"  // Polygon 原生币，原 MATIC
+	OKB  Crypto = "OKB"
+	XPL  Crypto = "XPL"
+	SOL  Crypto = "SOL"
+	APT  Crypto = "APT"
+	BTC  Crypto = "BTC"
+	LTC  Crypto = "LTC"
 )
 const (
 	Classic   MatchMode = "classic"    // 经典模式，精确匹配
 	HasPrefix MatchMode = "has_prefix" // 前缀匹配，允许多付
 	RoundOff  MatchMode = "round_off"  // 数值修约，四舍五入，允许容错
 )
+const (
+	SignMd5  SignMode = "md5"  // 仅 MD5 参数签名，兼容旧版对接
+	SignHmac SignMode = "hmac" // 仅 HMAC-SHA256 请求头签名
+	SignAuto SignMode = "auto" // 携带签名请求头时按 HMAC 校验，否则按 MD5 校验
+)
 
 // USD 交易类型常见扫描范围
 var usdGeneralRange = Range{
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// ScanCursor 区块扫描游标，记录各网络已连续处理完成的区块高度，重启后从此处继续扫描
+type ScanCursor struct {
+	Network string `gorm:"column:network;type:varchar(32);not null;primaryKey;comment:区块网络" json:"network"`
+	Height  int64  `gorm:"column:height;not null;default:0;comment:已处理高度" json:"height"`
+	AutoTimeAt
+}
+
+func (ScanCursor) TableName() string {
+
+	return "bep_scan_cursor"
+}
+
+// GetScanCursor 获取网络已处理高度，不存在时返回 0
+func GetScanCursor(network string) int64 {
+	var row ScanCursor
+
+	Db.Where("network = ?", network).Limit(1).Find(&row)
+
+	return row.Height
+}
+
+func SetScanCursor(network string, height int64) error {
+	return Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "network"}},
+		DoUpdates: clause.AssignmentColumns([]string{"height", "updated_at"}),
+	}).Create(&ScanCursor{Network: network, Height: height}).Error
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDb 使用临时 SQLite 替换全局 Db 并迁移所需的表，测试结束后恢复原来的 Db
+func setupTestDb(t *testing.T, models ...any) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var old = Db
+	t.Cleanup(func() {
+		Db = old
+		if sqlDb, err := db.DB(); err == nil {
+			_ = sqlDb.Close()
+		}
+	})
+
+	Db = db
+	if err = Db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+}
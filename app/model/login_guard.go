@@ -0,0 +1,146 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/v03413/bepusdt/app/utils"
+	"gorm.io/gorm"
+)
+
+const loginLockMax = time.Hour * 24
+
+var loginGuardLock sync.Mutex
+
+// LoginGuard 登录失败计数，按 IP 与账号分别统计；保存在库中，重启或多实例部署时锁定依然有效
+type LoginGuard struct {
+	Key       string    `gorm:"column:guard_key;type:varchar(128);primaryKey;comment:统计维度（IP或账号）" json:"key"`
+	Fails     int       `gorm:"column:fails;not null;default:0;comment:当前连续失败次数" json:"fails"`
+	Locks     int       `gorm:"column:locks;not null;default:0;comment:已触发锁定次数，决定下次锁定时长" json:"locks"`
+	Until     time.Time `gorm:"column:lock_until;not null;comment:锁定截止时间" json:"lock_until"`
+	ExpiredAt time.Time `gorm:"column:expired_at;not null;index;comment:过期时间，超过后计数清零" json:"expired_at"`
+}
+
+func (g *LoginGuard) TableName() string {
+
+	return "bep_login_guard"
+}
+
+func loginGuardKeys(ip, username string) []string {
+
+	return []string{
+		"ip_" + ip,
+		"user_" + strings.ToLower(strings.TrimSpace(username)),
+	}
+}
+
+func getLoginGuard(db *gorm.DB, key string) LoginGuard {
+	var g LoginGuard
+	db.Where("guard_key = ? and expired_at > ?", key, time.Now()).Limit(1).Find(&g)
+	if g.Key == "" {
+
+		return LoginGuard{Key: key}
+	}
+
+	return g
+}
+
+// LoginLocked 返回 IP 或账号剩余锁定时长，未锁定时返回 0
+func LoginLocked(ip, username string) time.Duration {
+	var left time.Duration
+	for _, key := range loginGuardKeys(ip, username) {
+		if d := time.Until(getLoginGuard(Db, key).Until); d > left {
+			left = d
+		}
+	}
+
+	return left
+}
+
+// LoginFailed 记录一次登录失败，连续失败达到上限后锁定，锁定时长按次数指数增长
+func LoginFailed(ip, username string) {
+	loginGuardLock.Lock()
+	defer loginGuardLock.Unlock()
+
+	var limit = cast.ToInt(GetC(LoginFailLimit))
+	if limit <= 0 {
+		limit = cast.ToInt(defaultConf[LoginFailLimit])
+	}
+	var base = time.Duration(cast.ToInt64(GetC(LoginLockTime))) * time.Second
+	if base <= 0 {
+		base = time.Duration(cast.ToInt64(defaultConf[LoginLockTime])) * time.Second
+	}
+
+	if err := Db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range loginGuardKeys(ip, username) {
+			var g = getLoginGuard(tx, key)
+
+			g.Fails++
+			if g.Fails >= limit {
+				var d = base << min(g.Locks, 16)
+				if d > loginLockMax || d <= 0 {
+					d = loginLockMax
+				}
+
+				g.Fails = 0
+				g.Locks++
+				g.Until = time.Now().Add(d)
+			}
+
+			g.ExpiredAt = time.Now().Add(loginLockMax)
+			if err := tx.Save(&g).Error; err != nil {
+
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, fmt.Sprintf("登录失败计数写入错误：%s", err.Error()))
+	}
+
+	SetK(AdminLoginFailIP, ip)
+	SetK(AdminLoginFailAt, time.Now().Format(time.DateTime))
+	SetK(AdminLoginFailUser, username)
+	SetK(AdminLoginFailNum, cast.ToString(cast.ToInt(GetK(AdminLoginFailNum))+1))
+}
+
+// LoginSucceeded 登录成功后清除对应 IP 与账号的失败计数
+func LoginSucceeded(ip, username string) {
+	Db.Where("guard_key in ?", loginGuardKeys(ip, username)).Delete(&LoginGuard{})
+}
+
+// ClearLoginGuards 清空全部登录失败计数与锁定
+func ClearLoginGuards() {
+	Db.Where("1 = 1").Delete(&LoginGuard{})
+}
+
+// AdminIpAllowed 判断 IP 是否允许访问后台接口，白名单配置有误时不做限制，避免管理员被锁在外面
+func AdminIpAllowed(ip string) bool {
+	list, err := utils.ParseIpAllowList(GetC(AdminAllowIps))
+	if err != nil {
+
+		return true
+	}
+
+	return utils.IpAllowed(ip, list)
+}
+
+// CheckAdminAllowIps 校验待保存的白名单格式，并要求包含当前操作者 IP
+func CheckAdminAllowIps(raw, ip string) error {
+	list, err := utils.ParseIpAllowList(raw)
+	if err != nil {
+
+		return err
+	}
+	if !utils.IpAllowed(ip, list) {
+
+		return fmt.Errorf("白名单未包含当前 IP %s，保存后将无法访问后台", ip)
+	}
+
+	return nil
+}
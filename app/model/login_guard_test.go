@@ -0,0 +1,81 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginGuard(t *testing.T) {
+	setupTestDb(t, &Conf{}, &LoginGuard{}, &AdminSession{}, &WebSession{})
+
+	var err error
+
+	SetK(LoginFailLimit, "3")
+	SetK(LoginLockTime, "60")
+	RefreshC()
+	t.Cleanup(func() {
+		confCache.Delete(LoginFailLimit)
+		confCache.Delete(LoginLockTime)
+	})
+
+	for i := 0; i < 2; i++ {
+		LoginFailed("10.0.0.1", "admin")
+	}
+	if d := LoginLocked("10.0.0.1", "admin"); d != 0 {
+		t.Fatalf("locked too early: %s", d)
+	}
+
+	LoginFailed("10.0.0.1", "admin")
+	if d := LoginLocked("10.0.0.2", "Admin"); d <= 50*time.Second || d > time.Minute {
+		t.Fatalf("username lock = %s", d)
+	}
+	if d := LoginLocked("10.0.0.1", "other"); d <= 0 {
+		t.Fatal("ip should be locked")
+	}
+
+	for i := 0; i < 3; i++ {
+		LoginFailed("10.0.0.1", "admin")
+	}
+	if d := LoginLocked("10.0.0.1", "admin"); d <= 110*time.Second || d > 2*time.Minute {
+		t.Fatalf("second lock should double: %s", d)
+	}
+	if GetK(AdminLoginFailNum) != "6" || GetK(AdminLoginFailIP) != "10.0.0.1" {
+		t.Fatalf("fail record = %s %s", GetK(AdminLoginFailNum), GetK(AdminLoginFailIP))
+	}
+
+	// 锁定保存在库中，其他实例或重启后同样生效
+	var g LoginGuard
+	Db.Where("guard_key = ?", "user_admin").Find(&g)
+	if g.Locks != 2 || time.Until(g.Until) <= 110*time.Second {
+		t.Fatalf("persisted guard = %+v", g)
+	}
+
+	LoginSucceeded("10.0.0.1", "admin")
+	if d := LoginLocked("10.0.0.1", "admin"); d != 0 {
+		t.Fatalf("success should reset guard: %s", d)
+	}
+
+	// 超过有效期的计数不再生效，并由定时任务清理
+	for i := 0; i < 3; i++ {
+		LoginFailed("10.0.0.3", "guest")
+	}
+	Db.Model(&LoginGuard{}).Where("guard_key = ?", "ip_10.0.0.3").Update("expired_at", time.Now().Add(-time.Second))
+	if d := LoginLocked("10.0.0.3", "nobody"); d != 0 {
+		t.Fatalf("expired guard should be ignored: %s", d)
+	}
+
+	CleanExpiredSessions()
+
+	var count int64
+	Db.Model(&LoginGuard{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("guards after clean = %d", count)
+	}
+
+	if err = CheckAdminAllowIps("192.168.0.0/16", "10.0.0.1"); err == nil {
+		t.Fatal("allowlist without current ip should be rejected")
+	}
+	if err = CheckAdminAllowIps("10.0.0.0/8", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/v03413/bepusdt/app/conf"
+)
+
+const (
+	MerchantStatusEnable  uint8 = 1
+	MerchantStatusDisable uint8 = 0
+)
+
+// Merchant 商户，ID 为 0 的默认商户沿用原有固定 PID 与 API 对接令牌，兼容单商户部署
+type Merchant struct {
+	Id
+	Name       string `gorm:"column:name;type:varchar(64);not null;default:'';comment:商户名称" json:"name"`
+	Pid        string `gorm:"column:pid;type:varchar(32);not null;uniqueIndex;comment:商户号" json:"pid"`
+	Secret     string `gorm:"column:secret;type:varchar(128);not null;comment:签名密钥" json:"secret"`
+	Fiat       Fiat   `gorm:"column:fiat;type:varchar(16);not null;default:CNY;comment:默认法币" json:"fiat"`
+	TradeTypes string `gorm:"column:trade_types;type:varchar(1024);not null;default:'';comment:允许的交易类型，逗号分隔，留空不限制" json:"trade_types"`
+	Status     uint8  `gorm:"column:status;not null;default:1;comment:状态" json:"status"`
+	Remark     string `gorm:"column:remark;type:varchar(255);not null;default:'';comment:备注" json:"remark"`
+	AutoTimeAt
+}
+
+func (m *Merchant) TableName() string {
+
+	return "bep_merchant"
+}
+
+func (m *Merchant) Validate() error {
+	m.Pid = strings.TrimSpace(m.Pid)
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Pid == "" || m.Pid == conf.Pid {
+
+		return fmt.Errorf("商户号不能为空且不能使用默认商户号 %s", conf.Pid)
+	}
+	if _, ok := supportFiat[m.Fiat]; !ok {
+
+		return fmt.Errorf("不支持的法币类型：%s", m.Fiat)
+	}
+
+	for _, t := range m.GetTradeTypes() {
+		if !IsSupportedTradeType(t) {
+
+			return fmt.Errorf("不支持的交易类型：%s", t)
+		}
+	}
+
+	if m.Secret == "" {
+		m.Secret = NewMerchantSecret()
+	}
+	if len(m.Secret) < 16 {
+
+		return errors.New("签名密钥长度不能少于 16 位")
+	}
+
+	return nil
+}
+
+func (m *Merchant) IsDefault() bool {
+
+	return m.ID == 0
+}
+
+func (m *Merchant) GetTradeTypes() []TradeType {
+	var list = make([]TradeType, 0)
+	for _, t := range strings.Split(m.TradeTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			list = append(list, TradeType(t))
+		}
+	}
+
+	return list
+}
+
+// AllowTradeType 判断商户是否允许使用该交易类型，未配置时不限制
+func (m *Merchant) AllowTradeType(t TradeType) bool {
+	var list = m.GetTradeTypes()
+	if len(list) == 0 {
+
+		return true
+	}
+
+	for _, v := range list {
+		if v == t {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultFiat 请求未指定法币时使用商户默认法币
+func (m *Merchant) DefaultFiat(f Fiat) Fiat {
+	if f != "" {
+
+		return f
+	}
+	if m.Fiat != "" {
+
+		return m.Fiat
+	}
+
+	return CNY
+}
+
+func NewMerchantSecret() string {
+	var b = make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// DefaultMerchant 默认商户，使用固定 PID 与系统 API 对接令牌
+func DefaultMerchant() Merchant {
+
+	return Merchant{Name: "默认商户", Pid: conf.Pid, Secret: AuthToken(), Fiat: CNY, Status: MerchantStatusEnable}
+}
+
+// GetMerchantByPid 按商户号查找启用的商户，未传或使用默认商户号时返回默认商户
+func GetMerchantByPid(pid string) (Merchant, bool) {
+	if pid == "" || pid == conf.Pid {
+
+		return DefaultMerchant(), true
+	}
+
+	var m Merchant
+	Db.Where("pid = ? and status = ?", pid, MerchantStatusEnable).Limit(1).Find(&m)
+
+	return m, m.ID != 0
+}
+
+// GetMerchant 按 ID 查找商户，用于回调签名，已停用的商户同样返回
+func GetMerchant(id int64) Merchant {
+	if id == 0 {
+
+		return DefaultMerchant()
+	}
+
+	var m Merchant
+	Db.Where("id = ?", id).Limit(1).Find(&m)
+	if m.ID == 0 {
+
+		return DefaultMerchant()
+	}
+
+	return m
+}
+
+func (o *Order) GetMerchant() Merchant {
+
+	return GetMerchant(o.MerchantId)
+}
+
+// MerchantExists 校验商户 ID，0 为默认商户
+func MerchantExists(id int64) bool {
+	if id == 0 {
+
+		return true
+	}
+
+	var count int64
+	Db.Model(&Merchant{}).Where("id = ?", id).Count(&count)
+
+	return count > 0
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestMerchantScope(t *testing.T) {
+	setupTestDb(t, &Merchant{}, &Wallet{})
+
+	var err error
+	var reserved = Merchant{Name: "m", Pid: "1000", Fiat: CNY}
+	if reserved.Validate() == nil {
+		t.Fatal("default pid should be reserved")
+	}
+
+	var m = Merchant{Name: "shop", Pid: "1001", Fiat: USD, TradeTypes: "usdt.trc20, usdt.polygon"}
+	if err = m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Secret) != 32 {
+		t.Fatalf("secret = %q, want generated", m.Secret)
+	}
+
+	Db.Create(&m)
+
+	if !m.AllowTradeType(UsdtTrc20) || m.AllowTradeType(TronTrx) {
+		t.Fatal("trade type whitelist mismatch")
+	}
+	if m.DefaultFiat("") != USD || m.DefaultFiat(EUR) != EUR {
+		t.Fatal("default fiat mismatch")
+	}
+	if got, ok := GetMerchantByPid("1001"); !ok || got.ID != m.ID {
+		t.Fatal("merchant 1001 not found")
+	}
+
+	m.Status = MerchantStatusDisable
+	Db.Save(&m)
+	if _, ok := GetMerchantByPid("1001"); ok {
+		t.Fatal("disabled merchant should not be resolved")
+	}
+
+	Db.Create(&Wallet{Address: "TA", MatchAddr: "TA", TradeType: string(UsdtTrc20), Status: WaStatusEnable})
+	Db.Create(&Wallet{Address: "TB", MatchAddr: "TB", TradeType: string(UsdtTrc20), Status: WaStatusEnable, MerchantId: m.ID})
+
+	if w := GetAvailableWallets(UsdtTrc20, m.ID); len(w) != 1 || w[0].Address != "TB" {
+		t.Fatalf("merchant wallets = %+v", w)
+	}
+	if w := GetAvailableWallets(UsdtTrc20, 0); len(w) != 1 || w[0].Address != "TA" {
+		t.Fatalf("default wallets = %+v", w)
+	}
+}
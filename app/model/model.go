@@ -77,7 +77,9 @@ func initSqlite(path string) error {
 	}
 
 	FillDefaultConf()
+	InitOwner()
 	RefreshC()
+	RefreshTokens()
 
 	return nil
 }
@@ -120,7 +122,9 @@ func initMySQL(dsn string) error {
 	}
 
 	FillDefaultConf()
+	InitOwner()
 	RefreshC()
+	RefreshTokens()
 
 	return nil
 }
@@ -160,13 +164,15 @@ func initPostgres(dsn string) error {
 	}
 
 	FillDefaultConf()
+	InitOwner()
 	RefreshC()
+	RefreshTokens()
 
 	return nil
 }
 
 func AutoMigrate() error {
-	return Db.AutoMigrate(&Wallet{}, &Order{}, &NotifyRecord{}, &Conf{}, &Rate{})
+	return Db.AutoMigrate(&Wallet{}, &Order{}, &NotifyRecord{}, &Conf{}, &Rate{}, &ScanCursor{}, &Token{}, &Xpub{}, &XpubAddress{}, &OrderPayment{}, &OrderEvent{}, &Merchant{}, &NotifyAttempt{}, &NotifyOutbox{}, &User{}, &AdminSession{}, &WebSession{}, &LoginGuard{}, &Audit{})
 }
 
 func Close() {
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/v03413/bepusdt/app/log"
+	"gorm.io/gorm"
+)
+
+const (
+	NotifyKindCallback = "callback" // 订单支付回调
+	NotifyKindStatus   = "status"   // 订单状态推送
+	NotifyKindReplay   = "replay"   // 后台重放
+
+	notifyResponseMaxLen = 1024
+)
+
+// NotifyAttempt 回调投递记录，每次请求一条
+type NotifyAttempt struct {
+	Id
+	TradeId    string `gorm:"column:trade_id;type:varchar(128);not null;index;comment:本地ID" json:"trade_id"`
+	Kind       string `gorm:"column:kind;type:varchar(16);not null;comment:投递类型" json:"kind"`
+	Method     string `gorm:"column:method;type:varchar(8);not null;comment:请求方法" json:"method"`
+	Url        string `gorm:"column:url;type:text;not null;comment:请求地址" json:"url"`
+	Payload    string `gorm:"column:payload;type:text;not null;comment:请求内容" json:"payload"`
+	HttpStatus int    `gorm:"column:http_status;not null;default:0;comment:响应状态码" json:"http_status"`
+	Response   string `gorm:"column:response;type:varchar(1024);not null;default:'';comment:响应内容片段" json:"response"`
+	Latency    int64  `gorm:"column:latency;not null;default:0;comment:耗时（毫秒）" json:"latency"`
+	Error      string `gorm:"column:error;type:varchar(512);not null;default:'';comment:错误信息" json:"error"`
+	Success    bool   `gorm:"column:success;not null;default:false;comment:是否成功" json:"success"`
+	ReplayOf   int64  `gorm:"column:replay_of;not null;default:0;comment:重放来源记录ID" json:"replay_of"`
+	AutoTimeAt
+}
+
+func (a *NotifyAttempt) TableName() string {
+
+	return "bep_notify_attempt"
+}
+
+// SetResponse 截取响应内容片段，避免商户返回整页 HTML 时占用过多空间
+func (a *NotifyAttempt) SetResponse(body []byte) {
+	a.Response = truncateUtf8(string(body), notifyResponseMaxLen)
+}
+
+func (a *NotifyAttempt) SetError(err error) {
+	a.Success = err == nil
+	if err != nil {
+		a.Error = truncateUtf8(err.Error(), 512)
+	}
+}
+
+// SaveNotifyAttempt 记录失败不影响回调流程，只写日志
+func SaveNotifyAttempt(db *gorm.DB, a *NotifyAttempt) {
+	if err := db.Create(a).Error; err != nil {
+		log.Warn("回调投递记录保存失败：", a.TradeId, err.Error())
+	}
+}
+
+func GetNotifyAttempt(id int64) (NotifyAttempt, bool) {
+	var a NotifyAttempt
+	Db.Where("id = ?", id).Limit(1).Find(&a)
+
+	return a, a.ID != 0
+}
+
+func (o *Order) GetNotifyAttempts() []NotifyAttempt {
+	var list = make([]NotifyAttempt, 0)
+	Db.Where("trade_id = ?", o.TradeId).Order("id desc").Find(&list)
+
+	return list
+}
+
+func truncateUtf8(s string, max int) string {
+	if len(s) <= max {
+
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:max], "")
+}
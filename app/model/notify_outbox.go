@@ -0,0 +1,204 @@
+package model
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxStatePending uint8 = 0 // 待投递
+	OutboxStateDone    uint8 = 1 // 投递完成
+	OutboxStateDead    uint8 = 2 // 死信，重试耗尽或超过最长重试时长
+
+	outboxLease = time.Minute // 领取后的租约时长，投递进程异常退出时到期后重新投递
+)
+
+// NotifyOutbox 回调事件发件箱，与订单状态变更在同一事务内写入，由投递任务异步发送
+type NotifyOutbox struct {
+	Id
+	TradeId     string    `gorm:"column:trade_id;type:varchar(128);not null;index;comment:本地ID" json:"trade_id"`
+	Kind        string    `gorm:"column:kind;type:varchar(16);not null;comment:事件类型" json:"kind"`
+	OrderStatus int       `gorm:"column:order_status;not null;comment:事件对应的订单状态" json:"order_status"`
+	State       uint8     `gorm:"column:state;not null;default:0;index:idx_outbox_due,priority:1;comment:投递状态" json:"state"`
+	Attempts    int       `gorm:"column:attempts;not null;default:0;comment:已投递次数" json:"attempts"`
+	NextAt      time.Time `gorm:"column:next_at;not null;index:idx_outbox_due,priority:2;comment:下次投递时间" json:"next_at"`
+	LastError   string    `gorm:"column:last_error;type:varchar(512);not null;default:'';comment:最后一次错误" json:"last_error"`
+	AutoTimeAt
+}
+
+func (e *NotifyOutbox) TableName() string {
+
+	return "bep_notify_outbox"
+}
+
+// NotifyPolicy 回调重试策略
+type NotifyPolicy struct {
+	Backoff  []time.Duration
+	Jitter   float64
+	MaxAge   time.Duration
+	MaxRetry int
+	Workers  int
+}
+
+func GetNotifyPolicy() NotifyPolicy {
+	var p = NotifyPolicy{
+		Backoff:  parseBackoff(GetC(NotifyBackoff)),
+		Jitter:   cast.ToFloat64(GetC(NotifyJitter)),
+		MaxRetry: cast.ToInt(GetC(NotifyMaxRetry)),
+		Workers:  cast.ToInt(GetC(NotifyWorkers)),
+	}
+	if len(p.Backoff) == 0 {
+		p.Backoff = parseBackoff(defaultConf[NotifyBackoff])
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = 0
+	}
+	if p.MaxRetry <= 0 {
+		p.MaxRetry = cast.ToInt(defaultConf[NotifyMaxRetry])
+	}
+	if p.Workers <= 0 {
+		p.Workers = cast.ToInt(defaultConf[NotifyWorkers])
+	}
+
+	p.MaxAge, _ = time.ParseDuration(GetC(NotifyMaxAge))
+	if p.MaxAge <= 0 {
+		p.MaxAge, _ = time.ParseDuration(defaultConf[NotifyMaxAge])
+	}
+
+	return p
+}
+
+func parseBackoff(s string) []time.Duration {
+	var list = make([]time.Duration, 0)
+	for _, v := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err == nil && d > 0 {
+			list = append(list, d)
+		}
+	}
+
+	return list
+}
+
+// Delay 第 attempts 次投递失败后的等待时长，超出曲线长度时沿用最后一项，并按比例随机抖动
+func (p NotifyPolicy) Delay(attempts int) time.Duration {
+	var i = attempts - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(p.Backoff) {
+		i = len(p.Backoff) - 1
+	}
+
+	var d = float64(p.Backoff[i])
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(rand.Float64()*2-1)
+	}
+
+	return time.Duration(d)
+}
+
+// outboxKinds 订单变更为目标状态（含新建的等待支付）后需要投递的回调事件
+func (o *Order) outboxKinds(to int) []string {
+	var kinds = make([]string, 0)
+	if IsPaidStatus(to) {
+		kinds = append(kinds, NotifyKindCallback)
+	}
+	if (to == OrderStatusWaiting || to == OrderStatusExpired || to == OrderStatusFailed) && o.statusPush() {
+		kinds = append(kinds, NotifyKindStatus)
+	}
+
+	return kinds
+}
+
+// statusPush 只有 BEpusdt 接口创建的订单推送状态变更
+func (o *Order) statusPush() bool {
+
+	return o.ApiType == OrderApiTypeEpusdt || o.ApiType == OrderApiTypeEpusdtOrder
+}
+
+func enqueueNotify(tx *gorm.DB, o *Order, kind string) error {
+
+	return tx.Create(&NotifyOutbox{TradeId: o.TradeId, Kind: kind, OrderStatus: o.Status, NextAt: time.Now()}).Error
+}
+
+// ClaimDueOutbox 领取到期的待投递事件；领取即把下次投递时间推迟一个租约，多实例部署时同一事件只会被一个实例领取
+func ClaimDueOutbox(limit int) []NotifyOutbox {
+	var rows = make([]NotifyOutbox, 0)
+	var now = time.Now()
+
+	Db.Where("state = ? and next_at <= ?", OutboxStatePending, now).Order("next_at asc").Limit(limit).Find(&rows)
+
+	var claimed = make([]NotifyOutbox, 0, len(rows))
+	for _, e := range rows {
+		res := Db.Model(&NotifyOutbox{}).Where("id = ? and state = ? and attempts = ? and next_at <= ?", e.ID, OutboxStatePending, e.Attempts, now).
+			Update("next_at", now.Add(outboxLease))
+		if res.Error == nil && res.RowsAffected == 1 {
+			claimed = append(claimed, e)
+		}
+	}
+
+	return claimed
+}
+
+func (e *NotifyOutbox) Done() error {
+	e.Attempts++
+	e.State = OutboxStateDone
+	e.LastError = ""
+
+	return Db.Model(e).Select("attempts", "state", "last_error").Updates(e).Error
+}
+
+// Fail 记录投递失败，重试次数耗尽或超过最长重试时长时进入死信
+func (e *NotifyOutbox) Fail(err error, p NotifyPolicy) error {
+	e.Attempts++
+	e.LastError = truncateUtf8(err.Error(), 512)
+	e.NextAt = time.Now().Add(p.Delay(e.Attempts))
+
+	if e.Attempts > p.MaxRetry || (e.CreatedAt != nil && time.Since(e.CreatedAt.Time()) > p.MaxAge) {
+		e.State = OutboxStateDead
+	}
+
+	return Db.Model(e).Select("attempts", "state", "last_error", "next_at").Updates(e).Error
+}
+
+// Redrive 死信重新投递，重试次数与时长重新计算
+func (e *NotifyOutbox) Redrive() error {
+	if e.State != OutboxStateDead {
+
+		return errors.New("只有死信事件可以重新投递")
+	}
+
+	var now = Datetime(time.Now())
+
+	e.State = OutboxStatePending
+	e.Attempts = 0
+	e.NextAt = time.Now()
+	e.CreatedAt = &now
+
+	return Db.Model(e).Select("state", "attempts", "next_at", "created_at").Updates(e).Error
+}
+
+// BackfillNotifyOutbox 升级前回调失败、尚未进入发件箱的订单补写回调事件，已有事件的订单不重复写入
+func BackfillNotifyOutbox() {
+	var orders = make([]Order, 0)
+	Db.Where("status in (?) and notify_state = ? and notify_num <= ?", PaidStatuses(), OrderNotifyStateFail, GetNotifyPolicy().MaxRetry).
+		Where("not exists (?)", Db.Model(&NotifyOutbox{}).Select("1").Where("bep_notify_outbox.trade_id = bep_order.trade_id")).
+		Find(&orders)
+
+	for _, o := range orders {
+		_ = enqueueNotify(Db, &o, NotifyKindCallback)
+	}
+}
+
+func GetNotifyOutbox(id int64) (NotifyOutbox, bool) {
+	var e NotifyOutbox
+	Db.Where("id = ?", id).Limit(1).Find(&e)
+
+	return e, e.ID != 0
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNotifyOutbox(t *testing.T) {
+	setupTestDb(t, &Order{}, &OrderEvent{}, &OrderPayment{}, &NotifyOutbox{})
+
+	var err error
+	zero := time.Unix(0, 0)
+	o := Order{TradeId: "t1", RefHash: "t1", TradeType: UsdtTrc20, ApiType: OrderApiTypeEpusdt, Amount: "10", Status: OrderStatusWaiting, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+	Db.Create(&o)
+
+	if err = o.SetExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.SetManualPaid("admin", "0xhash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []NotifyOutbox
+	Db.Order("id asc").Find(&rows)
+	if len(rows) != 2 || rows[0].Kind != NotifyKindStatus || rows[1].Kind != NotifyKindCallback || rows[1].OrderStatus != OrderStatusSuccess {
+		t.Fatalf("outbox = %+v", rows)
+	}
+
+	claimed := ClaimDueOutbox(10)
+	if len(claimed) != 2 || len(ClaimDueOutbox(10)) != 0 {
+		t.Fatalf("claimed = %d, second claim should be empty", len(claimed))
+	}
+
+	p := NotifyPolicy{Backoff: []time.Duration{time.Minute, time.Hour}, MaxRetry: 2, MaxAge: time.Hour, Workers: 1}
+	if p.Delay(1) != time.Minute || p.Delay(5) != time.Hour {
+		t.Fatalf("delay = %v %v", p.Delay(1), p.Delay(5))
+	}
+
+	e := claimed[1]
+	for i := 0; i < 3; i++ {
+		if err = e.Fail(errors.New("timeout"), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.State != OutboxStateDead || e.Attempts != 3 {
+		t.Fatalf("state = %d attempts = %d, want dead", e.State, e.Attempts)
+	}
+
+	if err = e.Redrive(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ClaimDueOutbox(10); len(got) != 1 || got[0].ID != e.ID {
+		t.Fatalf("redrive claim = %+v", got)
+	}
+}
+
+func TestBuildOrderEnqueueStatus(t *testing.T) {
+	setupTestDb(t, &Order{}, &OrderEvent{}, &OrderPayment{}, &NotifyOutbox{})
+
+	// 等待支付的状态推送与订单在同一事务写入发件箱
+	o, err := BuildOrder(OrderParams{OrderId: "o1", ApiType: OrderApiTypeEpusdt, NotifyUrl: "http://127.0.0.1/notify", Timeout: 600}, Trade{Crypto: USDT, Amount: "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = BuildOrder(OrderParams{OrderId: "o2", ApiType: OrderApiTypeEpay, Timeout: 600}, Trade{Crypto: USDT, Amount: "10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []NotifyOutbox
+	Db.Find(&rows)
+	if len(rows) != 1 || rows[0].TradeId != o.TradeId || rows[0].Kind != NotifyKindStatus || rows[0].OrderStatus != OrderStatusWaiting {
+		t.Fatalf("outbox = %+v", rows)
+	}
+}
@@ -11,10 +11,10 @@ import (
 
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cast"
-	"github.com/v03413/bepusdt/app/conf"
 	"github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/utils"
+	"gorm.io/gorm"
 )
 
 const (
@@ -27,9 +27,20 @@ const (
 	OrderStatusCanceled   = 4 // 订单取消
 	OrderStatusConfirming = 5 // 等待交易确认
 	OrderStatusFailed     = 6 // 交易确认失败
+	OrderStatusUnderpaid  = 7 // 少付，误差范围内已收款
+	OrderStatusOverpaid   = 8 // 多付，误差范围内已收款
 
 	BscBnb      TradeType = "bsc.bnb"
 	EthereumEth TradeType = "ethereum.eth"
+	ArbitrumEth TradeType = "arbitrum.eth"
+	BaseEth     TradeType = "base.eth"
+	PolygonPol  TradeType = "polygon.pol"
+	XlayerOkb   TradeType = "xlayer.okb"
+	PlasmaXpl   TradeType = "plasma.xpl"
+	SolanaSol   TradeType = "solana.sol"
+	AptosApt    TradeType = "aptos.apt"
+	BitcoinBtc  TradeType = "btc.bitcoin"
+	LitecoinLtc TradeType = "ltc.litecoin"
 	TronTrx     TradeType = "tron.trx"
 	TonGram     TradeType = "ton.gram"
 
@@ -63,6 +74,7 @@ const (
 
 type Order struct {
 	Id
+	MerchantId        int64      `gorm:"column:merchant_id;not null;default:0;index;comment:商户ID" json:"merchant_id"`
 	OrderId           string     `gorm:"column:order_id;type:varchar(128);not null;index;comment:商户ID" json:"order_id"`
 	TradeId           string     `gorm:"column:trade_id;type:varchar(128);not null;uniqueIndex;comment:本地ID" json:"trade_id"`
 	TradeType         TradeType  `gorm:"column:trade_type;type:varchar(20);not null;index;comment:交易类型" json:"trade_type"`
@@ -72,6 +84,7 @@ type Order struct {
 	CurrencyLimit     string     `gorm:"column:currency_limit;type:varchar(255);not null;default:'';comment:限定币种" json:"currency_limit"`
 	Rate              string     `gorm:"column:rate;type:varchar(10);not null;comment:交易汇率" json:"rate"`
 	Amount            string     `gorm:"column:amount;type:varchar(32);not null;default:0.00;comment:交易数额" json:"amount"`
+	PaidAmount        string     `gorm:"column:paid_amount;type:varchar(32);not null;default:0;comment:累计已付数额" json:"paid_amount"`
 	Money             string     `gorm:"column:money;type:varchar(32);not null;default:0.00;comment:交易金额" json:"money"`
 	Address           string     `gorm:"column:address;type:varchar(128);index;not null;comment:收款地址" json:"address"`
 	FromAddress       string     `gorm:"column:from_address;type:varchar(128);not null;default:'';comment:支付地址" json:"from_address"`
@@ -103,11 +116,10 @@ type MethodItem struct {
 	IsPopular       bool   `json:"is_popular"`
 }
 
-func (o *Order) SetCanceled() error {
+func (o *Order) SetCanceled(source string) error {
 	core.New()
-	o.Status = OrderStatusCanceled
 
-	return Db.Save(o).Error
+	return o.transition(OrderStatusCanceled, OrderEvent{Source: source}, nil)
 }
 
 // CanReselectPayment 判断订单是否支持重选交易类型
@@ -123,48 +135,125 @@ func (o *Order) CanReselectPayment() bool {
 	return o.TradeTypeReselect
 }
 
-func (o *Order) SetExpired() {
-	o.Status = OrderStatusExpired
+func (o *Order) SetExpired() error {
 
-	Db.Save(o)
+	return o.transition(OrderStatusExpired, OrderEvent{Source: EventSource(EventSourceTask, "expire")}, nil)
 }
 
-func (o *Order) SetSuccess() {
-	o.Status = OrderStatusSuccess
+// SetPaid 交易确认完成，按实收数额区分成功、少付与多付
+func (o *Order) SetPaid(status int) error {
+	var ev = OrderEvent{Source: o.scannerSource(), TxHash: o.RefHash, BlockNum: o.RefBlockNum}
 
-	Db.Save(o)
+	return o.transition(status, ev, nil)
 }
 
-func (o *Order) SetFailed() {
-	o.Status = OrderStatusFailed
+// SetManualPaid 管理员手动补单
+func (o *Order) SetManualPaid(source, hash string) error {
+	var ev = OrderEvent{Source: source, TxHash: hash, Remark: "手动补单"}
 
-	Db.Save(o)
+	return o.transition(OrderStatusSuccess, ev, func() []string {
+		now := time.Now()
+		o.RefHash = hash
+		o.ConfirmedAt = &now
+
+		return []string{"ref_hash", "confirmed_at"}
+	})
+}
+
+// IsPaid 订单已收款，包含误差范围内的少付与多付
+func (o *Order) IsPaid() bool {
+
+	return IsPaidStatus(o.Status)
+}
+
+func IsPaidStatus(status int) bool {
+
+	return status == OrderStatusSuccess || status == OrderStatusUnderpaid || status == OrderStatusOverpaid
+}
+
+func PaidStatuses() []int {
+
+	return []int{OrderStatusSuccess, OrderStatusUnderpaid, OrderStatusOverpaid}
+}
+
+// GetDiffAmount 实收数额与订单数额的差值，少付为负数
+func (o *Order) GetDiffAmount() string {
+	paid, err := decimal.NewFromString(o.PaidAmount)
+	if err != nil || paid.IsZero() {
+
+		return "0"
+	}
+
+	amount, _ := decimal.NewFromString(o.Amount)
+
+	return paid.Sub(amount).String()
+}
+
+func (o *Order) SetFailed() error {
+	var ev = OrderEvent{Source: EventSource(EventSourceTask, "confirm"), TxHash: o.RefHash, BlockNum: o.RefBlockNum, Remark: "订单过期前交易未确认"}
+
+	return o.transition(OrderStatusFailed, ev, nil)
 }
 
 func (o *Order) MarkConfirming(blockNum int, from, hash string, at time.Time, amount decimal.Decimal) error {
-	o.FromAddress = from
-	o.ConfirmedAt = &at
-	o.RefHash = hash
-	o.RefBlockNum = blockNum
-	o.Status = OrderStatusConfirming
-	if o.AddressLocked {
-		rate, _ := decimal.NewFromString(o.Rate)
-		o.Amount = amount.String()
-		o.Money = rate.Mul(amount).String()
-	}
-
-	return Db.Save(o).Error
+	var ev = OrderEvent{Source: o.scannerSource(), TxHash: hash, BlockNum: blockNum, Remark: "到账 " + amount.String()}
+
+	return o.transition(OrderStatusConfirming, ev, func() []string {
+		var columns = []string{"from_address", "confirmed_at", "ref_hash", "ref_block_num"}
+
+		o.FromAddress = from
+		o.ConfirmedAt = &at
+		o.RefHash = hash
+		o.RefBlockNum = blockNum
+		if !IsPaymentAccumulate() {
+			o.PaidAmount = amount.String()
+			columns = append(columns, "paid_amount")
+		}
+		if o.AddressLocked {
+			rate, _ := decimal.NewFromString(o.Rate)
+			o.Amount = amount.String()
+			o.Money = rate.Mul(amount).String()
+			columns = append(columns, "amount", "money")
+		}
+
+		return columns
+	})
 }
 
+// RollbackWaiting 关联交易已不在链上（例如区块重组），订单回退为等待支付；失效的收款记录由调用方通过 RemovePayment 移除
+func (o *Order) RollbackWaiting(reason string) error {
+	var ev = OrderEvent{Source: o.scannerSource(), TxHash: o.RefHash, BlockNum: o.RefBlockNum, Remark: reason}
+
+	return o.transition(OrderStatusWaiting, ev, func() []string {
+		zero := time.Unix(0, 0)
+		o.FromAddress = ""
+		o.ConfirmedAt = &zero
+		o.RefHash = o.TradeId
+		o.RefBlockNum = 0
+
+		return []string{"from_address", "confirmed_at", "ref_hash", "ref_block_num"}
+	})
+}
+
+// SetNotifyState 只更新回调相关字段，订单状态统一由 transition 写入，避免旧快照覆盖并发的状态变更
 func (o *Order) SetNotifyState(state int) error {
 	o.NotifyNum += 1
 	o.NotifyState = state
 
-	return Db.Save(o).Error
+	return Db.Model(o).UpdateColumns(map[string]any{
+		"notify_num":   gorm.Expr("notify_num + 1"),
+		"notify_state": state,
+	}).Error
 }
 
 func (o *Order) GetStatusLabel() string {
 	label := "🟢收款成功"
+	if o.Status == OrderStatusUnderpaid {
+		label = "🟠少付收款"
+	}
+	if o.Status == OrderStatusOverpaid {
+		label = "🔵多付收款"
+	}
 	if o.Status == OrderStatusExpired {
 		label = "🔴交易过期"
 	}
@@ -180,6 +269,12 @@ func (o *Order) GetStatusLabel() string {
 
 func (o *Order) GetStatusEmoji() string {
 	label := "🟢"
+	if o.Status == OrderStatusUnderpaid {
+		label = "🟠"
+	}
+	if o.Status == OrderStatusOverpaid {
+		label = "🔵"
+	}
 	if o.Status == OrderStatusExpired {
 		label = "🔴"
 	}
@@ -199,7 +294,7 @@ func (o *Order) GetTxUrl() string {
 
 func (o *Order) RedirectUrl() string {
 	var redirect = o.ReturnUrl
-	if o.Status == OrderStatusSuccess && o.ApiType == OrderApiTypeEpay {
+	if o.IsPaid() && o.ApiType == OrderApiTypeEpay {
 		redirect = fmt.Sprintf("%s?%s", redirect, o.BuildNotifyParams())
 	}
 
@@ -207,12 +302,46 @@ func (o *Order) RedirectUrl() string {
 }
 
 func (o *Order) BuildNotifyParams() string {
-	var signStr = utils.Md5String(fmt.Sprintf("money=%s&name=%s&out_trade_no=%s&pid=%s&trade_no=%s&trade_status=TRADE_SUCCESS&type=%s",
-		cast.ToString(o.Money), o.Name, o.OrderId, conf.Pid, o.TradeId, o.TradeType) + AuthToken())
-	var params = fmt.Sprintf("money=%s&name=%s&out_trade_no=%s&pid=%s&trade_no=%s&trade_status=TRADE_SUCCESS&type=%s",
-		cast.ToString(o.Money), url.QueryEscape(o.Name), url.QueryEscape(o.OrderId), conf.Pid, o.TradeId, o.TradeType)
+	var m = o.GetMerchant()
+	var data = map[string]string{
+		"money":        cast.ToString(o.Money),
+		"name":         o.Name,
+		"out_trade_no": o.OrderId,
+		"pid":          m.Pid,
+		"trade_no":     o.TradeId,
+		"trade_status": "TRADE_SUCCESS",
+		"type":         string(o.TradeType),
+	}
+	if o.Status == OrderStatusUnderpaid || o.Status == OrderStatusOverpaid { // 误差收款额外携带实收数额与差值
+		data["received_amount"] = o.PaidAmount
+		data["diff_amount"] = o.GetDiffAmount()
+	}
+
+	var hmacSign = GetSignMode() == SignHmac // 仅 HMAC 模式下回调改用 HMAC-SHA256 签名，并携带防重放参数
+	if hmacSign {
+		data["timestamp"] = cast.ToString(time.Now().Unix())
+		data["nonce"] = utils.Md5String(o.TradeId + data["timestamp"])[:16]
+	}
+
+	var keys = make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var signStr, params = make([]string, 0), make([]string, 0)
+	for _, k := range keys {
+		signStr = append(signStr, k+"="+data[k])
+		params = append(params, k+"="+url.QueryEscape(data[k]))
+	}
+
+	if hmacSign {
 
-	return fmt.Sprintf("%s&sign=%s", params, signStr)
+		return fmt.Sprintf("%s&sign=%s&sign_type=HMAC-SHA256", strings.Join(params, "&"), utils.HmacSha256(m.Secret, strings.Join(signStr, "&")))
+	}
+
+	return fmt.Sprintf("%s&sign=%s", strings.Join(params, "&"), utils.Md5String(strings.Join(signStr, "&")+m.Secret))
 }
 
 func (o *Order) GetMethods(crypto Crypto) []MethodItem {
@@ -225,6 +354,7 @@ func (o *Order) GetMethods(crypto Crypto) []MethodItem {
 	}
 
 	allTrades := GetAllTradeConfig()
+	merchant := o.GetMerchant()
 
 	// 解析限定币种
 	var whitelist = make(map[string]bool)
@@ -256,8 +386,13 @@ func (o *Order) GetMethods(crypto Crypto) []MethodItem {
 			continue
 		}
 
+		// 商户未开通的交易类型不展示
+		if !merchant.AllowTradeType(TradeType(tradeTypeStr)) {
+			continue
+		}
+
 		// 检查是否有可用钱包
-		count := len(GetAvailableWallets(TradeType(tradeTypeStr)))
+		count := len(GetAvailableWallets(TradeType(tradeTypeStr), o.MerchantId))
 		if count == 0 {
 			continue
 		}
@@ -316,7 +451,7 @@ func (o *Order) Network() any {
 	}
 
 	var net = network{}
-	info, ok := registry[o.TradeType]
+	info, ok := getTradeConf(o.TradeType)
 	if !ok {
 		return net
 	}
@@ -348,20 +483,6 @@ func GetOrderByStatus(Status int) []Order {
 	return orders
 }
 
-func GetNotifyFailedTradeOrders() ([]Order, error) {
-	var orders []Order
-	maxRetry := cast.ToInt(GetC(NotifyMaxRetry))
-	if maxRetry <= 0 {
-		maxRetry = cast.ToInt(defaultConf[NotifyMaxRetry])
-	}
-
-	res := Db.Where("status = ?", OrderStatusSuccess).
-		Where("notify_state = ?", OrderNotifyStateFail).
-		Where("notify_num <= ?", maxRetry).Find(&orders)
-
-	return orders, res.Error
-}
-
 // CalcTradeAmount 计算当前实际可用的交易金额
 func CalcTradeAmount(wallets []Wallet, rate decimal.Decimal, p OrderParams) (Wallet, string, error) {
 	if p.AddressLocked {
@@ -406,6 +527,25 @@ func CalcTradeAmount(wallets []Wallet, rate decimal.Decimal, p OrderParams) (Wal
 	}
 }
 
+// CalcExactAmount 独立收款地址按原始金额收款，不做原子精度递增
+func CalcExactAmount(rate decimal.Decimal, p OrderParams) (string, error) {
+	if p.AddressLocked {
+		return decimal.Zero.String(), nil
+	}
+
+	atom, precision := GetAtomicity(p.TradeType)
+	if rate.LessThanOrEqual(decimal.Zero) || precision <= 0 {
+		return "", errors.New(fmt.Sprintf("[%v - %v]原子颗粒度计算异常，联系管理员处理！", atom, precision))
+	}
+
+	amount := p.Money.DivRound(rate, precision)
+	if amount.LessThan(atom) {
+		amount = atom
+	}
+
+	return amount.String(), nil
+}
+
 // LockTradeAddress 检测交易地址，独占使用
 func LockTradeAddress(wallets []Wallet, t TradeType) (Wallet, string, error) {
 	zero := decimal.Zero.String()
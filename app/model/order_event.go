@@ -0,0 +1,154 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventSourceApi     = "api"     // 商户接口
+	EventSourceAdmin   = "admin"   // 管理后台
+	EventSourceTask    = "task"    // 定时任务
+	EventSourceScanner = "scanner" // 区块扫描
+)
+
+// OrderEvent 订单状态流转记录
+type OrderEvent struct {
+	Id
+	TradeId   string `gorm:"column:trade_id;type:varchar(128);not null;index;comment:本地ID" json:"trade_id"`
+	OldStatus int    `gorm:"column:old_status;not null;default:0;comment:原状态" json:"old_status"`
+	NewStatus int    `gorm:"column:new_status;not null;default:0;comment:新状态" json:"new_status"`
+	Source    string `gorm:"column:source;type:varchar(64);not null;default:'';comment:变更来源" json:"source"`
+	TxHash    string `gorm:"column:tx_hash;type:varchar(128);not null;default:'';comment:交易哈希" json:"tx_hash"`
+	BlockNum  int    `gorm:"column:block_num;not null;default:0;comment:区块索引" json:"block_num"`
+	Remark    string `gorm:"column:remark;type:varchar(255);not null;default:'';comment:备注" json:"remark"`
+	AutoTimeAt
+}
+
+// orderTransitions 订单状态机，key 为当前状态，value 为允许流转的目标状态
+var orderTransitions = map[int][]int{
+	0:                     {OrderStatusWaiting},
+	OrderStatusWaiting:    {OrderStatusConfirming, OrderStatusExpired, OrderStatusCanceled, OrderStatusSuccess},
+	OrderStatusExpired:    {OrderStatusConfirming, OrderStatusSuccess},
+	OrderStatusConfirming: {OrderStatusSuccess, OrderStatusUnderpaid, OrderStatusOverpaid, OrderStatusFailed, OrderStatusWaiting},
+	OrderStatusFailed:     {OrderStatusSuccess},
+	OrderStatusUnderpaid:  {OrderStatusSuccess},
+	OrderStatusOverpaid:   {OrderStatusSuccess},
+}
+
+var orderStatusText = map[int]string{
+	OrderStatusWaiting:    "等待支付",
+	OrderStatusSuccess:    "交易成功",
+	OrderStatusExpired:    "交易过期",
+	OrderStatusCanceled:   "交易取消",
+	OrderStatusConfirming: "等待确认",
+	OrderStatusFailed:     "确认失败",
+	OrderStatusUnderpaid:  "少付收款",
+	OrderStatusOverpaid:   "多付收款",
+}
+
+var ErrOrderStatusChanged = errors.New("订单状态已变更，请刷新后重试")
+
+func (e *OrderEvent) TableName() string {
+
+	return "bep_order_event"
+}
+
+// EventSource 变更来源，例如 scanner:tron、admin:root
+func EventSource(kind, name string) string {
+	if name == "" {
+
+		return kind
+	}
+
+	return kind + ":" + name
+}
+
+func CanTransition(from, to int) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func OrderStatusText(status int) string {
+	if text, ok := orderStatusText[status]; ok {
+
+		return text
+	}
+
+	return fmt.Sprintf("未知状态(%d)", status)
+}
+
+// transition 校验并执行状态流转，仅当数据库中的状态仍为当前状态时写入，同时记录流转事件并写入待投递的回调；
+// apply 修改流转附带的字段并返回对应列名，只写入状态与这些列，避免旧快照覆盖其它并发更新的字段
+func (o *Order) transition(to int, ev OrderEvent, apply func() []string) error {
+	var from = o.Status
+	if !CanTransition(from, to) {
+
+		return fmt.Errorf("订单状态不允许从「%s」变更为「%s」", OrderStatusText(from), OrderStatusText(to))
+	}
+
+	var backup = *o
+	var columns = []string{"status"}
+	if apply != nil {
+		columns = append(columns, apply()...)
+	}
+
+	o.Status = to
+
+	err := Db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(o).Where("status = ?", from).Select(columns).Updates(o)
+		if res.Error != nil {
+
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+
+			return ErrOrderStatusChanged
+		}
+
+		ev.TradeId = o.TradeId
+		ev.OldStatus = from
+		ev.NewStatus = to
+		if err := tx.Create(&ev).Error; err != nil {
+
+			return err
+		}
+
+		for _, kind := range o.outboxKinds(to) {
+			if err := enqueueNotify(tx, o, kind); err != nil {
+
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		*o = backup
+	}
+
+	return err
+}
+
+func (o *Order) GetEvents() []OrderEvent {
+	var list = make([]OrderEvent, 0)
+
+	Db.Where("trade_id = ?", o.TradeId).Order("id asc").Find(&list)
+
+	return list
+}
+
+// scannerSource 区块扫描来源，按订单交易类型所属网络区分
+func (o *Order) scannerSource() string {
+	c, _ := getTradeConf(o.TradeType)
+
+	return EventSource(EventSourceScanner, string(c.Network))
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderTransition(t *testing.T) {
+	setupTestDb(t, &Order{}, &OrderEvent{}, &OrderPayment{}, &NotifyOutbox{})
+
+	var err error
+	zero := time.Unix(0, 0)
+	o := Order{TradeId: "t1", RefHash: "t1", TradeType: UsdtTrc20, Amount: "10", Status: OrderStatusWaiting, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+	Db.Create(&o)
+
+	if err = o.SetExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.RollbackWaiting("reorg"); err == nil {
+		t.Fatal("expired → waiting should be rejected")
+	}
+	if o.Status != OrderStatusExpired {
+		t.Fatalf("status = %d, want %d", o.Status, OrderStatusExpired)
+	}
+
+	if err = o.MarkConfirming(100, "from", "0xhash", time.Now(), decimal.RequireFromString("10")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其它协程持有的旧数据不能覆盖已变更的状态
+	var stale Order
+	Db.Where("trade_id = ?", "t1").First(&stale)
+	if err = o.SetPaid(OrderStatusSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if err = stale.SetFailed(); err != ErrOrderStatusChanged {
+		t.Fatalf("err = %v, want %v", err, ErrOrderStatusChanged)
+	}
+
+	// 回调结果只更新回调字段，不回写旧状态
+	if err = stale.SetNotifyState(OrderNotifyStateFail); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved Order
+	Db.Where("trade_id = ?", "t1").First(&saved)
+	if saved.Status != OrderStatusSuccess || saved.RefHash != "0xhash" || saved.NotifyNum != 1 {
+		t.Fatalf("unexpected order: status=%d hash=%s notify_num=%d", saved.Status, saved.RefHash, saved.NotifyNum)
+	}
+
+	events := saved.GetEvents()
+	if len(events) != 3 {
+		t.Fatalf("events = %d, want 3", len(events))
+	}
+	if events[1].OldStatus != OrderStatusExpired || events[1].NewStatus != OrderStatusConfirming || events[1].TxHash != "0xhash" || events[1].Source != "scanner:tron" {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+}
+
+func TestTransitionKeepsOtherColumns(t *testing.T) {
+	setupTestDb(t, &Order{}, &OrderEvent{}, &OrderPayment{}, &NotifyOutbox{})
+
+	zero := time.Unix(0, 0)
+	o := Order{TradeId: "t2", RefHash: "t2", TradeType: UsdtTrc20, Amount: "10", Status: OrderStatusWaiting, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+	Db.Create(&o)
+
+	// 状态未变期间其它协程更新的回调与实收字段，流转时不能被旧快照覆盖
+	var other Order
+	Db.Where("trade_id = ?", "t2").First(&other)
+	if err := other.SetNotifyState(OrderNotifyStateFail); err != nil {
+		t.Fatal(err)
+	}
+	Db.Model(&other).UpdateColumn("paid_amount", "4")
+
+	if err := o.SetExpired(); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved Order
+	Db.Where("trade_id = ?", "t2").First(&saved)
+	if saved.Status != OrderStatusExpired || saved.NotifyNum != 1 || saved.PaidAmount != "4" {
+		t.Fatalf("unexpected order: status=%d notify_num=%d paid_amount=%s", saved.Status, saved.NotifyNum, saved.PaidAmount)
+	}
+}
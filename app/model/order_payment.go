@@ -0,0 +1,115 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+// OrderPayment 订单收款记录，累计支付模式下一个订单可由多笔转账凑齐
+type OrderPayment struct {
+	Id
+	TradeId     string    `gorm:"column:trade_id;type:varchar(128);not null;uniqueIndex:idx_order_payment;comment:本地ID" json:"trade_id"`
+	TxHash      string    `gorm:"column:tx_hash;type:varchar(128);not null;uniqueIndex:idx_order_payment;comment:交易哈希" json:"tx_hash"`
+	FromAddress string    `gorm:"column:from_address;type:varchar(128);not null;default:'';comment:支付地址" json:"from_address"`
+	Amount      string    `gorm:"column:amount;type:varchar(32);not null;default:0;comment:交易数额" json:"amount"`
+	BlockNum    int       `gorm:"column:block_num;not null;default:0;comment:区块索引" json:"block_num"`
+	PaidAt      time.Time `gorm:"column:paid_at;not null;comment:交易时间" json:"paid_at"`
+	AutoTimeAt
+}
+
+func (p *OrderPayment) TableName() string {
+
+	return "bep_order_payment"
+}
+
+func IsPaymentAccumulate() bool {
+
+	return GetC(PaymentAccumulate) == "1"
+}
+
+// AddPayment 记录订单收款并刷新累计已付数额，同一笔交易重复扫描只记录一次
+func (o *Order) AddPayment(blockNum int, from, hash string, at time.Time, amount decimal.Decimal) (decimal.Decimal, error) {
+	var p = OrderPayment{
+		TradeId:     o.TradeId,
+		TxHash:      hash,
+		FromAddress: from,
+		Amount:      amount.String(),
+		BlockNum:    blockNum,
+		PaidAt:      at,
+	}
+	if err := Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&p).Error; err != nil {
+
+		return decimal.Zero, err
+	}
+
+	return o.refreshPaidAmount()
+}
+
+// RemovePayment 关联交易失效（例如区块重组）时移除收款记录
+func (o *Order) RemovePayment(hash string) error {
+	if err := Db.Where("trade_id = ? and tx_hash = ?", o.TradeId, hash).Delete(&OrderPayment{}).Error; err != nil {
+
+		return err
+	}
+
+	_, err := o.refreshPaidAmount()
+
+	return err
+}
+
+func (o *Order) refreshPaidAmount() (decimal.Decimal, error) {
+	var paid = decimal.Zero
+	for _, p := range o.GetPayments() {
+		paid = paid.Add(decimal.RequireFromString(p.Amount))
+	}
+
+	o.PaidAmount = paid.String()
+
+	return paid, Db.Model(o).Update("paid_amount", o.PaidAmount).Error
+}
+
+func (o *Order) GetPayments() []OrderPayment {
+	var list = make([]OrderPayment, 0)
+
+	Db.Where("trade_id = ?", o.TradeId).Order("paid_at asc, id asc").Find(&list)
+
+	return list
+}
+
+// GetOrdersPayments 一次查询多个订单的收款记录，按交易编号分组，用于列表避免逐单查询
+func GetOrdersPayments(tradeIds []string) map[string][]OrderPayment {
+	var result = make(map[string][]OrderPayment, len(tradeIds))
+	if len(tradeIds) == 0 {
+
+		return result
+	}
+
+	var list = make([]OrderPayment, 0)
+	Db.Where("trade_id IN ?", tradeIds).Order("paid_at asc, id asc").Find(&list)
+	for _, p := range list {
+		result[p.TradeId] = append(result[p.TradeId], p)
+	}
+
+	return result
+}
+
+// GetPaymentNum 订单已收款笔数
+func (o *Order) GetPaymentNum() int64 {
+	var num int64
+
+	Db.Model(&OrderPayment{}).Where("trade_id = ?", o.TradeId).Count(&num)
+
+	return num
+}
+
+// GetPaymentHashes 订单全部收款交易哈希
+func (o *Order) GetPaymentHashes() []string {
+	var list = make([]string, 0)
+	for _, p := range o.GetPayments() {
+		list = append(list, p.TxHash)
+	}
+
+	return list
+}
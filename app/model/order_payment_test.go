@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderPaymentAccumulate(t *testing.T) {
+	setupTestDb(t, &Order{}, &OrderPayment{})
+
+	zero := time.Unix(0, 0)
+	o := Order{TradeId: "p1", RefHash: "p1", TradeType: UsdtTrc20, Amount: "10", Status: OrderStatusWaiting, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+	Db.Create(&o)
+
+	var add = func(hash, amount string) decimal.Decimal {
+		paid, err := o.AddPayment(100, "from", hash, time.Now(), decimal.RequireFromString(amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return paid
+	}
+
+	if paid := add("0x1", "9.5"); paid.String() != "9.5" {
+		t.Fatalf("paid = %s, want 9.5", paid)
+	}
+
+	// 同一笔交易重复扫描只计一次
+	if paid := add("0x1", "9.5"); paid.String() != "9.5" {
+		t.Fatalf("duplicate hash counted: paid = %s", paid)
+	}
+	if paid := add("0x2", "0.5"); paid.String() != "10" {
+		t.Fatalf("paid = %s, want 10", paid)
+	}
+
+	var saved Order
+	Db.Where("trade_id = ?", "p1").First(&saved)
+	if saved.PaidAmount != "10" || saved.GetPaymentNum() != 2 {
+		t.Fatalf("paid_amount = %s, payments = %d", saved.PaidAmount, saved.GetPaymentNum())
+	}
+
+	if err := o.RemovePayment("0x1"); err != nil {
+		t.Fatal(err)
+	}
+	if o.PaidAmount != "0.5" {
+		t.Fatalf("paid after remove = %s, want 0.5", o.PaidAmount)
+	}
+}
@@ -54,7 +54,7 @@ func CoingeckoRate() error {
 
 	var ids = make([]string, 0)
 	var tokens = make(map[CoinId]Crypto)
-	for token, id := range supportCrypto {
+	for token, id := range GetSupportCrypto() {
 		ids = append(ids, string(id))
 		tokens[id] = token
 	}
@@ -2,7 +2,9 @@ package model
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/shopspring/decimal"
 	"github.com/v03413/bepusdt/app/conf"
@@ -25,6 +27,13 @@ var supportCrypto = map[Crypto]CoinId{
 	BNB:  "binancecoin",
 	ETH:  "ethereum",
 	GRAM: "the-open-network",
+	POL:  "polygon-ecosystem-token",
+	OKB:  "okb",
+	XPL:  "plasma",
+	SOL:  "solana",
+	APT:  "aptos",
+	BTC:  "bitcoin",
+	LTC:  "litecoin",
 }
 
 // TradeType 交易类型，当下类型开始增多，现在这里统一管理、尽量收缩配置项
@@ -57,7 +66,7 @@ var registry = map[TradeType]TradeTypeConf{
 		NetworkName:  "Tron",
 		Network:      conf.Tron,
 		Crypto:       USDT,
-		Contract:     "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", // 占位，目前实际没使用
+		Contract:     "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
 		Decimal:      conf.UsdtTronDecimals,
 		AmountRange:  usdGeneralRange,
 		ExplorerFmt:  "https://tronscan.org/#/transaction/%s",
@@ -213,7 +222,7 @@ var registry = map[TradeType]TradeTypeConf{
 		NetworkName:  "Tron",
 		Network:      conf.Tron,
 		Crypto:       USDC,
-		Contract:     "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8", // 占位，目前实际没使用
+		Contract:     "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8",
 		Decimal:      conf.UsdcTronDecimals,
 		AmountRange:  usdGeneralRange,
 		ExplorerFmt:  "https://tronscan.org/#/transaction/%s",
@@ -286,6 +295,135 @@ var registry = map[TradeType]TradeTypeConf{
 		ExplorerFmt: "https://bscscan.com/tx/%s",
 		EndpointKey: RpcEndpointBsc,
 	},
+	ArbitrumEth: {
+		Alias:       "Arbitrum・Eth",
+		NetworkName: "Arbitrum",
+		Network:     conf.Arbitrum,
+		Crypto:      ETH,
+		Native:      true,
+		Decimal:     conf.ArbitrumEthDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.000001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://arbiscan.io/tx/%s",
+		EndpointKey: RpcEndpointArbitrum,
+	},
+	BaseEth: {
+		Alias:       "Base・Eth",
+		NetworkName: "Base",
+		Network:     conf.Base,
+		Crypto:      ETH,
+		Native:      true,
+		Decimal:     conf.BaseEthDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.000001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://basescan.org/tx/%s",
+		EndpointKey: RpcEndpointBase,
+	},
+	PolygonPol: {
+		Alias:       "Polygon・Pol",
+		NetworkName: "Polygon",
+		Network:     conf.Polygon,
+		Crypto:      POL,
+		Native:      true,
+		Decimal:     conf.PolygonPolDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.0001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://polygonscan.com/tx/%s",
+		EndpointKey: RpcEndpointPolygon,
+	},
+	XlayerOkb: {
+		Alias:       "X Layer・Okb",
+		NetworkName: "X Layer",
+		Network:     conf.Xlayer,
+		Crypto:      OKB,
+		Native:      true,
+		Decimal:     conf.XlayerOkbDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.00001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://web3.okx.com/zh-hans/explorer/x-layer/tx/%s",
+		EndpointKey: RpcEndpointXlayer,
+	},
+	PlasmaXpl: {
+		Alias:       "Plasma・Xpl",
+		NetworkName: "Plasma",
+		Network:     conf.Plasma,
+		Crypto:      XPL,
+		Native:      true,
+		Decimal:     conf.PlasmaXplDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.0001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://plasmascan.to/tx/%s",
+		EndpointKey: RpcEndpointPlasma,
+	},
+	SolanaSol: {
+		Alias:       "Solana・Sol",
+		NetworkName: "Solana",
+		Network:     conf.Solana,
+		Crypto:      SOL,
+		Native:      true,
+		Decimal:     conf.SolanaSolDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.00001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt:  "https://solscan.io/tx/%s",
+		EndpointKey:  RpcEndpointSolana,
+		AddrCaseSens: true,
+	},
+	AptosApt: {
+		Alias:       "Aptos・Apt",
+		NetworkName: "Aptos",
+		Network:     conf.Aptos,
+		Crypto:      APT,
+		Native:      true,
+		Decimal:     conf.AptosAptDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.0001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt: "https://explorer.aptoslabs.com/txn/%s",
+		EndpointKey: RpcEndpointAptos,
+	},
+	BitcoinBtc: {
+		Alias:       "Bitcoin・Btc",
+		NetworkName: "Bitcoin",
+		Network:     conf.Bitcoin,
+		Crypto:      BTC,
+		Native:      true,
+		Decimal:     conf.BitcoinBtcDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.000001),
+			MaxAmount: decimal.NewFromFloat(10000),
+		},
+		ExplorerFmt:  "https://mempool.space/tx/%s",
+		EndpointKey:  RpcEndpointBitcoin,
+		AddrCaseSens: true,
+	},
+	LitecoinLtc: {
+		Alias:       "Litecoin・Ltc",
+		NetworkName: "Litecoin",
+		Network:     conf.Litecoin,
+		Crypto:      LTC,
+		Native:      true,
+		Decimal:     conf.LitecoinLtcDecimals,
+		AmountRange: Range{
+			MinAmount: decimal.NewFromFloat(0.0001),
+			MaxAmount: decimal.NewFromFloat(1000000),
+		},
+		ExplorerFmt:  "https://litecoinspace.org/tx/%s",
+		EndpointKey:  RpcEndpointLitecoin,
+		AddrCaseSens: true,
+	},
 	TonGram: {
 		Alias:       "Ton・Gram",
 		NetworkName: "Ton",
@@ -303,30 +441,93 @@ var registry = map[TradeType]TradeTypeConf{
 	},
 }
 
+// registryMu 注册表在自定义代币变更后会整体重建，读取统一加读锁
+var registryMu sync.RWMutex
+
+// builtinRegistry 内置交易类型与币种，自定义代币在此基础上叠加
+var builtinRegistry = make(map[TradeType]TradeTypeConf)
+var builtinCrypto = make(map[Crypto]CoinId)
+
 func init() {
 	for t, c := range registry {
-		cryptoAtomKeys[c.Crypto] = ConfKey(fmt.Sprintf("atom_%s", strings.ToLower(string(c.Crypto))))
-		explorerUrlMap[t] = c.ExplorerFmt
-		networkTradesMap[c.Network] = append(networkTradesMap[c.Network], t)
-		networkEndpointMap[c.Network] = c.EndpointKey
+		builtinRegistry[t] = c
+	}
+	for c, id := range supportCrypto {
+		builtinCrypto[c] = id
+	}
+
+	rebuildRegistry(registry, supportCrypto)
+}
+
+// rebuildRegistry 替换注册表并重建派生索引
+func rebuildRegistry(reg map[TradeType]TradeTypeConf, crypto map[Crypto]CoinId) {
+	var atomKeys = make(map[Crypto]ConfKey)
+	var explorer = make(map[TradeType]string)
+	var netTrades = make(map[Network][]TradeType)
+	var netEndpoint = make(map[Network]ConfKey)
+	var contractDecimal = make(map[string]int32)
+	var contractTrade = make(map[string]TradeType)
+	var amountRange = make(map[TradeType]Range)
+
+	var types = make([]TradeType, 0, len(reg))
+	for t := range reg {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, t := range types {
+		c := reg[t]
+		atomKeys[c.Crypto] = ConfKey(fmt.Sprintf("atom_%s", strings.ToLower(string(c.Crypto))))
+		explorer[t] = c.ExplorerFmt
+		netTrades[c.Network] = append(netTrades[c.Network], t)
+		netEndpoint[c.Network] = c.EndpointKey
 		if c.Contract != "" {
-			contractDecimalMap[c.Contract] = c.Decimal
-			contractTradeMap[c.Contract] = t
+			contractDecimal[contractKey(c.Network, c.Contract)] = c.Decimal
+			contractTrade[contractKey(c.Network, c.Contract)] = t
 		}
 		if c.AmountRange != (Range{}) {
-			tradeAmountRangeMap[t] = c.AmountRange
+			amountRange[t] = c.AmountRange
 		}
 	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = reg
+	supportCrypto = crypto
+	cryptoAtomKeys = atomKeys
+	explorerUrlMap = explorer
+	networkTradesMap = netTrades
+	networkEndpointMap = netEndpoint
+	contractDecimalMap = contractDecimal
+	contractTradeMap = contractTrade
+	tradeAmountRangeMap = amountRange
+}
+
+// contractKey 合约索引按网络区分，不同 EVM 网络可能存在相同地址的合约
+func contractKey(net Network, addr string) string {
+
+	return string(net) + "|" + addr
+}
+
+func getTradeConf(t TradeType) (TradeTypeConf, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, ok := registry[t]
+
+	return c, ok
 }
 
 func IsSupportedTradeType(t TradeType) bool {
-	_, ok := registry[t]
+	_, ok := getTradeConf(t)
 
 	return ok
 }
 
 func GetCrypto(t TradeType) (Crypto, error) {
-	if c, ok := registry[t]; ok {
+	if c, ok := getTradeConf(t); ok {
 		return c.Crypto, nil
 	}
 	return "", fmt.Errorf("unsupported trade type: %s", t)
@@ -335,6 +536,9 @@ func GetCrypto(t TradeType) (Crypto, error) {
 func GetAllAlias() map[string]string {
 	var alias = make(map[string]string)
 
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	for t, c := range registry {
 		alias[string(t)] = c.Alias
 	}
@@ -345,6 +549,9 @@ func GetAllAlias() map[string]string {
 func GetAllTradeConfig() map[string]TradeTypeConf {
 	var config = make(map[string]TradeTypeConf)
 
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	for t, c := range registry {
 		config[string(t)] = c
 	}
@@ -353,6 +560,9 @@ func GetAllTradeConfig() map[string]TradeTypeConf {
 }
 
 func GetNetworkTrades(n Network) []TradeType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	list, ok := networkTradesMap[n]
 	if !ok {
 		return []TradeType{}
@@ -361,14 +571,34 @@ func GetNetworkTrades(n Network) []TradeType {
 	return list
 }
 
-func GetContractTrade(addr string) (TradeType, bool) {
-	t, ok := contractTradeMap[addr]
+// GetNetworkContracts 网络下所有代币合约地址
+func GetNetworkContracts(n Network) []string {
+	var list = make([]string, 0)
+	for _, t := range GetNetworkTrades(n) {
+		if c, _ := getTradeConf(t); c.Contract != "" {
+			list = append(list, c.Contract)
+		}
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+func GetContractTrade(net Network, addr string) (TradeType, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	t, ok := contractTradeMap[contractKey(net, addr)]
 
 	return t, ok
 }
 
-func GetContractDecimal(addr string) int32 {
-	if d, ok := contractDecimalMap[addr]; ok {
+func GetContractDecimal(net Network, addr string) int32 {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if d, ok := contractDecimalMap[contractKey(net, addr)]; ok {
 
 		return d
 	}
@@ -377,6 +607,9 @@ func GetContractDecimal(addr string) int32 {
 }
 
 func GetTxUrl(t TradeType, hash string) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	if url, ok := explorerUrlMap[t]; ok {
 		if url == "" {
 			return ""
@@ -388,7 +621,7 @@ func GetTxUrl(t TradeType, hash string) string {
 }
 
 func GetTradeDecimal(t TradeType) int32 {
-	c, ok := registry[t]
+	c, ok := getTradeConf(t)
 	if !ok {
 
 		return -6
@@ -398,7 +631,9 @@ func GetTradeDecimal(t TradeType) int32 {
 }
 
 func IsAmountValid(t TradeType, d decimal.Decimal) bool {
+	registryMu.RLock()
 	r, ok := tradeAmountRangeMap[t]
+	registryMu.RUnlock()
 	if !ok {
 
 		return false
@@ -417,14 +652,96 @@ func IsAmountValid(t TradeType, d decimal.Decimal) bool {
 	return true
 }
 
+func getEndpointKey(net Network) (ConfKey, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	k, ok := networkEndpointMap[net]
+
+	return k, ok
+}
+
+// Endpoint 当前可用的 RPC 节点，配置多个节点时按健康状态自动切换
 func Endpoint(net Network) string {
-	if endpointKey, ok := networkEndpointMap[net]; ok {
-		return GetC(endpointKey)
+
+	return conf.PickEndpoint(string(net), Endpoints(net))
+}
+
+// Endpoints 网络 RPC 节点列表，支持逗号或换行分隔配置多个节点，按顺序优先使用
+func Endpoints(net Network) []string {
+	endpointKey, ok := getEndpointKey(net)
+	if !ok {
+		return []string{}
+	}
+
+	var list = make([]string, 0)
+	for _, v := range splitEndpoints(GetC(endpointKey)) {
+		if !isWsEndpoint(v) {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// WsEndpoint 网络 WebSocket 节点（ws:// 或 wss:// 开头），未配置时返回空
+func WsEndpoint(net Network) string {
+	endpointKey, ok := getEndpointKey(net)
+	if !ok {
+		return ""
+	}
+
+	for _, v := range splitEndpoints(GetC(endpointKey)) {
+		if isWsEndpoint(v) {
+			return v
+		}
 	}
+
 	return ""
 }
 
+func splitEndpoints(val string) []string {
+	var list = make([]string, 0)
+	for _, v := range strings.FieldsFunc(val, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+func isWsEndpoint(url string) bool {
+	url = strings.ToLower(url)
+
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+// GetEndpointNetworks 支持多节点切换的网络（TON 使用全局配置文件，由客户端自行负载）
+func GetEndpointNetworks() []Network {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var list = make([]Network, 0)
+	for net := range networkEndpointMap {
+		if net == conf.Ton {
+			continue
+		}
+
+		list = append(list, net)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+
+	return list
+}
+
 func GetTradeAtomKey(t TradeType) (ConfKey, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	if c, ok := registry[t]; ok {
 		atomKey, ok := cryptoAtomKeys[c.Crypto]
 
@@ -440,12 +757,19 @@ func GetSupportFiat() map[Fiat]struct{} {
 }
 
 func GetSupportCrypto() map[Crypto]CoinId {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 
-	return supportCrypto
+	var data = make(map[Crypto]CoinId, len(supportCrypto))
+	for c, id := range supportCrypto {
+		data[c] = id
+	}
+
+	return data
 }
 
 func AddrCaseSens(t TradeType) bool {
-	if c, ok := registry[t]; ok {
+	if c, ok := getTradeConf(t); ok {
 
 		return c.AddrCaseSens
 	}
@@ -454,6 +778,9 @@ func AddrCaseSens(t TradeType) bool {
 }
 
 func GetTradeTypeByCurrencyAndNetwork(currency, network string) (TradeType, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
 	for t, c := range registry {
 		if string(c.Crypto) == currency && (string(c.Network) == network || c.NetworkName == network) {
 			return t, nil
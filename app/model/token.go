@@ -0,0 +1,203 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/utils"
+	"github.com/xssnick/tonutils-go/address"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TokenStatusEnable  uint8 = 1
+	TokenStatusDisable uint8 = 0
+)
+
+var tokenSymbolRegexp = regexp.MustCompile(`^[A-Z0-9]{2,10}$`)
+
+// Token 自定义代币，运行时注册到交易类型注册表
+type Token struct {
+	Id
+	Network     string `gorm:"column:network;type:varchar(32);not null;uniqueIndex:idx_token_contract;comment:所属网络" json:"network"`
+	Contract    string `gorm:"column:contract;type:varchar(128);not null;uniqueIndex:idx_token_contract;comment:合约地址" json:"contract"`
+	Crypto      string `gorm:"column:crypto;type:varchar(16);not null;comment:币种符号" json:"crypto"`
+	TradeType   string `gorm:"column:trade_type;type:varchar(20);not null;uniqueIndex;comment:交易类型" json:"trade_type"`
+	Decimals    int32  `gorm:"column:decimals;not null;comment:小数位数" json:"decimals"`
+	CoinId      string `gorm:"column:coin_id;type:varchar(64);not null;default:'';comment:CoinGecko Id" json:"coin_id"`
+	ExplorerFmt string `gorm:"column:explorer_fmt;type:varchar(255);not null;default:'';comment:交易链接格式" json:"explorer_fmt"`
+	MinAmount   string `gorm:"column:min_amount;type:varchar(32);not null;default:'0.01';comment:最小扫描数额" json:"min_amount"`
+	Status      uint8  `gorm:"column:status;not null;default:1;comment:状态" json:"status"`
+	AutoTimeAt
+}
+
+func (t *Token) TableName() string {
+
+	return "bep_token"
+}
+
+// tokenNetworkBase 网络内置交易类型配置，自定义代币沿用其网络名称、节点与地址规则
+func tokenNetworkBase(net Network) (TradeTypeConf, bool) {
+	for _, c := range builtinRegistry {
+		if c.Network == net && c.Contract != "" {
+
+			return c, true
+		}
+	}
+
+	return TradeTypeConf{}, false
+}
+
+// Validate 校验并规范化代币参数；支持 EVM ERC20、TRC20、SPL 与 Jetton
+func (t *Token) Validate() error {
+	t.Network = strings.ToLower(strings.TrimSpace(t.Network))
+	t.Contract = strings.TrimSpace(t.Contract)
+	t.Crypto = strings.ToUpper(strings.TrimSpace(t.Crypto))
+	t.CoinId = strings.TrimSpace(t.CoinId)
+	t.ExplorerFmt = strings.TrimSpace(t.ExplorerFmt)
+
+	var net = Network(t.Network)
+	base, ok := tokenNetworkBase(net)
+	if !ok || net == conf.Aptos {
+
+		return fmt.Errorf("不支持的网络：%s", t.Network)
+	}
+
+	switch net {
+	case conf.Tron:
+		if !utils.IsValidTronAddress(t.Contract) {
+			return errors.New("合约地址格式不合法，请检查")
+		}
+	case conf.Solana:
+		if !utils.IsValidSolanaAddress(t.Contract) {
+			return errors.New("合约地址格式不合法，请检查")
+		}
+	case conf.Ton:
+		addr, err := address.ParseAddr(t.Contract)
+		if err != nil {
+			return errors.New("合约地址格式不合法，请检查")
+		}
+
+		t.Contract = addr.Bounce(true).String()
+	default:
+		if !utils.IsValidEvmAddress(t.Contract) {
+			return errors.New("合约地址格式不合法，请检查")
+		}
+
+		t.Contract = strings.ToLower(t.Contract)
+	}
+
+	if !tokenSymbolRegexp.MatchString(t.Crypto) {
+
+		return errors.New("币种符号只能由 2-10 位字母或数字组成")
+	}
+	if t.Decimals < 0 || t.Decimals > 36 {
+
+		return errors.New("小数位数必须在 0-36 之间")
+	}
+
+	if _, ok := builtinCrypto[Crypto(t.Crypto)]; !ok && t.CoinId == "" {
+
+		return errors.New("新币种需要填写 CoinGecko Id，用于同步汇率")
+	}
+
+	if t.MinAmount == "" {
+		t.MinAmount = "0.01"
+	}
+	if d, err := decimal.NewFromString(t.MinAmount); err != nil || d.Sign() <= 0 {
+
+		return errors.New("最小扫描数额必须大于 0")
+	}
+
+	if t.ExplorerFmt == "" {
+		t.ExplorerFmt = base.ExplorerFmt
+	}
+	if strings.Count(t.ExplorerFmt, "%s") != 1 {
+
+		return errors.New("交易链接格式必须包含且只包含一个 %s")
+	}
+
+	if t.TradeType == "" {
+		t.TradeType = fmt.Sprintf("%s.%s", strings.ToLower(t.Crypto), t.Network)
+	}
+	if len(t.TradeType) > 20 {
+
+		return errors.New("交易类型长度不能超过 20 个字符")
+	}
+	if _, ok := builtinRegistry[TradeType(t.TradeType)]; ok {
+
+		return fmt.Errorf("交易类型与内置类型冲突：%s", t.TradeType)
+	}
+	for _, c := range builtinRegistry {
+		if c.Network == net && c.Contract == t.Contract {
+
+			return errors.New("该合约已是内置代币，无需重复添加")
+		}
+	}
+
+	return nil
+}
+
+func (t *Token) tradeConf() TradeTypeConf {
+	var base, _ = tokenNetworkBase(Network(t.Network))
+	var minAmount, _ = decimal.NewFromString(t.MinAmount)
+
+	return TradeTypeConf{
+		Alias:        fmt.Sprintf("%s・%s", t.Crypto, base.NetworkName),
+		NetworkName:  base.NetworkName,
+		Network:      base.Network,
+		Crypto:       Crypto(t.Crypto),
+		Contract:     t.Contract,
+		Decimal:      -t.Decimals,
+		AmountRange:  Range{MinAmount: minAmount, MaxAmount: usdGeneralRange.MaxAmount},
+		ExplorerFmt:  t.ExplorerFmt,
+		EndpointKey:  base.EndpointKey,
+		AddrCaseSens: base.AddrCaseSens,
+	}
+}
+
+// RefreshTokens 加载已启用的自定义代币，重建交易类型注册表
+func RefreshTokens() {
+	var tokens []Token
+	if err := Db.Where("status = ?", TokenStatusEnable).Find(&tokens).Error; err != nil {
+		log.Warn("自定义代币加载失败：", err.Error())
+
+		return
+	}
+
+	var reg = make(map[TradeType]TradeTypeConf, len(builtinRegistry)+len(tokens))
+	for t, c := range builtinRegistry {
+		reg[t] = c
+	}
+
+	var crypto = make(map[Crypto]CoinId, len(builtinCrypto)+len(tokens))
+	for c, id := range builtinCrypto {
+		crypto[c] = id
+	}
+
+	for _, t := range tokens {
+		if _, ok := reg[TradeType(t.TradeType)]; ok {
+
+			continue
+		}
+
+		reg[TradeType(t.TradeType)] = t.tradeConf()
+		if _, ok := crypto[Crypto(t.Crypto)]; !ok && t.CoinId != "" {
+			crypto[Crypto(t.Crypto)] = CoinId(t.CoinId)
+		}
+
+		// 新币种补充默认原子精度配置
+		var atom = ConfKey(fmt.Sprintf("atom_%s", strings.ToLower(t.Crypto)))
+		if GetC(atom) == "" {
+			Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Conf{K: atom, V: "0.01"})
+			confCache.Store(atom, GetK(atom))
+		}
+	}
+
+	rebuildRegistry(reg, crypto)
+}
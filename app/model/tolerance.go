@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// GetPaymentTolerance 交易类型允许的支付误差百分比，配置格式：usdt.trc20=1,tron.trx=2,*=0.5
+func GetPaymentTolerance(t TradeType) decimal.Decimal {
+	var fallback = decimal.Zero
+	for _, item := range strings.Split(GetC(PaymentTolerance), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+
+		pct, err := decimal.NewFromString(strings.TrimSpace(v))
+		if err != nil || pct.IsNegative() || pct.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			continue
+		}
+
+		switch strings.TrimSpace(k) {
+		case string(t):
+
+			return pct
+		case "*":
+			fallback = pct
+		}
+	}
+
+	return fallback
+}
+
+// IsWithinTolerance 判断实收数额是否在订单数额的误差范围内
+func IsWithinTolerance(t TradeType, amount decimal.Decimal, target string) bool {
+	pct := GetPaymentTolerance(t)
+	if pct.IsZero() {
+
+		return false
+	}
+
+	d, err := decimal.NewFromString(target)
+	if err != nil || !d.IsPositive() {
+
+		return false
+	}
+
+	return amount.Sub(d).Abs().LessThanOrEqual(d.Mul(pct).Div(decimal.NewFromInt(100)))
+}
+
+// ToleranceFloor 误差范围内可接受的最小数额
+func ToleranceFloor(t TradeType, target decimal.Decimal) decimal.Decimal {
+	pct := GetPaymentTolerance(t)
+
+	return target.Sub(target.Mul(pct).Div(decimal.NewFromInt(100)))
+}
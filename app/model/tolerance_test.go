@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentTolerance(t *testing.T) {
+	setupTestDb(t, &Conf{})
+	SetK(PaymentTolerance, "usdt.trc20=1,*=0.5")
+	RefreshC()
+	t.Cleanup(func() {
+		SetK(PaymentTolerance, "")
+		RefreshC()
+	})
+
+	if pct := GetPaymentTolerance(UsdtTrc20); pct.String() != "1" {
+		t.Fatalf("usdt.trc20 tolerance = %s, want 1", pct)
+	}
+	if pct := GetPaymentTolerance(TronTrx); pct.String() != "0.5" {
+		t.Fatalf("fallback tolerance = %s, want 0.5", pct)
+	}
+	if floor := ToleranceFloor(UsdtTrc20, decimal.RequireFromString("10")); floor.String() != "9.9" {
+		t.Fatalf("floor = %s, want 9.9", floor)
+	}
+
+	var cases = []struct {
+		amount string
+		want   bool
+	}{
+		{"10", true},
+		{"9.9", true},       // 恰好少付 1%
+		{"9.899999", false}, // 比误差下限少 1 个最小单位
+		{"10.1", true},      // 恰好多付 1%
+		{"10.100001", false},
+	}
+
+	for _, c := range cases {
+		if got := IsWithinTolerance(UsdtTrc20, decimal.RequireFromString(c.amount), "10"); got != c.want {
+			t.Errorf("IsWithinTolerance(%s) = %t, want %t", c.amount, got, c.want)
+		}
+	}
+}
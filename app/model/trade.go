@@ -10,9 +10,11 @@ import (
 	"github.com/spf13/cast"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/utils"
+	"gorm.io/gorm"
 )
 
 type OrderParams struct {
+	MerchantId        int64           `json:"merchant_id"`         // 商户 ID，0 为默认商户
 	Money             decimal.Decimal `json:"money"`               // 交易金额 (单位：法币)s
 	ApiType           string          `json:"api_type"`            // 支付 API 类型
 	Address           string          `json:"address"`             // 收款地址
@@ -55,9 +57,12 @@ func StartBuildOrder(p OrderParams) (Order, error) {
 			return order, fmt.Errorf("钱包地址格式错误：%s", p.Address)
 		}
 	}
-	if _, ok := registry[p.TradeType]; !ok {
+	if _, ok := getTradeConf(p.TradeType); !ok {
 		return order, fmt.Errorf("不支持的交易类型：%s", p.TradeType)
 	}
+	if m := GetMerchant(p.MerchantId); !m.AllowTradeType(p.TradeType) {
+		return order, fmt.Errorf("商户未开通该交易类型：%s", p.TradeType)
+	}
 	if _, ok := supportFiat[p.Fiat]; !ok {
 		return order, fmt.Errorf("不支持的法币类型：%s", p.Fiat)
 	}
@@ -67,16 +72,16 @@ func StartBuildOrder(p OrderParams) (Order, error) {
 		return order, fmt.Errorf("交易金额必须在 %s - %s 之间", minAmount.String(), maxAmount.String())
 	}
 
-	Db.Where("order_id = ?", p.OrderId).Order("id desc").Limit(1).Find(&order)
-	if order.Status == OrderStatusSuccess || order.Status == OrderStatusConfirming {
+	Db.Where("order_id = ? and merchant_id = ?", p.OrderId, p.MerchantId).Order("id desc").Limit(1).Find(&order)
+	if order.IsPaid() || order.Status == OrderStatusConfirming {
 		return order, nil
 	}
 
 	buildMutex.Lock()
 	defer buildMutex.Unlock()
 
-	Db.Where("order_id = ?", p.OrderId).Order("id desc").Limit(1).Find(&order)
-	if order.Status == OrderStatusSuccess || order.Status == OrderStatusConfirming {
+	Db.Where("order_id = ? and merchant_id = ?", p.OrderId, p.MerchantId).Order("id desc").Limit(1).Find(&order)
+	if order.IsPaid() || order.Status == OrderStatusConfirming {
 		return order, nil
 	}
 	if order.Status == OrderStatusWaiting {
@@ -99,6 +104,7 @@ func BuildOrder(p OrderParams, trade Trade) (Order, error) {
 
 	zero := time.Unix(0, 0)
 	tradeOrder := Order{
+		MerchantId:        p.MerchantId,
 		OrderId:           p.OrderId,
 		TradeId:           tradeId,
 		RefHash:           tradeId,
@@ -127,7 +133,32 @@ func BuildOrder(p OrderParams, trade Trade) (Order, error) {
 	if tradeOrder.Name == "" {
 		tradeOrder.Name = tradeOrder.OrderId
 	}
-	if err = Db.Create(&tradeOrder).Error; err != nil {
+
+	var source = EventSource(EventSourceApi, p.ApiType)
+	if p.ApiType == OrderApiTypeAdmin {
+		source = EventSourceAdmin
+	}
+
+	// 订单、状态事件与状态推送在同一事务内写入
+	err = Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tradeOrder).Error; err != nil {
+
+			return err
+		}
+		if err := tx.Create(&OrderEvent{TradeId: tradeId, NewStatus: OrderStatusWaiting, Source: source}).Error; err != nil {
+
+			return err
+		}
+		for _, kind := range tradeOrder.outboxKinds(OrderStatusWaiting) {
+			if err := enqueueNotify(tx, &tradeOrder, kind); err != nil {
+
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
 		log.Error("订单创建失败：", err.Error())
 		return Order{}, err
 	}
@@ -151,7 +182,22 @@ func BuildTrade(p OrderParams) (Trade, error) {
 		return Trade{}, fmt.Errorf("%s %s 汇率异常", crypto, p.Fiat)
 	}
 
-	var wallets = GetAvailableWallets(p.TradeType)
+	if p.Address == "" { // 配置了扩展公钥时，每个订单推导独立地址，无需递增金额区分
+		w, ok, err := NextXpubWallet(p.TradeType, p.MerchantId)
+		if err != nil {
+			return Trade{}, err
+		}
+		if ok {
+			amount, err := CalcExactAmount(rate, p)
+			if err != nil {
+				return Trade{}, err
+			}
+
+			return Trade{Crypto: crypto, Rate: rate, Wallet: w, Amount: amount}, nil
+		}
+	}
+
+	var wallets = GetAvailableWallets(p.TradeType, p.MerchantId)
 	if p.Address != "" { // 指定地址
 		w, err := NewWallet(p.Address, p.TradeType)
 		if err != nil {
@@ -185,6 +231,11 @@ func RebuildOrder(t Order, p OrderParams) (Order, error) {
 		return t, nil
 	}
 
+	p.MerchantId = t.MerchantId
+	if m := t.GetMerchant(); !m.AllowTradeType(p.TradeType) {
+		return t, fmt.Errorf("商户未开通该交易类型：%s", p.TradeType)
+	}
+
 	data, err := BuildTrade(p)
 	if err != nil {
 		return t, err
@@ -201,7 +252,19 @@ func RebuildOrder(t Order, p OrderParams) (Order, error) {
 	t.Rate = fmt.Sprintf("%v", data.Rate)
 	t.ExpiredAt = CalcTradeExpiredAt(p.Timeout)
 
-	return t, Db.Save(&t).Error
+	res := Db.Model(&t).Where("status = ?", OrderStatusWaiting).
+		Select("fiat", "address", "match_address", "crypto", "amount", "money", "trade_type", "trade_type_reselect", "rate", "expired_at").
+		Updates(&t)
+	if res.Error != nil {
+
+		return t, res.Error
+	}
+	if res.RowsAffected == 0 {
+
+		return t, ErrOrderStatusChanged
+	}
+
+	return t, nil
 }
 
 // BuildPendingOrder 创建待支付订单（不锁定地址和汇率）
@@ -214,8 +277,8 @@ func BuildPendingOrder(p OrderParams) (Order, error) {
 		return order, fmt.Errorf("交易金额必须在 %s - %s 之间", minAmount.String(), maxAmount.String())
 	}
 
-	Db.Where("order_id = ?", p.OrderId).Order("id desc").Limit(1).Find(&order)
-	if order.Status == OrderStatusSuccess || order.Status == OrderStatusConfirming || order.Status == OrderStatusWaiting {
+	Db.Where("order_id = ? and merchant_id = ?", p.OrderId, p.MerchantId).Order("id desc").Limit(1).Find(&order)
+	if order.IsPaid() || order.Status == OrderStatusConfirming || order.Status == OrderStatusWaiting {
 		return order, nil
 	}
 
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+type Permission string
+
+const (
+	RoleOwner    Role = "owner"    // 所有者，拥有全部权限
+	RoleOperator Role = "operator" // 运营，可管理订单与钱包
+	RoleFinance  Role = "finance"  // 财务，可处理订单
+	RoleReadonly Role = "readonly" // 只读，仅可查看订单与钱包
+)
+
+const (
+	PermPublic Permission = ""       // 无需登录
+	PermLogin  Permission = "login"  // 登录即可访问
+	PermView   Permission = "view"   // 查看订单、钱包、汇率等数据
+	PermOrder  Permission = "order"  // 订单补单、回调、取消、删除等操作
+	PermWallet Permission = "wallet" // 钱包与 HD 钱包的增删改
+	PermSystem Permission = "system" // 系统设置、节点、代币、商户、账号管理
+)
+
+const (
+	UserStatusEnable  uint8 = 1
+	UserStatusDisable uint8 = 0
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleOwner:    {PermLogin, PermView, PermOrder, PermWallet, PermSystem},
+	RoleOperator: {PermLogin, PermView, PermOrder, PermWallet},
+	RoleFinance:  {PermLogin, PermView, PermOrder},
+	RoleReadonly: {PermLogin, PermView},
+}
+
+var roleText = map[Role]string{
+	RoleOwner:    "所有者",
+	RoleOperator: "运营",
+	RoleFinance:  "财务",
+	RoleReadonly: "只读",
+}
+
+// User 后台管理账号
+type User struct {
+	Id
+	Username string    `gorm:"column:username;type:varchar(64);not null;uniqueIndex;comment:登录账号" json:"username"`
+	Password string    `gorm:"column:password;type:varchar(255);not null;comment:登录密码" json:"-"`
+	Role     Role      `gorm:"column:role;type:varchar(16);not null;default:readonly;comment:角色" json:"role"`
+	Status   uint8     `gorm:"column:status;not null;default:1;comment:状态" json:"status"`
+	Remark   string    `gorm:"column:remark;type:varchar(255);not null;default:'';comment:备注" json:"remark"`
+	LoginIp  string    `gorm:"column:login_ip;type:varchar(64);not null;default:'';comment:最后登录IP" json:"login_ip"`
+	LoginAt  *Datetime `gorm:"column:login_at;comment:最后登录时间" json:"login_at"`
+
+	TotpEnabled   bool   `gorm:"column:totp_enabled;not null;default:false;comment:是否启用两步验证" json:"totp_enabled"`
+	TotpSecret    string `gorm:"column:totp_secret;type:varchar(64);not null;default:'';comment:两步验证密钥" json:"-"`
+	RecoveryCodes string `gorm:"column:recovery_codes;type:text;comment:恢复码摘要，逗号分隔" json:"-"`
+	AutoTimeAt
+}
+
+func (u *User) TableName() string {
+
+	return "bep_user"
+}
+
+func IsValidRole(r Role) bool {
+	_, ok := rolePermissions[r]
+
+	return ok
+}
+
+func RoleText(r Role) string {
+	if text, ok := roleText[r]; ok {
+
+		return text
+	}
+
+	return string(r)
+}
+
+func (u *User) Permissions() []Permission {
+
+	return rolePermissions[u.Role]
+}
+
+// Can 公开路由无需权限，其余按角色权限判断
+func (u *User) Can(p Permission) bool {
+	if p == PermPublic {
+
+		return true
+	}
+
+	for _, v := range u.Permissions() {
+		if v == p {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *User) Validate() error {
+	u.Username = strings.TrimSpace(u.Username)
+	if len(u.Username) < 3 || len(u.Username) > 64 {
+
+		return errors.New("登录账号长度必须在 3 到 64 位之间")
+	}
+	if !IsValidRole(u.Role) {
+
+		return fmt.Errorf("不支持的角色：%s", u.Role)
+	}
+
+	return nil
+}
+
+func (u *User) SetPassword(password string) error {
+	password = strings.TrimSpace(password)
+	if len(password) < 6 {
+
+		return errors.New("密码长度不能少于6位")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+
+		return err
+	}
+
+	u.Password = string(hash)
+
+	return nil
+}
+
+func (u *User) CheckPassword(password string) bool {
+
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
+
+func (u *User) SetLogin(ip string) {
+	var now = Datetime(time.Now())
+
+	u.LoginIp = ip
+	u.LoginAt = &now
+
+	Db.Model(u).Select("login_ip", "login_at").Updates(u)
+}
+
+func GetUser(id int64) (User, bool) {
+	var u User
+	Db.Where("id = ?", id).Limit(1).Find(&u)
+
+	return u, u.ID != 0
+}
+
+func GetUserByName(username string) (User, bool) {
+	var u User
+	Db.Where("username = ?", username).Limit(1).Find(&u)
+
+	return u, u.ID != 0
+}
+
+// CountOwners 启用状态的所有者数量，至少保留一个，避免无人可管理系统设置
+func CountOwners() int64 {
+	var count int64
+	Db.Model(&User{}).Where("role = ? and status = ?", RoleOwner, UserStatusEnable).Count(&count)
+
+	return count
+}
+
+// InitOwner 账号表为空时，以原有单管理员账号密码创建所有者，升级后可直接登录
+func InitOwner() {
+	var count int64
+	Db.Model(&User{}).Count(&count)
+	if count > 0 {
+
+		return
+	}
+
+	var u = User{Username: GetK(AdminUsername), Password: GetK(AdminPassword), Role: RoleOwner, Status: UserStatusEnable, Remark: "初始管理员"}
+	if u.Username == "" || u.Password == "" {
+
+		return
+	}
+
+	Db.Create(&u)
+}
+
+// ResetOwner 重置命令使用，重置最早创建的所有者账号密码，不存在时新建
+func ResetOwner(username, password string) error {
+	var u User
+	Db.Where("role = ?", RoleOwner).Order("id asc").Limit(1).Find(&u)
+
+	u.Username = username
+	u.Role = RoleOwner
+	u.Status = UserStatusEnable
+	if err := u.SetPassword(password); err != nil {
+
+		return err
+	}
+
+	return Db.Save(&u).Error
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/v03413/bepusdt/app/utils"
+	"github.com/v03413/go-cache"
+)
+
+func TestUserCan(t *testing.T) {
+	var cases = []struct {
+		role Role
+		perm Permission
+		want bool
+	}{
+		{RoleOwner, PermSystem, true},
+		{RoleOperator, PermWallet, true},
+		{RoleOperator, PermSystem, false},
+		{RoleFinance, PermOrder, true},
+		{RoleFinance, PermWallet, false},
+		{RoleReadonly, PermView, true},
+		{RoleReadonly, PermOrder, false},
+		{RoleReadonly, PermPublic, true},
+		{Role("unknown"), PermLogin, false},
+	}
+
+	for _, c := range cases {
+		u := User{Role: c.role}
+		if got := u.Can(c.perm); got != c.want {
+			t.Errorf("%s can %q = %v, want %v", c.role, c.perm, got, c.want)
+		}
+	}
+}
+
+func TestCheckTotpOnce(t *testing.T) {
+	u := User{TotpSecret: utils.NewTotpSecret()}
+	u.ID = 9001
+	step := uint64(time.Now().Unix() / utils.TotpPeriod)
+	code, _ := utils.TotpCode(u.TotpSecret, step)
+	t.Cleanup(func() {
+		cache.Delete(fmt.Sprintf("totp_used_%d_%d", u.ID, step))
+	})
+
+	// 并发提交同一验证码，只能有一个通过
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u.checkTotp(code) {
+				passed.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if n := passed.Load(); n != 1 {
+		t.Fatalf("passed = %d, want 1", n)
+	}
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/v03413/bepusdt/app/utils"
+	"github.com/v03413/go-cache"
+)
+
+const recoveryCodeNum = 10
+
+var totpUsedMutex sync.Mutex
+
+var ErrTotpInvalid = errors.New("动态验证码错误或已使用")
+
+// SetupTotp 生成待绑定的两步验证密钥，验证通过 EnableTotp 后才生效
+func (u *User) SetupTotp() (string, string, error) {
+	if u.TotpEnabled {
+
+		return "", "", errors.New("两步验证已启用，请先关闭后再重新绑定")
+	}
+
+	u.TotpSecret = utils.NewTotpSecret()
+	if err := Db.Model(u).Update("totp_secret", u.TotpSecret).Error; err != nil {
+
+		return "", "", err
+	}
+
+	return u.TotpSecret, utils.TotpUri("BEpusdt", u.Username, u.TotpSecret), nil
+}
+
+// EnableTotp 校验认证器生成的验证码后启用两步验证，返回仅展示一次的恢复码
+func (u *User) EnableTotp(code string) ([]string, error) {
+	if u.TotpEnabled {
+
+		return nil, errors.New("两步验证已启用")
+	}
+	if u.TotpSecret == "" {
+
+		return nil, errors.New("请先生成两步验证密钥")
+	}
+	if !u.checkTotp(code) {
+
+		return nil, ErrTotpInvalid
+	}
+
+	var codes = make([]string, 0, recoveryCodeNum)
+	var hashes = make([]string, 0, recoveryCodeNum)
+	for i := 0; i < recoveryCodeNum; i++ {
+		var b = make([]byte, 5)
+		_, _ = rand.Read(b)
+
+		var c = hex.EncodeToString(b)
+		codes = append(codes, c)
+		hashes = append(hashes, utils.StrSha256(c))
+	}
+
+	u.TotpEnabled = true
+	u.RecoveryCodes = strings.Join(hashes, ",")
+
+	return codes, Db.Model(u).Select("totp_enabled", "recovery_codes").Updates(u).Error
+}
+
+// DisableTotp 关闭两步验证并清除密钥与恢复码
+func (u *User) DisableTotp() error {
+	u.TotpEnabled = false
+	u.TotpSecret = ""
+	u.RecoveryCodes = ""
+
+	return Db.Model(u).Select("totp_enabled", "totp_secret", "recovery_codes").Updates(u).Error
+}
+
+// VerifySecondFactor 校验动态验证码或恢复码，恢复码使用后立即作废
+func (u *User) VerifySecondFactor(code string) error {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if code == "" {
+
+		return errors.New("请输入动态验证码")
+	}
+	if u.checkTotp(code) {
+
+		return nil
+	}
+
+	var hash = utils.StrSha256(code)
+	var hashes = strings.Split(u.RecoveryCodes, ",")
+	for i, h := range hashes {
+		if h != "" && subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			var left = strings.Join(append(hashes[:i:i], hashes[i+1:]...), ",")
+
+			// 以读取时的恢复码列表为条件更新，并发使用同一恢复码时只有一个能成功
+			res := Db.Model(&User{}).Where("id = ? and recovery_codes = ?", u.ID, u.RecoveryCodes).Update("recovery_codes", left)
+			if res.Error != nil {
+
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+
+				return ErrTotpInvalid
+			}
+
+			u.RecoveryCodes = left
+
+			return nil
+		}
+	}
+
+	return ErrTotpInvalid
+}
+
+// RecoveryCodesLeft 剩余可用恢复码数量
+func (u *User) RecoveryCodesLeft() int {
+	if u.RecoveryCodes == "" {
+
+		return 0
+	}
+
+	return len(strings.Split(u.RecoveryCodes, ","))
+}
+
+// checkTotp 同一账号的同一时间步验证码只能使用一次，并发请求同一验证码时只有一个能通过
+func (u *User) checkTotp(code string) bool {
+	step, ok := utils.VerifyTotp(u.TotpSecret, code, time.Now())
+	if !ok {
+
+		return false
+	}
+
+	totpUsedMutex.Lock()
+	defer totpUsedMutex.Unlock()
+
+	var key = fmt.Sprintf("totp_used_%d_%d", u.ID, step)
+	if _, used := cache.Get(key); used {
+
+		return false
+	}
+
+	cache.Set(key, true, time.Second*utils.TotpPeriod*3)
+
+	return true
+}
+
+// DisableAllTotp 重置命令使用，关闭全部账号的两步验证
+func DisableAllTotp() error {
+
+	return Db.Model(&User{}).Where("totp_enabled = ?", true).
+		Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "recovery_codes": ""}).Error
+}
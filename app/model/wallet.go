@@ -18,6 +18,7 @@ const (
 
 type Wallet struct {
 	Id
+	MerchantId  int64  `gorm:"column:merchant_id;not null;default:0;index;comment:所属商户" json:"merchant_id"`
 	Name        string `gorm:"column:name;type:varchar(32);not null;default:-';comment:名称" json:"name"`
 	Status      uint8  `gorm:"column:status;not null;default:1;comment:地址状态" json:"status"`
 	Address     string `gorm:"column:address;type:varchar(128);not null;index;comment:钱包地址" json:"address"`
@@ -42,20 +43,27 @@ func (wa *Wallet) Validate() error {
 	tradeType := TradeType(wa.TradeType)
 	wa.MatchAddr = wa.Address
 
-	switch tradeType {
-	case TronTrx, UsdtTrc20, UsdcTrc20:
+	switch wa.GetNetwork() {
+	case conf.Tron:
 		if !utils.IsValidTronAddress(wa.Address) {
 			return errors.New("钱包地址格式不合法，请检查")
 		}
-	case UsdtSolana, UsdcSolana:
+	case conf.Solana:
 		if !utils.IsValidSolanaAddress(wa.Address) {
 			return errors.New("钱包地址格式不合法，请检查")
 		}
-	case UsdtAptos, UsdcAptos:
+	case conf.Aptos:
 		if !utils.IsValidAptosAddress(wa.Address) {
 			return errors.New("钱包地址格式不合法，请检查")
 		}
-	case UsdtTon:
+	case conf.Bitcoin, conf.Litecoin:
+		addr, ok := utils.NormalizeUtxoAddress(string(wa.GetNetwork()), GetC(UtxoChain), wa.Address)
+		if !ok {
+			return errors.New("钱包地址格式不合法，请检查")
+		}
+		wa.Address = addr
+		wa.MatchAddr = addr
+	case conf.Ton:
 		if !strings.HasPrefix(wa.Address, "UQ") {
 			return errors.New("TON 地址必须以 UQ 开头")
 		}
@@ -63,21 +71,20 @@ func (wa *Wallet) Validate() error {
 		if err != nil {
 			return err
 		}
-		addr, err := utils.GetJettonWalletAddr(utils.NewTonClient(GetC(RpcGlobalConfigUrlTon)), address.MustParseAddr(conf.UsdtTon), owner)
+		if tradeType == TonGram {
+			wa.MatchAddr = owner.Bounce(false).String()
+			return nil
+		}
+		// Jetton 代币以钱包对应的 Jetton Wallet 地址匹配
+		master, err := address.ParseAddr(wa.GetTokenContract())
 		if err != nil {
 			return err
 		}
-		wa.MatchAddr = addr.Bounce(false).String()
-		return nil
-	case TonGram:
-		if !strings.HasPrefix(wa.Address, "UQ") {
-			return errors.New("TON 地址必须以 UQ 开头")
-		}
-		owner, err := address.ParseAddr(wa.Address)
+		addr, err := utils.GetJettonWalletAddr(utils.NewTonClient(GetC(RpcGlobalConfigUrlTon)), master, owner)
 		if err != nil {
 			return err
 		}
-		wa.MatchAddr = owner.Bounce(false).String()
+		wa.MatchAddr = addr.Bounce(false).String()
 		return nil
 	default:
 		if !utils.IsValidEvmAddress(wa.Address) {
@@ -103,7 +110,7 @@ func (wa *Wallet) Delete() {
 }
 
 func (wa *Wallet) GetTokenContract() string {
-	if c, ok := registry[TradeType(wa.TradeType)]; ok {
+	if c, ok := getTradeConf(TradeType(wa.TradeType)); ok {
 
 		return c.Contract
 	}
@@ -112,7 +119,7 @@ func (wa *Wallet) GetTokenContract() string {
 }
 
 func (wa *Wallet) GetTokenDecimals() int32 {
-	if c, ok := registry[TradeType(wa.TradeType)]; ok {
+	if c, ok := getTradeConf(TradeType(wa.TradeType)); ok {
 
 		return c.Decimal
 	}
@@ -121,7 +128,7 @@ func (wa *Wallet) GetTokenDecimals() int32 {
 }
 
 func (wa *Wallet) GetNetwork() Network {
-	if c, ok := registry[TradeType(wa.TradeType)]; ok {
+	if c, ok := getTradeConf(TradeType(wa.TradeType)); ok {
 
 		return c.Network
 	}
@@ -142,10 +149,11 @@ func (wa *Wallet) GetMatchAddr() string {
 	return wa.MatchAddr
 }
 
-func GetAvailableWallets(t TradeType) []Wallet {
+// GetAvailableWallets 商户可用的收款钱包，商户之间钱包池互相隔离
+func GetAvailableWallets(t TradeType, merchantId int64) []Wallet {
 	var wallets = make([]Wallet, 0)
 
-	Db.Where("trade_type = ? and status = ?", t, WaStatusEnable).Find(&wallets)
+	Db.Where("trade_type = ? and status = ? and merchant_id = ?", t, WaStatusEnable, merchantId).Find(&wallets)
 
 	return wallets
 }
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	XpubStatusEnable  uint8 = 1
+	XpubStatusDisable uint8 = 0
+)
+
+// Xpub 扩展公钥收款源，每个订单推导一个独立的只读收款地址，按订单原始金额收款
+type Xpub struct {
+	Id
+	MerchantId int64  `gorm:"column:merchant_id;not null;default:0;index;comment:所属商户" json:"merchant_id"`
+	Name       string `gorm:"column:name;type:varchar(32);not null;default:'';comment:名称" json:"name"`
+	TradeType  string `gorm:"column:trade_type;type:varchar(20);not null;uniqueIndex:idx_xpub;comment:交易类型" json:"trade_type"`
+	Xpub       string `gorm:"column:xpub;type:varchar(128);not null;uniqueIndex:idx_xpub;comment:扩展公钥" json:"xpub"`
+	NextIndex  uint32 `gorm:"column:next_index;not null;default:0;comment:下一个推导索引" json:"next_index"`
+	Status     uint8  `gorm:"column:status;not null;default:1;comment:状态" json:"status"`
+	Remark     string `gorm:"column:remark;type:varchar(255);not null;default:'';comment:备注" json:"remark"`
+	AutoTimeAt
+}
+
+// XpubAddress 扩展公钥已推导的地址，推导路径为 {xpub}/0/{derive_index}
+type XpubAddress struct {
+	Id
+	XpubId      int64  `gorm:"column:xpub_id;not null;uniqueIndex:idx_xpub_index;comment:扩展公钥ID" json:"xpub_id"`
+	DeriveIndex uint32 `gorm:"column:derive_index;not null;uniqueIndex:idx_xpub_index;comment:推导索引" json:"derive_index"`
+	TradeType   string `gorm:"column:trade_type;type:varchar(20);not null;index;comment:交易类型" json:"trade_type"`
+	Address     string `gorm:"column:address;type:varchar(128);not null;index;comment:收款地址" json:"address"`
+	MatchAddr   string `gorm:"column:match_addr;type:varchar(128);not null;comment:匹配地址" json:"match_addr"`
+	AutoTimeAt
+}
+
+func (x *Xpub) TableName() string {
+
+	return "bep_xpub"
+}
+
+func (a *XpubAddress) TableName() string {
+
+	return "bep_xpub_address"
+}
+
+// Validate 校验扩展公钥；Ed25519（SLIP-10）只支持强化推导，无法仅凭公钥推导地址，因此不支持 Solana 与 Aptos
+func (x *Xpub) Validate() error {
+	x.Xpub = strings.TrimSpace(x.Xpub)
+	x.Name = strings.TrimSpace(x.Name)
+
+	c, ok := getTradeConf(TradeType(x.TradeType))
+	if !ok {
+
+		return fmt.Errorf("不支持的交易类型：%s", x.TradeType)
+	}
+
+	switch c.Network {
+	case conf.Solana, conf.Aptos:
+
+		return errors.New("Solana / Aptos 使用 Ed25519 强化推导，无法通过扩展公钥生成地址")
+	case conf.Ton, conf.Bitcoin, conf.Litecoin:
+
+		return fmt.Errorf("%s 网络暂不支持扩展公钥收款", c.NetworkName)
+	}
+
+	if _, err := utils.ParseXpub(x.Xpub); err != nil {
+
+		return err
+	}
+
+	return nil
+}
+
+// deriveAddress 推导指定索引的收款地址
+func (x *Xpub) deriveAddress(index uint32) (string, error) {
+	key, err := utils.ParseXpub(x.Xpub)
+	if err != nil {
+
+		return "", err
+	}
+
+	child, err := key.Derive(0, index)
+	if err != nil {
+
+		return "", err
+	}
+
+	c, _ := getTradeConf(TradeType(x.TradeType))
+	if c.Network == conf.Tron {
+
+		return child.TronAddress(), nil
+	}
+
+	return child.EvmAddress(), nil
+}
+
+// NextXpubWallet 从启用的扩展公钥推导下一个收款地址；未配置扩展公钥时返回 false
+func NextXpubWallet(t TradeType, merchantId int64) (Wallet, bool, error) {
+	var x Xpub
+	Db.Where("trade_type = ? and status = ? and merchant_id = ?", t, XpubStatusEnable, merchantId).Order("id asc").Limit(1).Find(&x)
+	if x.ID == 0 {
+
+		return Wallet{}, false, nil
+	}
+
+	var wa Wallet
+	err := Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Xpub{}).Where("id = ?", x.ID).Update("next_index", gorm.Expr("next_index + 1")).Error; err != nil {
+
+			return err
+		}
+		if err := tx.Where("id = ?", x.ID).First(&x).Error; err != nil {
+
+			return err
+		}
+
+		var index = x.NextIndex - 1
+		addr, err := x.deriveAddress(index)
+		if err != nil {
+
+			return err
+		}
+
+		if wa, err = NewWallet(addr, t); err != nil {
+
+			return err
+		}
+
+		return tx.Create(&XpubAddress{
+			XpubId:      x.ID,
+			DeriveIndex: index,
+			TradeType:   string(t),
+			Address:     wa.Address,
+			MatchAddr:   wa.MatchAddr,
+		}).Error
+	})
+	if err != nil {
+
+		return Wallet{}, true, fmt.Errorf("扩展公钥地址推导失败：%w", err)
+	}
+
+	return wa, true, nil
+}
+
+// GetRecentXpubAddresses 回溯时间范围内推导的匹配地址，用于扫块按地址过滤
+func GetRecentXpubAddresses(trades []TradeType) []string {
+	var list = make([]string, 0)
+	Db.Model(&XpubAddress{}).Where("trade_type in (?) and created_at > ?", trades, time.Now().Add(GetLookbackHour())).
+		Pluck("match_addr", &list)
+
+	return list
+}
@@ -12,6 +12,8 @@ func (None) Success(order model.Order) {}
 
 func (None) NotifyFail(order model.Order, reason string) {}
 
+func (None) OrderRollback(order model.Order, reason string) {}
+
 func (None) NonOrderTransfer(trans model.TronTransfer, wa model.Wallet) {
 
 }
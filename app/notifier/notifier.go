@@ -15,6 +15,7 @@ type Notifier interface {
 	Initialize(params string) error                             // 初始化
 	Success(o model.Order)                                      // 交易成功通知
 	NotifyFail(o model.Order, reason string)                    // 订单回调失败通知
+	OrderRollback(o model.Order, reason string)                 // 订单回退通知（区块重组等）
 	NonOrderTransfer(trans model.TronTransfer, wa model.Wallet) // 非订单交易通知
 	TronResourceChange(res model.TronResource)                  // Tron 资源变动通知
 	Welcome()                                                   // 程序启动时的欢迎信息
@@ -78,6 +79,14 @@ func NotifyFail(order model.Order, reason string) {
 	go notifier.NotifyFail(order, reason)
 }
 
+func OrderRollback(order model.Order, reason string) {
+	notifier, err := getNotifier()
+	if err != nil {
+		return
+	}
+	go notifier.OrderRollback(order, reason)
+}
+
 func NonOrderTransfer(trans model.TronTransfer, wa model.Wallet) {
 	notifier, err := getNotifier()
 	if err != nil {
@@ -43,7 +43,7 @@ func (t *Telegram) Initialize(params string) error {
 }
 
 func (t *Telegram) Success(o model.Order) {
-	if o.Status != model.OrderStatusSuccess {
+	if !o.IsPaid() {
 		return
 	}
 
@@ -123,7 +123,7 @@ func (t *Telegram) NotifyFail(o model.Order, reason string) {
 		o.Money, o.Rate,
 		strings.ToUpper(tradeType),
 		o.ConfirmedAt.Format(time.DateTime),
-		utils.CalcNextNotifyTime(*o.ConfirmedAt, o.NotifyNum+1).Format(time.DateTime),
+		time.Now().Add(model.GetNotifyPolicy().Delay(o.NotifyNum)).Format(time.DateTime),
 		reason,
 	)
 
@@ -140,6 +140,36 @@ func (t *Telegram) NotifyFail(o model.Order, reason string) {
 	})
 }
 
+func (t *Telegram) OrderRollback(o model.Order, reason string) {
+	tradeType := string(o.TradeType)
+	tokenT, err := model.GetCrypto(o.TradeType)
+	if err != nil {
+		t.sendMessage(&bot.SendMessageParams{Text: "❌交易类型不支持：" + tradeType})
+
+		return
+	}
+
+	text := fmt.Sprintf(`
+\#订单回退 \#订单交易 \#`+string(tokenT)+`
+\-\-\-
+`+"```"+`
+🚦商户订单：%v
+💲支付数额：%v
+💍交易类别：%s
+✅收款地址：%s
+🗒️回退原因：%s
+`+"```"+`
+`,
+		utils.Ec(o.OrderId),
+		o.Amount,
+		strings.ToUpper(tradeType),
+		utils.MaskAddress(o.Address),
+		reason,
+	)
+
+	t.sendMessage(&bot.SendMessageParams{Text: text, ParseMode: models.ParseModeMarkdown})
+}
+
 func (t *Telegram) NonOrderTransfer(trans model.TronTransfer, wa model.Wallet) {
 	title := nonOrderTransferTitle(trans, wa)
 
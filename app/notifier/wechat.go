@@ -14,6 +14,8 @@ func (Wechat) Success(order model.Order) {}
 
 func (Wechat) NotifyFail(order model.Order, reason string) {}
 
+func (Wechat) OrderRollback(order model.Order, reason string) {}
+
 func (Wechat) NonOrderTransfer(trans model.TronTransfer, wa model.Wallet) {
 
 }
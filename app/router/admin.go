@@ -3,57 +3,122 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/v03413/bepusdt/app/handler/admin"
+	"github.com/v03413/bepusdt/app/handler/base"
+	"github.com/v03413/bepusdt/app/model"
 )
 
 func adminInit(e *gin.Engine) {
 	var confRtr = e.Group("/api/conf")
 	var confHdr = new(admin.Conf)
 	{
-		GetRegister(confRtr, "/rpc", true, confHdr.Rpc)
-		PostRegister(confRtr, "/set", true, confHdr.Set)
-		PostRegister(confRtr, "/get", true, confHdr.Get)
-		PostRegister(confRtr, "/del", true, confHdr.Del)
-		PostRegister(confRtr, "/gets", true, confHdr.Gets)
-		PostRegister(confRtr, "/sets", true, confHdr.Sets)
-		PostRegister(confRtr, "/notifier", true, confHdr.Notifier)
-		PostRegister(confRtr, "/notifier_test", true, confHdr.NotifierTest)
-		PostRegister(confRtr, "/checkout_list", true, confHdr.CheckoutList)
-		PostRegister(confRtr, "/reset_api_auth_token", true, confHdr.ResetApiAuthToken)
+		GetRegister(confRtr, "/rpc", model.PermSystem, confHdr.Rpc)
+		PostRegister(confRtr, "/set", model.PermSystem, confHdr.Set)
+		PostRegister(confRtr, "/get", model.PermSystem, confHdr.Get)
+		PostRegister(confRtr, "/del", model.PermSystem, confHdr.Del)
+		PostRegister(confRtr, "/gets", model.PermSystem, confHdr.Gets)
+		PostRegister(confRtr, "/sets", model.PermSystem, confHdr.Sets)
+		PostRegister(confRtr, "/notifier", model.PermSystem, base.TotpGuard, confHdr.Notifier)
+		PostRegister(confRtr, "/notifier_test", model.PermSystem, confHdr.NotifierTest)
+		PostRegister(confRtr, "/checkout_list", model.PermSystem, confHdr.CheckoutList)
+		PostRegister(confRtr, "/reset_api_auth_token", model.PermSystem, base.TotpGuard, confHdr.ResetApiAuthToken)
 	}
 
 	var walletRtr = e.Group("/api/wallet")
 	var walletHdr = new(admin.Wallet)
 	{
-		PostRegister(walletRtr, "/add", true, walletHdr.Add)
-		PostRegister(walletRtr, "/list", true, walletHdr.List)
-		PostRegister(walletRtr, "/mod", true, walletHdr.Mod)
-		PostRegister(walletRtr, "/del", true, walletHdr.Del)
+		PostRegister(walletRtr, "/add", model.PermWallet, base.TotpGuard, walletHdr.Add)
+		PostRegister(walletRtr, "/list", model.PermView, walletHdr.List)
+		PostRegister(walletRtr, "/mod", model.PermWallet, base.TotpGuard, walletHdr.Mod)
+		PostRegister(walletRtr, "/del", model.PermWallet, walletHdr.Del)
+	}
+
+	var tokenRtr = e.Group("/api/token")
+	var tokenHdr = new(admin.Token)
+	{
+		PostRegister(tokenRtr, "/add", model.PermSystem, tokenHdr.Add)
+		PostRegister(tokenRtr, "/list", model.PermView, tokenHdr.List)
+		PostRegister(tokenRtr, "/mod", model.PermSystem, tokenHdr.Mod)
+		PostRegister(tokenRtr, "/del", model.PermSystem, tokenHdr.Del)
+	}
+
+	var xpubRtr = e.Group("/api/xpub")
+	var xpubHdr = new(admin.Xpub)
+	{
+		PostRegister(xpubRtr, "/add", model.PermWallet, base.TotpGuard, xpubHdr.Add)
+		PostRegister(xpubRtr, "/list", model.PermView, xpubHdr.List)
+		PostRegister(xpubRtr, "/mod", model.PermWallet, base.TotpGuard, xpubHdr.Mod)
+		PostRegister(xpubRtr, "/del", model.PermWallet, xpubHdr.Del)
+		PostRegister(xpubRtr, "/addresses", model.PermView, xpubHdr.Addresses)
+	}
+
+	var merchantRtr = e.Group("/api/merchant")
+	var merchantHdr = new(admin.Merchant)
+	{
+		PostRegister(merchantRtr, "/add", model.PermSystem, merchantHdr.Add)
+		PostRegister(merchantRtr, "/list", model.PermSystem, merchantHdr.List)
+		PostRegister(merchantRtr, "/mod", model.PermSystem, base.TotpGuard, merchantHdr.Mod)
+		PostRegister(merchantRtr, "/del", model.PermSystem, merchantHdr.Del)
+		PostRegister(merchantRtr, "/reset_secret", model.PermSystem, base.TotpGuard, merchantHdr.ResetSecret)
+	}
+
+	var userRtr = e.Group("/api/user")
+	var userHdr = new(admin.User)
+	{
+		PostRegister(userRtr, "/add", model.PermSystem, base.TotpGuard, userHdr.Add)
+		PostRegister(userRtr, "/list", model.PermSystem, userHdr.List)
+		PostRegister(userRtr, "/mod", model.PermSystem, base.TotpGuard, userHdr.Mod)
+		PostRegister(userRtr, "/del", model.PermSystem, userHdr.Del)
+	}
+
+	var sessionRtr = e.Group("/api/session")
+	var sessionHdr = new(admin.Session)
+	{
+		PostRegister(sessionRtr, "/list", model.PermSystem, sessionHdr.List)
+		PostRegister(sessionRtr, "/revoke", model.PermSystem, sessionHdr.Revoke)
+		PostRegister(sessionRtr, "/revoke_all", model.PermSystem, sessionHdr.RevokeAll)
+	}
+
+	var auditRtr = e.Group("/api/audit")
+	var auditHdr = new(admin.Audit)
+	{
+		PostRegister(auditRtr, "/list", model.PermSystem, auditHdr.List)
+		PostRegister(auditRtr, "/export", model.PermSystem, auditHdr.Export)
+	}
+
+	var notifyRtr = e.Group("/api/notify")
+	var notifyHdr = new(admin.Notify)
+	{
+		PostRegister(notifyRtr, "/list", model.PermView, notifyHdr.List)
+		PostRegister(notifyRtr, "/redrive", model.PermOrder, notifyHdr.Redrive)
+		PostRegister(notifyRtr, "/del", model.PermOrder, notifyHdr.Del)
 	}
 
 	var orderRtr = e.Group("/api/order")
 	var orderHdr = new(admin.Order)
 	{
-		PostRegister(orderRtr, "/list", true, orderHdr.List)
-		PostRegister(orderRtr, "/create", true, orderHdr.Create)
-		PostRegister(orderRtr, "/detail", true, orderHdr.Detail)
-		PostRegister(orderRtr, "/paid", true, orderHdr.Paid)
-		PostRegister(orderRtr, "/manual_notify", true, orderHdr.ManualNotify)
-		PostRegister(orderRtr, "/cancel", true, orderHdr.Cancel)
-		PostRegister(orderRtr, "/del", true, orderHdr.Del)
+		PostRegister(orderRtr, "/list", model.PermView, orderHdr.List)
+		PostRegister(orderRtr, "/create", model.PermOrder, orderHdr.Create)
+		PostRegister(orderRtr, "/detail", model.PermView, orderHdr.Detail)
+		PostRegister(orderRtr, "/paid", model.PermOrder, orderHdr.Paid)
+		PostRegister(orderRtr, "/manual_notify", model.PermOrder, orderHdr.ManualNotify)
+		PostRegister(orderRtr, "/notify_attempts", model.PermView, orderHdr.NotifyAttempts)
+		PostRegister(orderRtr, "/notify_replay", model.PermOrder, orderHdr.NotifyReplay)
+		PostRegister(orderRtr, "/cancel", model.PermOrder, orderHdr.Cancel)
+		PostRegister(orderRtr, "/del", model.PermOrder, orderHdr.Del)
 	}
 
 	var rateRtr = e.Group("/api/rate")
 	var rateHdr = new(admin.Rate)
 	{
-		PostRegister(rateRtr, "/list", true, rateHdr.List)
-		PostRegister(rateRtr, "/syntax", true, rateHdr.Syntax)
-		PostRegister(rateRtr, "/set_syntax", true, rateHdr.SetSyntax)
-		PostRegister(rateRtr, "/sync", true, rateHdr.Sync)
+		PostRegister(rateRtr, "/list", model.PermView, rateHdr.List)
+		PostRegister(rateRtr, "/syntax", model.PermView, rateHdr.Syntax)
+		PostRegister(rateRtr, "/set_syntax", model.PermSystem, rateHdr.SetSyntax)
+		PostRegister(rateRtr, "/sync", model.PermSystem, rateHdr.Sync)
 	}
 
 	var dashboardRtr = e.Group("/api/dashboard")
 	var dashboardHdr = new(admin.Dashboard)
 	{
-		PostRegister(dashboardRtr, "/home", true, dashboardHdr.Home)
+		PostRegister(dashboardRtr, "/home", model.PermView, dashboardHdr.Home)
 	}
 }
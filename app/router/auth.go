@@ -3,16 +3,20 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/v03413/bepusdt/app/handler/auth"
+	"github.com/v03413/bepusdt/app/model"
 )
 
 func authInit(e *gin.Engine) {
 	var authRtr = e.Group("/api/auth")
 	var authHdr = new(auth.Auth)
 	{
-		GetRegister(authRtr, "/info", true, authHdr.Info)
-		GetRegister(authRtr, "/menu", true, authHdr.Menu)
-		PostRegister(authRtr, "/login", false, authHdr.Login)
-		PostRegister(authRtr, "/logout", true, authHdr.Logout)
-		PostRegister(authRtr, "/set_password", true, authHdr.SetPassword)
+		GetRegister(authRtr, "/info", model.PermLogin, authHdr.Info)
+		GetRegister(authRtr, "/menu", model.PermLogin, authHdr.Menu)
+		PostRegister(authRtr, "/login", model.PermPublic, authHdr.Login)
+		PostRegister(authRtr, "/logout", model.PermLogin, authHdr.Logout)
+		PostRegister(authRtr, "/set_password", model.PermLogin, authHdr.SetPassword)
+		PostRegister(authRtr, "/totp/setup", model.PermLogin, authHdr.TotpSetup)
+		PostRegister(authRtr, "/totp/enable", model.PermLogin, authHdr.TotpEnable)
+		PostRegister(authRtr, "/totp/disable", model.PermLogin, authHdr.TotpDisable)
 	}
 }
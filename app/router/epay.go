@@ -10,5 +10,9 @@ func epayInit(engine *gin.Engine) {
 	{
 		engine.POST("/submit.php", epHdr.Submit)
 		engine.GET("/submit.php", epHdr.Submit)
+		engine.POST("/mapi.php", epHdr.Mapi)
+		engine.GET("/mapi.php", epHdr.Mapi)
+		engine.POST("/api.php", epHdr.Api)
+		engine.GET("/api.php", epHdr.Api)
 	}
 }
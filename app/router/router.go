@@ -1,29 +1,31 @@
 package router
 
 import (
-	"crypto/subtle"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/cast"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/handler/base"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
-	"github.com/v03413/go-cache"
+	"github.com/v03413/bepusdt/app/utils"
 )
 
 var engine *gin.Engine
-var authRoute = make(map[string]bool)
+var authRoute = make(map[string]model.Permission)
 var secureRoute = make(map[string]struct{})
 
 func Handler() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	engine = gin.New()
-	session := memstore.NewStore([]byte(model.GetK(model.AdminSecret)))
+	if err := trustProxies(engine, model.GetC(model.TrustedProxies)); err != nil {
+		log.Warn("受信任代理配置有误，已忽略", err)
+	}
+
+	session := newDbStore([]byte(model.GetK(model.AdminSecret)))
 	session.Options(sessions.Options{
 		MaxAge:   86400,
 		HttpOnly: true,
@@ -68,6 +70,23 @@ func Handler() *gin.Engine {
 	return engine
 }
 
+// trustProxies 仅信任配置的反向代理传递的 X-Forwarded-For，未配置或配置有误时直接使用连接来源 IP
+func trustProxies(e *gin.Engine, raw string) error {
+	list, err := utils.ParseIpAllowList(raw)
+	if err != nil || len(list) == 0 {
+		_ = e.SetTrustedProxies(nil)
+
+		return err
+	}
+
+	var proxies = make([]string, 0, len(list))
+	for _, p := range list {
+		proxies = append(proxies, p.String())
+	}
+
+	return e.SetTrustedProxies(proxies)
+}
+
 func sessionAuth() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if conf.Debug {
@@ -85,16 +104,14 @@ func sessionAuth() gin.HandlerFunc {
 			}
 		}
 
-		var need, ok = authRoute[route]
-		if !ok || !need {
-			ctx.Next()
+		var perm, ok = authRoute[route]
+		if ok && !model.AdminIpAllowed(ctx.ClientIP()) {
+			ctx.JSON(403, gin.H{"code": 403, "msg": "当前 IP 不在后台访问白名单内"})
+			ctx.Abort()
 			return
 		}
-
-		token, ok := cache.Get(conf.AdminTokenK)
-		if !ok {
-			ctx.JSON(403, gin.H{"code": 403, "msg": "token expired, please login again"})
-			ctx.Abort()
+		if !ok || perm == model.PermPublic {
+			ctx.Next()
 			return
 		}
 
@@ -105,12 +122,21 @@ func sessionAuth() gin.HandlerFunc {
 			return
 		}
 
-		if subtle.ConstantTimeCompare([]byte(cast.ToString(token)), []byte(authHeader)) != 1 {
-			ctx.JSON(403, gin.H{"code": 403, "msg": "invalid authorization token"})
+		user, sess, err := base.CheckSession(authHeader)
+		if err != nil {
+			ctx.JSON(403, gin.H{"code": 403, "msg": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		if !user.Can(perm) {
+			ctx.JSON(200, gin.H{"code": 400, "msg": "当前账号没有该操作权限"})
 			ctx.Abort()
 			return
 		}
 
+		base.SetUser(ctx, user)
+		base.SetSession(ctx, sess)
 		ctx.Next()
 	}
 }
@@ -135,19 +161,20 @@ func copyright() gin.HandlerFunc {
 	}
 }
 
-func PostRegister(router *gin.RouterGroup, relativePath string, checkAuth bool, handlers ...gin.HandlerFunc) {
+// PostRegister 注册后台接口，perm 为访问所需权限，PermPublic 表示无需登录
+func PostRegister(router *gin.RouterGroup, relativePath string, perm model.Permission, handlers ...gin.HandlerFunc) {
 	var route = fmt.Sprintf("POST.%s%s", router.BasePath(), relativePath)
 
-	authRoute[route] = checkAuth
+	authRoute[route] = perm
 	secureRoute[route] = struct{}{}
 
 	router.POST(relativePath, handlers...)
 }
 
-func GetRegister(router *gin.RouterGroup, relativePath string, checkAuth bool, handlers ...gin.HandlerFunc) {
+func GetRegister(router *gin.RouterGroup, relativePath string, perm model.Permission, handlers ...gin.HandlerFunc) {
 	var route = fmt.Sprintf("GET.%s%s", router.BasePath(), relativePath)
 
-	authRoute[route] = checkAuth
+	authRoute[route] = perm
 	secureRoute[route] = struct{}{}
 
 	router.GET(relativePath, handlers...)
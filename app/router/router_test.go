@@ -0,0 +1,46 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIp := func(raw string) string {
+		e := gin.New()
+		_ = trustProxies(e, raw)
+		e.GET("/ip", func(ctx *gin.Context) {
+			ctx.String(200, ctx.ClientIP())
+		})
+
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = "203.0.113.9:52000"
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+
+		return w.Body.String()
+	}
+
+	// 未配置受信任代理时伪造的请求头不生效，白名单与登录锁定均按真实来源 IP
+	if ip := clientIp(""); ip != "203.0.113.9" {
+		t.Fatalf("ip = %s, want 203.0.113.9", ip)
+	}
+	if ip := clientIp("198.51.100.0/24"); ip != "203.0.113.9" {
+		t.Fatalf("ip = %s, want 203.0.113.9", ip)
+	}
+	if ip := clientIp("bad-ip"); ip != "203.0.113.9" {
+		t.Fatalf("ip = %s, want 203.0.113.9", ip)
+	}
+
+	// 来自受信任代理的请求才采用 X-Forwarded-For
+	if ip := clientIp("203.0.113.0/24"); ip != "10.0.0.1" {
+		t.Fatalf("ip = %s, want 10.0.0.1", ip)
+	}
+}
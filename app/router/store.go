@@ -0,0 +1,108 @@
+package router
+
+import (
+	"encoding/base32"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// dbStore 基于数据库的 Cookie 会话存储，Cookie 中只保存签名后的会话ID，数据落库以便重启后保持、多实例共享
+type dbStore struct {
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+func newDbStore(keyPairs ...[]byte) *dbStore {
+
+	return &dbStore{
+		codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{Path: "/", MaxAge: 86400},
+	}
+}
+
+func (s *dbStore) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(s.options.MaxAge)
+		}
+	}
+}
+
+func (s *dbStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New 读取 Cookie 中的会话ID并加载数据；Cookie 无效或会话已过期时返回新会话，不视为错误
+func (s *dbStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	var sess = gsessions.NewSession(s, name)
+	var opts = *s.options
+
+	sess.Options = &opts
+	sess.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+
+		return sess, nil
+	}
+	if err = securecookie.DecodeMulti(name, c.Value, &sess.ID, s.codecs...); err != nil {
+		sess.ID = ""
+
+		return sess, nil
+	}
+
+	data, ok := model.GetWebSession(sess.ID)
+	if !ok {
+		sess.ID = ""
+
+		return sess, nil
+	}
+	if err = securecookie.DecodeMulti(name, data, &sess.Values, s.codecs...); err != nil {
+
+		return sess, nil
+	}
+
+	sess.IsNew = false
+
+	return sess, nil
+}
+
+func (s *dbStore) Save(r *http.Request, w http.ResponseWriter, sess *gsessions.Session) error {
+	if sess.Options.MaxAge <= 0 {
+		model.DelWebSession(sess.ID)
+		http.SetCookie(w, gsessions.NewCookie(sess.Name(), "", sess.Options))
+
+		return nil
+	}
+
+	if sess.ID == "" {
+		sess.ID = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	data, err := securecookie.EncodeMulti(sess.Name(), sess.Values, s.codecs...)
+	if err != nil {
+
+		return err
+	}
+	if err = model.SaveWebSession(sess.ID, data, time.Now().Add(time.Duration(sess.Options.MaxAge)*time.Second)); err != nil {
+
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(sess.Name(), sess.ID, s.codecs...)
+	if err != nil {
+
+		return err
+	}
+
+	http.SetCookie(w, gsessions.NewCookie(sess.Name(), encoded, sess.Options))
+
+	return nil
+}
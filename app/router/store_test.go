@@ -0,0 +1,79 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/v03413/bepusdt/app/model"
+	"gorm.io/gorm"
+)
+
+func TestDbStoreSurvivesRestart(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/store.db"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model.Db = db
+	if err = model.Db.AutoMigrate(&model.WebSession{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每次构造新的 engine 与 store，模拟重启或另一个实例
+	newEngine := func() *gin.Engine {
+		gin.SetMode(gin.TestMode)
+
+		var store = newDbStore([]byte("secret"))
+		store.Options(sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true})
+
+		e := gin.New()
+		e.Use(sessions.Sessions("session", store))
+		e.GET("/set", func(ctx *gin.Context) {
+			sess := sessions.Default(ctx)
+			sess.Set("secure", true)
+			_ = sess.Save()
+		})
+		e.GET("/get", func(ctx *gin.Context) {
+			secure, _ := sessions.Default(ctx).Get("secure").(bool)
+			if !secure {
+				ctx.Status(http.StatusForbidden)
+
+				return
+			}
+
+			ctx.Status(http.StatusOK)
+		})
+
+		return e
+	}
+
+	w := httptest.NewRecorder()
+	newEngine().ServeHTTP(w, httptest.NewRequest("GET", "/set", nil))
+
+	var cookie = w.Result().Cookies()
+	if len(cookie) != 1 {
+		t.Fatalf("cookies = %v", cookie)
+	}
+
+	req := httptest.NewRequest("GET", "/get", nil)
+	req.AddCookie(cookie[0])
+	w = httptest.NewRecorder()
+	newEngine().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("session lost after restart, code = %d", w.Code)
+	}
+
+	model.RevokeAllWebSessions()
+
+	req = httptest.NewRequest("GET", "/get", nil)
+	req.AddCookie(cookie[0])
+	w = httptest.NewRecorder()
+	newEngine().ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("revoked session still valid, code = %d", w.Code)
+	}
+}
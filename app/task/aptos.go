@@ -16,7 +16,6 @@ import (
 	"github.com/spf13/cast"
 	"github.com/tidwall/gjson"
 	"github.com/v03413/bepusdt/app/conf"
-	blockapi "github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
@@ -49,6 +48,15 @@ type aptAmount struct {
 	Type   model.TradeType
 }
 
+var aptEventActions = map[string]string{
+	"0x1::fungible_asset::Deposit":  "deposit",
+	"0x1::fungible_asset::Withdraw": "withdraw",
+	"0x1::coin::CoinDeposit":        "deposit",
+	"0x1::coin::CoinWithdraw":       "withdraw",
+	"0x1::coin::DepositEvent":       "deposit",
+	"0x1::coin::WithdrawEvent":      "withdraw",
+}
+
 func init() {
 	apt = newAptos()
 	Register(Task{Callback: apt.versionDispatch})
@@ -73,9 +81,11 @@ func (a *aptos) syncVersionForward(ctx context.Context) {
 		return
 	}
 
-	req, _ := http.NewRequestWithContext(ctx, "GET", model.Endpoint(conf.Aptos)+"/v1", nil)
+	var node = model.Endpoint(conf.Aptos)
+	req, _ := http.NewRequestWithContext(ctx, "GET", aptosBase(node)+"v1", nil)
 	resp, err := a.client.Do(req)
 	if err != nil {
+		conf.RecordFailure(conf.Aptos, node)
 		log.Task.Warn("aptos syncVersionForward Error sending request:", err)
 
 		return
@@ -84,6 +94,7 @@ func (a *aptos) syncVersionForward(ctx context.Context) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		conf.RecordFailure(conf.Aptos, node)
 		log.Task.Warn("aptos syncVersionForward Error response status code:", resp.StatusCode)
 
 		return
@@ -103,12 +114,18 @@ func (a *aptos) syncVersionForward(ctx context.Context) {
 		return
 	}
 
+	var cursor = getCursor(conf.Aptos)
+	if a.lastVersion == 0 { // 启动后从持久化游标继续扫描
+		a.lastVersion = int(cursor.Resume())
+	}
+
 	if now-a.lastVersion > 10000 {
 		a.lastVersion = now - a.versionChunkSize
 	}
 
 	var sub = now - a.lastVersion
 	if sub <= a.versionChunkSize {
+		cursor.Push(int64(a.lastVersion), int64(a.lastVersion+sub-1))
 		a.versionQueue.In <- version{Start: a.lastVersion, Limit: sub}
 	} else {
 		chunks := (sub + a.versionChunkSize - 1) / a.versionChunkSize
@@ -122,6 +139,7 @@ func (a *aptos) syncVersionForward(ctx context.Context) {
 				}
 			}
 
+			cursor.Push(int64(start), int64(start+limit-1))
 			a.versionQueue.In <- version{Start: start, Limit: limit}
 		}
 	}
@@ -139,7 +157,7 @@ func (a *aptos) lookbackVersion(ctx context.Context) {
 		return
 	}
 
-	start, end := blockapi.New().GetBoundaryHeights(startAt, endAt, conf.Aptos)
+	start, end := getBoundaryHeights(conf.Aptos, a, startAt, endAt)
 	for i := int(start); i <= int(end); i += a.versionChunkSize {
 		select {
 		case <-ctx.Done():
@@ -191,12 +209,13 @@ func (a *aptos) versionParse(n any) {
 	p := n.(version)
 
 	var net = conf.Aptos
-	var url = fmt.Sprintf("%sv1/transactions?start=%d&limit=%d", model.Endpoint(conf.Aptos), p.Start, p.Limit)
+	var node = model.Endpoint(conf.Aptos)
+	var url = fmt.Sprintf("%sv1/transactions?start=%d&limit=%d", aptosBase(node), p.Start, p.Limit)
 
-	conf.RecordSuccess(net, cast.ToString(p.Start+p.Limit))
 	resp, err := a.client.Get(url)
 	if err != nil {
-		conf.RecordFailure(net)
+		conf.RecordFailure(net, node)
+		a.versionQueue.In <- p
 		log.Task.Warn("versionParse Error sending request:", err)
 
 		return
@@ -204,7 +223,8 @@ func (a *aptos) versionParse(n any) {
 
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		conf.RecordFailure(net)
+		conf.RecordFailure(net, node)
+		a.versionQueue.In <- p
 		log.Task.Warn("versionParse Error response status code:", resp.StatusCode)
 
 		return
@@ -212,7 +232,7 @@ func (a *aptos) versionParse(n any) {
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		conf.RecordFailure(net)
+		conf.RecordFailure(net, node)
 		a.versionQueue.In <- p
 		log.Task.Warn("versionParse Error reading response body:", err)
 
@@ -220,13 +240,15 @@ func (a *aptos) versionParse(n any) {
 	}
 
 	if !gjson.ValidBytes(body) {
-		conf.RecordFailure(net)
+		conf.RecordFailure(net, node)
 		a.versionQueue.In <- p
 		log.Task.Warn("versionParse Error: invalid JSON response body")
 
 		return
 	}
 
+	conf.RecordSuccess(net, node, cast.ToString(p.Start+p.Limit))
+
 	transfers := make([]transfer, 0)
 	for _, trans := range gjson.ParseBytes(body).Array() {
 		tsNano := trans.Get("timestamp").Int() * 1000
@@ -238,6 +260,7 @@ func (a *aptos) versionParse(n any) {
 		addrType := make(map[string]model.TradeType)                                 // [address] => tradeType
 		amtAddrMap := map[string]map[aptAmount]string{"deposit": {}, "withdraw": {}} // [amount] => address
 		aptEvents := make([]aptEvent, 0)
+		aptCoinStores := make(map[string]struct{}) // 持有 CoinStore<AptosCoin> 的账户，用于识别旧版 Coin 事件
 		trans.Get("changes").ForEach(func(_, v gjson.Result) bool {
 			if v.Get("type").String() != "write_resource" {
 
@@ -247,16 +270,21 @@ func (a *aptos) versionParse(n any) {
 			data := v.Get("data")
 			if data.Get("type").String() == "0x1::fungible_asset::FungibleStore" {
 				addr := v.Get("address").String()
-				switch data.Get("data.metadata.inner").String() {
-				case conf.UsdtAptos:
+				switch inner := data.Get("data.metadata.inner").String(); {
+				case inner == conf.UsdtAptos:
 					addrType[addr] = model.UsdtAptos
-				case conf.UsdcAptos:
+				case inner == conf.UsdcAptos:
 					addrType[addr] = model.UsdcAptos
+				case a.padAddressLeadingZeros(inner) == a.padAddressLeadingZeros(conf.AptFaAddress):
+					addrType[addr] = model.AptosApt
 				}
 			}
 			if data.Get("type").String() == "0x1::object::ObjectCore" {
 				addrOwner[v.Get("address").String()] = data.Get("data.owner").String()
 			}
+			if data.Get("type").String() == fmt.Sprintf("0x1::coin::CoinStore<%s>", conf.AptCoinType) {
+				aptCoinStores[v.Get("address").String()] = struct{}{}
+			}
 
 			return true
 		})
@@ -268,15 +296,41 @@ func (a *aptos) versionParse(n any) {
 				return true
 			}
 
-			address := v.Get("data.store").String()
+			var action, address string
 			switch v.Get("type").String() {
-			case "0x1::fungible_asset::Deposit":
-				aptEvents = append(aptEvents, aptEvent{Amount: amt, Address: address, Action: "deposit"})
-				amtAddrMap["deposit"][aptAmount{Amount: amount, Type: addrType[address]}] = address
-			case "0x1::fungible_asset::Withdraw":
-				amtAddrMap["withdraw"][aptAmount{Amount: amount, Type: addrType[address]}] = address
-				aptEvents = append(aptEvents, aptEvent{Amount: amt, Address: address, Action: "withdraw"})
+			case "0x1::fungible_asset::Deposit", "0x1::fungible_asset::Withdraw":
+				action = aptEventActions[v.Get("type").String()]
+				address = v.Get("data.store").String()
+			case "0x1::coin::CoinDeposit", "0x1::coin::CoinWithdraw":
+				// Coin 标准事件直接记录账户地址，账户即所有者
+				if v.Get("data.coin_type").String() != conf.AptCoinType {
+
+					return true
+				}
+
+				action = aptEventActions[v.Get("type").String()]
+				address = v.Get("data.account").String()
+				addrType[address] = model.AptosApt
+				addrOwner[address] = address
+			case "0x1::coin::DepositEvent", "0x1::coin::WithdrawEvent":
+				// 旧版事件不携带币种，只认可本交易内写入了 CoinStore<AptosCoin> 的账户
+				address = v.Get("guid.account_address").String()
+				if _, ok := aptCoinStores[address]; !ok {
+
+					return true
+				}
+
+				action = aptEventActions[v.Get("type").String()]
+				addrType[address] = model.AptosApt
+				addrOwner[address] = address
+			default:
+
+				return true
 			}
+
+			amtAddrMap[action][aptAmount{Amount: amount, Type: addrType[address]}] = address
+			aptEvents = append(aptEvents, aptEvent{Amount: amt, Address: address, Action: action})
+
 			return true
 		})
 
@@ -364,13 +418,10 @@ func (a *aptos) versionParse(n any) {
 			}
 		}
 
-		// 处理 USDT
-		usdtDeposits, usdtFrom := processEvents(model.UsdtAptos, aptEvents)
-		generateTransfers(usdtDeposits, usdtFrom, model.UsdtAptos, model.GetTradeDecimal(model.UsdtAptos))
-
-		// 处理 USDC
-		usdcDeposits, usdcFrom := processEvents(model.UsdcAptos, aptEvents)
-		generateTransfers(usdcDeposits, usdcFrom, model.UsdcAptos, model.GetTradeDecimal(model.UsdcAptos))
+		for _, t := range []model.TradeType{model.UsdtAptos, model.UsdcAptos, model.AptosApt} {
+			deposits, from := processEvents(t, aptEvents)
+			generateTransfers(deposits, from, t, model.GetTradeDecimal(t))
+		}
 	}
 
 	if len(transfers) > 0 {
@@ -378,9 +429,71 @@ func (a *aptos) versionParse(n any) {
 		transferQueue.In <- transfers
 	}
 
+	getCursor(net).Done(int64(p.Start), int64(p.Start+p.Limit-1))
+
 	log.Task.Info(fmt.Sprintf("区块扫描完成(Aptos) %d.%d 成功率：%s", p.Start, p.Limit, conf.GetSuccessRate(net)))
 }
 
+func (a *aptos) latestHeight(ctx context.Context) (int64, error) {
+	res, err := a.get(ctx, "v1")
+	if err != nil {
+
+		return 0, err
+	}
+
+	return res.Get("ledger_version").Int(), nil
+}
+
+func (a *aptos) blockTime(ctx context.Context, height int64) (int64, error) {
+	res, err := a.get(ctx, fmt.Sprintf("v1/blocks/by_version/%d", height))
+	if err != nil {
+
+		return 0, err
+	}
+
+	return res.Get("block_timestamp").Int() / 1e6, nil
+}
+
+func (a *aptos) get(ctx context.Context, path string) (gjson.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.endpoint()+path, nil)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	if resp.StatusCode != 200 {
+
+		return gjson.Result{}, fmt.Errorf("aptos response status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	return gjson.ParseBytes(body), nil
+}
+
+func (a *aptos) endpoint() string {
+
+	return aptosBase(model.Endpoint(conf.Aptos))
+}
+
+// aptosBase 节点地址统一以 / 结尾
+func aptosBase(node string) string {
+
+	return strings.TrimSuffix(node, "/") + "/"
+}
+
 func (a *aptos) padAddressLeadingZeros(addr string) string {
 	addr = strings.TrimPrefix(addr, "0x")
 	addr = strings.Repeat("0", 64-len(addr)) + addr
@@ -402,7 +515,7 @@ func (a *aptos) tradeConfirmHandle(ctx context.Context) {
 			}
 		}
 
-		req, _ := http.NewRequestWithContext(ctx, "GET", model.Endpoint(conf.Aptos)+"v1/transactions/by_hash/"+o.RefHash, nil)
+		req, _ := http.NewRequestWithContext(ctx, "GET", a.endpoint()+"v1/transactions/by_hash/"+o.RefHash, nil)
 		resp, err := a.client.Do(req)
 		if err != nil {
 			log.Task.Warn("aptos tradeConfirmHandle Error sending request:", err)
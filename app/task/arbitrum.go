@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallnest/chanx"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
 )
 
@@ -16,12 +17,18 @@ func arbitrumInit() {
 		Block: block{
 			ConfirmedOffset: 40,
 		},
+		Native: evmNative{
+			Parse:     true,
+			Decimal:   conf.ArbitrumEthDecimals,
+			TradeType: model.ArbitrumEth,
+		},
 		Client:         utils.NewHttpClient(),
 		blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 30),
 	}
 
 	Register(Task{Callback: arb.blockDispatch})
 	Register(Task{Callback: arb.syncBlocksForward, Duration: time.Second * 5})
+	Register(Task{Callback: arb.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: arb.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: arb.lookbackBlocks, Duration: time.Second * 15})
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallnest/chanx"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
 )
 
@@ -16,12 +17,18 @@ func baseInit() {
 		Block: block{
 			ConfirmedOffset: 40,
 		},
+		Native: evmNative{
+			Parse:     true,
+			Decimal:   conf.BaseEthDecimals,
+			TradeType: model.BaseEth,
+		},
 		Client:         utils.NewHttpClient(),
 		blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 30),
 	}
 
 	Register(Task{Callback: base.blockDispatch})
 	Register(Task{Callback: base.syncBlocksForward, Duration: time.Second * 5})
+	Register(Task{Callback: base.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: base.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: base.lookbackBlocks, Duration: time.Second * 15})
 }
@@ -0,0 +1,26 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallnest/chanx"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/utils"
+)
+
+func bitcoinInit() {
+	btc := &utxo{
+		Network:         conf.Bitcoin,
+		TradeType:       model.BitcoinBtc,
+		ConfirmedOffset: 2,
+		Client:          utils.NewHttpClient(),
+		blockQueue:      chanx.NewUnboundedChan[int](context.Background(), 30),
+	}
+
+	Register(Task{Callback: btc.blockDispatch})
+	Register(Task{Callback: btc.syncBlocksForward, Duration: time.Second * 30})
+	Register(Task{Callback: btc.tradeConfirmHandle, Duration: time.Second * 30})
+	Register(Task{Callback: btc.lookbackBlocks, Duration: time.Minute})
+}
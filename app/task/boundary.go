@@ -0,0 +1,135 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	blockapi "github.com/v03413/bepusdt/app/core"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/go-cache"
+)
+
+// blockClock 区块时间查询，用于本地二分查找回溯时间范围对应的区块高度
+type blockClock interface {
+	latestHeight(ctx context.Context) (int64, error)
+	blockTime(ctx context.Context, height int64) (int64, error) // 区块时间 unix 秒
+}
+
+const (
+	boundaryCacheTTL = time.Hour
+	boundaryRound    = 60 // 查找时间按分钟取整，调用方基于当前时间计算，取整后缓存才能命中
+)
+
+// getBoundaryHeights 获取时间范围对应的区块高度范围，本地查找失败时按配置回退到远程接口
+func getBoundaryHeights(network string, c blockClock, startAt, endAt int64) (int64, int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+
+	start, end, err := searchBoundaryHeights(ctx, network, c, startAt, endAt)
+	if err == nil {
+
+		return start, end
+	}
+
+	log.Task.Warn(fmt.Sprintf("区块高度查找失败(%s)：%s", network, err.Error()))
+	if model.GetC(model.LookbackApiFallback) != "1" {
+
+		return 0, 0
+	}
+
+	return blockapi.New().GetBoundaryHeights(startAt, endAt, network)
+}
+
+func searchBoundaryHeights(ctx context.Context, network string, c blockClock, startAt, endAt int64) (int64, int64, error) {
+	latest, err := c.latestHeight(ctx)
+	if err != nil {
+
+		return 0, 0, fmt.Errorf("获取最新高度失败：%w", err)
+	}
+
+	// 开始时间向下、结束时间向上取整，范围只会放宽不会遗漏
+	startAt -= startAt % boundaryRound
+	if endAt%boundaryRound != 0 {
+		endAt += boundaryRound - endAt%boundaryRound
+	}
+
+	start, err := searchHeight(ctx, network, c, startAt, latest)
+	if err != nil {
+
+		return 0, 0, err
+	}
+
+	end, err := searchHeight(ctx, network, c, endAt, latest)
+	if err != nil {
+
+		return 0, 0, err
+	}
+
+	// end 取时间点之后的第一个区块，保证范围完整覆盖
+	if end < latest {
+		end++
+	}
+
+	return start, end, nil
+}
+
+// searchHeight 查找区块时间不晚于 ts 的最高区块，先向后指数扩展确定区间再二分
+func searchHeight(ctx context.Context, network string, c blockClock, ts, latest int64) (int64, error) {
+	var key = fmt.Sprintf("boundary_%s_%d", network, ts)
+	if v, ok := cache.Get(key); ok {
+
+		return v.(int64), nil
+	}
+
+	latestAt, err := c.blockTime(ctx, latest)
+	if err != nil {
+
+		return 0, fmt.Errorf("获取区块时间失败 %d：%w", latest, err)
+	}
+	if latestAt <= ts {
+
+		return latest, nil
+	}
+
+	var hi = latest
+	var lo = latest
+	for step := int64(1); ; step *= 2 {
+		lo = hi - step
+		if lo < 1 {
+			lo = 1
+		}
+
+		at, err := c.blockTime(ctx, lo)
+		if err != nil {
+
+			return 0, fmt.Errorf("获取区块时间失败 %d：%w", lo, err)
+		}
+		if at <= ts || lo == 1 {
+
+			break
+		}
+
+		hi = lo
+	}
+
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		at, err := c.blockTime(ctx, mid)
+		if err != nil {
+
+			return 0, fmt.Errorf("获取区块时间失败 %d：%w", mid, err)
+		}
+
+		if at <= ts {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	cache.Set(key, lo, boundaryCacheTTL)
+
+	return lo, nil
+}
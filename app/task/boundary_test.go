@@ -0,0 +1,51 @@
+package task
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeClock 每个区块间隔 10 秒，记录区块时间查询次数
+type fakeClock struct {
+	latest int64
+	calls  int
+}
+
+func (f *fakeClock) latestHeight(context.Context) (int64, error) {
+
+	return f.latest, nil
+}
+
+func (f *fakeClock) blockTime(_ context.Context, height int64) (int64, error) {
+	f.calls++
+
+	return 1_700_000_000 + height*10, nil
+}
+
+func TestSearchBoundaryHeightsCache(t *testing.T) {
+	var c = &fakeClock{latest: 10000}
+	var ctx = context.Background()
+	var base = int64(1_700_000_000 + 5000*10)
+	base -= base % 60
+
+	start, end, err := searchBoundaryHeights(ctx, "test-boundary", c, base+5, base+605)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start > 5000 || end < 5061 {
+		t.Fatalf("range = %d-%d, want to cover 5000-5061", start, end)
+	}
+
+	// 同一分钟内再次查找应全部命中缓存
+	var calls = c.calls
+	start2, end2, err := searchBoundaryHeights(ctx, "test-boundary", c, base+30, base+630)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.calls != calls {
+		t.Fatalf("blockTime called %d more times, want cache hit", c.calls-calls)
+	}
+	if start2 != start || end2 != end {
+		t.Fatalf("cached range = %d-%d, want %d-%d", start2, end2, start, end)
+	}
+}
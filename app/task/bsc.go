@@ -28,6 +28,7 @@ func bscInit() {
 
 	Register(Task{Callback: bsc.blockDispatch})
 	Register(Task{Callback: bsc.syncBlocksForward, Duration: time.Second * 5})
+	Register(Task{Callback: bsc.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: bsc.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: bsc.lookbackBlocks, Duration: time.Second * 15})
 }
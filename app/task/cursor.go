@@ -0,0 +1,115 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// scanCursor 扫描游标，跟踪正向同步入列的区块范围；
+// 区块处理可能乱序完成，只有连续完成的高度才会被持久化，保证重启后不会跳过未处理的区块
+type scanCursor struct {
+	network string
+	mu      sync.Mutex
+	pending map[int64]int64 // 已入列未完成的区块范围 from => to
+	last    int64           // 已入列的最大高度
+	saved   int64           // 已持久化的高度
+}
+
+var cursors sync.Map
+
+func init() {
+	Register(Task{Duration: time.Second * 3, Callback: cursorFlush})
+}
+
+func getCursor(network string) *scanCursor {
+	v, _ := cursors.LoadOrStore(network, &scanCursor{network: network, pending: make(map[int64]int64)})
+
+	return v.(*scanCursor)
+}
+
+// Resume 读取持久化高度，作为启动后的同步起点
+func (c *scanCursor) Resume() int64 {
+	var height = model.GetScanCursor(c.network)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last == 0 {
+		c.last = height
+		c.saved = height
+	}
+
+	return height
+}
+
+// Push 正向同步区块范围入列
+func (c *scanCursor) Push(from, to int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[from] = to
+	if to > c.last {
+		c.last = to
+	}
+}
+
+// Done 区块范围处理完成，未完整覆盖入列范围的（例如回溯）会被忽略
+func (c *scanCursor) Done(from, to int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if end, ok := c.pending[from]; ok && end <= to {
+		delete(c.pending, from)
+	}
+}
+
+// Height 已连续处理完成的高度
+func (c *scanCursor) Height() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var height = c.last
+	for from := range c.pending {
+		if from-1 < height {
+			height = from - 1
+		}
+	}
+
+	return height
+}
+
+func (c *scanCursor) flush() {
+	var height = c.Height()
+
+	c.mu.Lock()
+	var saved = c.saved
+	c.mu.Unlock()
+
+	if height <= 0 || height == saved {
+
+		return
+	}
+
+	if err := model.SetScanCursor(c.network, height); err != nil {
+		log.Task.Warn(fmt.Sprintf("扫描游标保存失败(%s)：%s", c.network, err.Error()))
+
+		return
+	}
+
+	c.mu.Lock()
+	c.saved = height
+	c.mu.Unlock()
+}
+
+func cursorFlush(context.Context) {
+	cursors.Range(func(_, v any) bool {
+		v.(*scanCursor).flush()
+
+		return true
+	})
+}
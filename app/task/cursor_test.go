@@ -0,0 +1,37 @@
+package task
+
+import "testing"
+
+func TestScanCursorHeight(t *testing.T) {
+	c := &scanCursor{network: "test", pending: make(map[int64]int64), last: 100}
+
+	c.Push(101, 110)
+	c.Push(111, 120)
+	c.Push(121, 130)
+
+	if h := c.Height(); h != 100 {
+		t.Fatalf("height = %d, want 100", h)
+	}
+
+	// 乱序完成，前面的范围未完成时高度不推进
+	c.Done(111, 120)
+	if h := c.Height(); h != 100 {
+		t.Fatalf("height = %d, want 100", h)
+	}
+
+	c.Done(101, 110)
+	if h := c.Height(); h != 120 {
+		t.Fatalf("height = %d, want 120", h)
+	}
+
+	// 回溯范围未完整覆盖时忽略
+	c.Done(121, 125)
+	if h := c.Height(); h != 120 {
+		t.Fatalf("height = %d, want 120", h)
+	}
+
+	c.Done(121, 130)
+	if h := c.Height(); h != 130 {
+		t.Fatalf("height = %d, want 130", h)
+	}
+}
@@ -0,0 +1,39 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+	"gorm.io/gorm"
+)
+
+// setupTestDb 使用临时 SQLite 替换 model.Db 并迁移所需的表，同时初始化任务日志，测试结束后恢复
+func setupTestDb(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	if err = log.Init(filepath.Join(t.TempDir(), "logs")); err != nil {
+		t.Fatal(err)
+	}
+
+	var old = model.Db
+	model.Db = db
+	t.Cleanup(func() {
+		model.Db = old
+		log.Close()
+		if sqlDb, err := db.DB(); err == nil {
+			_ = sqlDb.Close()
+		}
+	})
+
+	return db
+}
@@ -0,0 +1,129 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/utils"
+	"github.com/v03413/tronprotocol/api"
+)
+
+// endpointProber RPC 节点后台探测，记录延迟并让故障节点恢复后重新参与调度
+type endpointProber struct {
+	client *http.Client
+}
+
+func init() {
+	var p = endpointProber{client: utils.NewHttpClient()}
+
+	Register(Task{Duration: time.Second * 30, Callback: p.probe})
+}
+
+func (p endpointProber) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, net := range model.GetEndpointNetworks() {
+		for _, url := range model.Endpoints(net) {
+			wg.Add(1)
+			go func(net model.Network, url string) {
+				defer wg.Done()
+
+				ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+				defer cancel()
+
+				var start = time.Now()
+				var err = p.check(ctx, net, url)
+
+				conf.ProbeEndpoint(string(net), url, time.Since(start), err)
+			}(net, url)
+		}
+	}
+
+	wg.Wait()
+}
+
+func (p endpointProber) check(ctx context.Context, net model.Network, url string) error {
+	switch net {
+	case conf.Tron:
+		conn, err := tr.clientFor(url)
+		if err != nil {
+
+			return err
+		}
+
+		_, err = api.NewWalletClient(conn).GetNowBlock2(ctx, nil)
+
+		return err
+	case conf.Solana:
+
+		return p.jsonRpc(ctx, url, `{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	case conf.Aptos:
+		req, _ := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(url, "/")+"/v1", nil)
+
+		return p.do(req, "ledger_version")
+	case conf.Bitcoin, conf.Litecoin:
+		if utxoEsplora(url) {
+			req, _ := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(url, "/")+"/blocks/tip/height", nil)
+
+			return p.do(req, "@this")
+		}
+
+		return p.jsonRpc(ctx, url, `{"jsonrpc":"1.0","method":"getblockcount","params":[],"id":1}`)
+	default:
+
+		return p.jsonRpc(ctx, url, `{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
+	}
+}
+
+func (p endpointProber) jsonRpc(ctx context.Context, url, post string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBufferString(post))
+	if err != nil {
+
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return p.do(req, "result")
+}
+
+func (p endpointProber) do(req *http.Request, field string) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+
+		return err
+	}
+
+	var data = gjson.ParseBytes(body)
+	if e := data.Get("error"); e.Exists() && e.Type != gjson.Null { // Bitcoin Core 成功时也返回 "error": null
+
+		return fmt.Errorf("响应错误 %s", data.Get("error").String())
+	}
+	if !data.Get(field).Exists() {
+
+		return fmt.Errorf("响应数据异常 %s", string(body))
+	}
+
+	return nil
+}
@@ -29,6 +29,7 @@ func ethInit() {
 
 	Register(Task{Callback: eth.blockDispatch})
 	Register(Task{Callback: eth.syncBlocksForward, Duration: time.Second * 12})
+	Register(Task{Callback: eth.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: eth.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: eth.lookbackBlocks, Duration: time.Second * 20})
 }
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
@@ -18,7 +19,6 @@ import (
 	"github.com/spf13/cast"
 	"github.com/tidwall/gjson"
 	"github.com/v03413/bepusdt/app/conf"
-	blockapi "github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
@@ -48,6 +48,7 @@ type evm struct {
 	Native           evmNative
 	Client           *http.Client
 	blockScanQueue   *chanx.UnboundedChan[evmBlock]
+	wsAlive          atomic.Bool   // WebSocket 订阅是否正常，正常时暂停轮询
 	LookbackInterval time.Duration // 回溯时每批入队的间隔，控制 RPC 调用速率；默认 500ms
 }
 
@@ -57,13 +58,19 @@ type evmBlock struct {
 }
 
 func (e *evm) syncBlocksForward(ctx context.Context) {
+	if e.wsAlive.Load() { // WebSocket 订阅正常时由 newHeads 推送驱动
+
+		return
+	}
+
 	if syncBreak(e.Network, e.blockScanQueue.Len()) {
 
 		return
 	}
 
+	var url = e.rpcEndpoint()
 	post := []byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
-	req, err := http.NewRequestWithContext(ctx, "POST", e.rpcEndpoint(), bytes.NewBuffer(post))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(post))
 	if err != nil {
 		log.Task.Warn("Error creating request:", err)
 
@@ -73,6 +80,7 @@ func (e *evm) syncBlocksForward(ctx context.Context) {
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := e.Client.Do(req)
 	if err != nil {
+		conf.RecordFailure(e.Network, url)
 		log.Task.Warn("Error sending request:", err)
 
 		return
@@ -89,20 +97,28 @@ func (e *evm) syncBlocksForward(ctx context.Context) {
 
 	var res = gjson.ParseBytes(body)
 	if !res.IsObject() {
+		conf.RecordFailure(e.Network, url)
 		log.Task.Warn(fmt.Sprintf("EVM 数据解析错误(%s): %s", e.Network, string(body)))
 
 		return
 	}
 
-	var now = utils.HexStr2Int(res.Get("result").String()).Int64() - e.Block.RollDelayOffset
+	e.enqueueForward(utils.HexStr2Int(res.Get("result").String()).Int64() - e.Block.RollDelayOffset)
+}
+
+// enqueueForward 将上次扫描高度到 now 之间的区块加入扫描队列
+func (e *evm) enqueueForward(now int64) {
 	if now <= 0 {
 
 		return
 	}
 
+	var cursor = getCursor(e.Network)
 	var lastBlockNumber int64
 	if v, ok := chainBlockNum.Load(e.Network); ok {
 		lastBlockNumber = v.(int64)
+	} else { // 启动后从持久化游标继续扫描
+		lastBlockNumber = cursor.Resume()
 	}
 
 	if now-lastBlockNumber > cast.ToInt64(model.GetC(model.BlockHeightMaxDiff)) {
@@ -122,6 +138,7 @@ func (e *evm) syncBlocksForward(ctx context.Context) {
 			to = now
 		}
 
+		cursor.Push(from, to)
 		e.blockScanQueue.In <- evmBlock{From: from, To: to}
 	}
 }
@@ -141,7 +158,7 @@ func (e *evm) lookbackBlocks(ctx context.Context) {
 		interval = time.Millisecond * 300
 	}
 
-	start, end := blockapi.New().GetBoundaryHeights(startAt, endAt, e.Network)
+	start, end := getBoundaryHeights(e.Network, e, startAt, endAt)
 	for i := start; i <= end; i += blockParseMaxNum {
 		select {
 		case <-ctx.Done():
@@ -160,6 +177,30 @@ func (e *evm) lookbackBlocks(ctx context.Context) {
 	}
 }
 
+func (e *evm) latestHeight(ctx context.Context) (int64, error) {
+	res, err := e.rpcCall(ctx, "eth_blockNumber", "[]")
+	if err != nil {
+
+		return 0, err
+	}
+
+	return utils.HexStr2Int(res.String()).Int64() - e.Block.RollDelayOffset, nil
+}
+
+func (e *evm) blockTime(ctx context.Context, height int64) (int64, error) {
+	res, err := e.rpcCall(ctx, "eth_getBlockByNumber", fmt.Sprintf(`["0x%x",false]`, height))
+	if err != nil {
+
+		return 0, err
+	}
+	if !res.IsObject() {
+
+		return 0, fmt.Errorf("区块不存在 %d", height)
+	}
+
+	return utils.HexStr2Int(res.Get("timestamp").String()).Int64(), nil
+}
+
 func (e *evm) blockDispatch(ctx context.Context) {
 	p, err := ants.NewPoolWithFunc(3, e.getBlockByNumber)
 	if err != nil {
@@ -200,7 +241,8 @@ func (e *evm) getBlockByNumber(a any) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", e.rpcEndpoint(), bytes.NewBuffer([]byte(fmt.Sprintf(`[%s]`, strings.Join(items, ",")))))
+	var url = e.rpcEndpoint()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(fmt.Sprintf(`[%s]`, strings.Join(items, ",")))))
 	if err != nil {
 		log.Task.Warn("Error creating request:", err)
 
@@ -210,7 +252,7 @@ func (e *evm) getBlockByNumber(a any) {
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := e.Client.Do(req)
 	if err != nil {
-		conf.RecordFailure(e.Network)
+		conf.RecordFailure(e.Network, url)
 		e.blockScanQueue.In <- b
 		log.Task.Warn("eth_getBlockByNumber Error sending request:", err)
 
@@ -221,20 +263,21 @@ func (e *evm) getBlockByNumber(a any) {
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		conf.RecordFailure(e.Network)
+		conf.RecordFailure(e.Network, url)
 		e.blockScanQueue.In <- b
 		log.Task.Warn("eth_getBlockByNumber Error reading response body:", err)
 
 		return
 	}
 
-	conf.RecordSuccess(e.Network, cast.ToString(b.To))
+	conf.RecordSuccess(e.Network, url, cast.ToString(b.To))
 
 	nativeTransfers := make([]transfer, 0)
 	blockTimestamp := make(map[string]time.Time)
+	blockHashes := make(map[int64]evmBlockHash)
 	for _, itm := range gjson.ParseBytes(body).Array() {
 		if itm.Get("error").Exists() {
-			conf.RecordFailure(e.Network)
+			conf.RecordFailure(e.Network, url)
 			e.blockScanQueue.In <- b
 			log.Task.Warn(fmt.Sprintf("%s eth_getBlockByNumber response error %s", e.Network, itm.Get("error").String()))
 
@@ -245,6 +288,10 @@ func (e *evm) getBlockByNumber(a any) {
 		blockTime := time.Unix(timestamp, 0)
 		blockNumHex := itm.Get("result.number").String()
 		blockTimestamp[blockNumHex] = blockTime
+		blockHashes[utils.HexStr2Int(blockNumHex).Int64()] = evmBlockHash{
+			Hash:   itm.Get("result.hash").String(),
+			Parent: itm.Get("result.parentHash").String(),
+		}
 
 		var array = itm.Get("result.transactions").Array()
 		if e.Native.Parse && len(array) != 0 {
@@ -255,7 +302,7 @@ func (e *evm) getBlockByNumber(a any) {
 
 	transfers, err := e.parseEventTransfer(b, blockTimestamp)
 	if err != nil {
-		conf.RecordFailure(e.Network)
+		conf.RecordFailure(e.Network, url)
 		e.blockScanQueue.In <- b
 		log.Task.Warn("Evm Block Parse Error parsing block transfer:", err)
 
@@ -269,6 +316,12 @@ func (e *evm) getBlockByNumber(a any) {
 		transferQueue.In <- transfers
 	}
 
+	getCursor(e.Network).Done(b.From, b.To)
+
+	if height := getEvmHashes(e.Network).record(blockHashes); height > 0 {
+		e.reorgHandle(height)
+	}
+
 	log.Task.Info(fmt.Sprintf("区块扫描完成(%s): %d → %d 成功率：%s", e.Network, b.From, b.To, conf.GetSuccessRate(e.Network)))
 }
 
@@ -316,68 +369,63 @@ func (e *evm) parseNativeTransfer(array []gjson.Result, num int, timestamp time.
 }
 
 func (e *evm) parseEventTransfer(b evmBlock, timestamp map[string]time.Time) ([]transfer, error) {
-	transfers := make([]transfer, 0)
-	post := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x%x","toBlock":"0x%x","topics":["%s"]}],"id":1}`, b.From, b.To, evmTransferEvent))
-	resp, err := e.Client.Post(e.rpcEndpoint(), "application/json", bytes.NewBuffer(post))
-	if err != nil {
-
-		return transfers, errors.Join(errors.New("eth_getLogs Post Error"), err)
-	}
-
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
 
-	body, err := io.ReadAll(resp.Body)
+	transfers := make([]transfer, 0)
+	logs, err := e.getTransferLogs(ctx, b)
 	if err != nil {
 
-		return transfers, errors.Join(errors.New("eth_getLogs ReadAll Error"), err)
+		return transfers, errors.Join(errors.New("eth_getLogs Error"), err)
 	}
 
-	data := gjson.ParseBytes(body)
-	if data.Get("error").Exists() {
-
-		return transfers, errors.New(fmt.Sprintf("%s eth_getLogs response error %s", e.Network, data.Get("error").String()))
+	for _, itm := range logs {
+		if t, ok := e.parseTransferLog(itm, timestamp[itm.Get("blockNumber").String()]); ok {
+			transfers = append(transfers, t)
+		}
 	}
 
-	for _, itm := range data.Get("result").Array() {
-		to := itm.Get("address").String()
-		tradeType, ok := model.GetContractTrade(to)
-		if !ok {
+	return transfers, nil
+}
 
-			continue
-		}
+// parseTransferLog 解析 ERC20 Transfer 事件日志
+func (e *evm) parseTransferLog(itm gjson.Result, timestamp time.Time) (transfer, bool) {
+	to := itm.Get("address").String()
+	tradeType, ok := model.GetContractTrade(model.Network(e.Network), to)
+	if !ok {
 
-		topics := itm.Get("topics").Array()
-		if len(topics) < 3 {
+		return transfer{}, false
+	}
 
-			continue
-		}
+	topics := itm.Get("topics").Array()
+	if len(topics) < 3 {
 
-		if topics[0].String() != evmTransferEvent { // transfer event signature
+		return transfer{}, false
+	}
 
-			continue
-		}
+	if topics[0].String() != evmTransferEvent { // transfer event signature
 
-		from := fmt.Sprintf("0x%s", topics[1].String()[26:])
-		recv := fmt.Sprintf("0x%s", topics[2].String()[26:])
-		amount, ok := big.NewInt(0).SetString(itm.Get("data").String()[2:], 16)
-		if !ok || amount.Sign() <= 0 {
+		return transfer{}, false
+	}
 
-			continue
-		}
+	from := fmt.Sprintf("0x%s", topics[1].String()[26:])
+	recv := fmt.Sprintf("0x%s", topics[2].String()[26:])
+	amount, ok := big.NewInt(0).SetString(itm.Get("data").String()[2:], 16)
+	if !ok || amount.Sign() <= 0 {
 
-		transfers = append(transfers, transfer{
-			Network:     e.Network,
-			FromAddress: from,
-			RecvAddress: recv,
-			Amount:      decimal.NewFromBigInt(amount, model.GetContractDecimal(to)),
-			TxHash:      itm.Get("transactionHash").String(),
-			BlockNum:    cast.ToInt(itm.Get("blockNumber").String()),
-			Timestamp:   timestamp[itm.Get("blockNumber").String()],
-			TradeType:   tradeType,
-		})
+		return transfer{}, false
 	}
 
-	return transfers, nil
+	return transfer{
+		Network:     e.Network,
+		FromAddress: from,
+		RecvAddress: recv,
+		Amount:      decimal.NewFromBigInt(amount, model.GetContractDecimal(model.Network(e.Network), to)),
+		TxHash:      itm.Get("transactionHash").String(),
+		BlockNum:    cast.ToInt(itm.Get("blockNumber").String()),
+		Timestamp:   timestamp,
+		TradeType:   tradeType,
+	}, true
 }
 
 func (e *evm) tradeConfirmHandle(ctx context.Context) {
@@ -443,6 +491,67 @@ func (e *evm) tradeConfirmHandle(ctx context.Context) {
 	wg.Wait()
 }
 
+// rpcCall 发起单个 JSON-RPC 请求，返回 result 字段
+func (e *evm) rpcCall(ctx context.Context, method, params string) (gjson.Result, error) {
+	post := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"%s","params":%s,"id":1}`, method, params))
+	req, err := http.NewRequestWithContext(ctx, "POST", e.rpcEndpoint(), bytes.NewBuffer(post))
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.Client.Do(req)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	data := gjson.ParseBytes(body)
+	if data.Get("error").Exists() {
+
+		return gjson.Result{}, &evmRpcError{
+			Network: e.Network,
+			Method:  method,
+			Status:  resp.StatusCode,
+			Code:    data.Get("error.code").Int(),
+			Message: data.Get("error").String(),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+
+		return gjson.Result{}, &evmRpcError{Network: e.Network, Method: method, Status: resp.StatusCode, Message: string(body)}
+	}
+
+	return data.Get("result"), nil
+}
+
+// evmRpcError 节点返回的 JSON-RPC 错误或非 200 响应
+type evmRpcError struct {
+	Network string
+	Method  string
+	Status  int   // HTTP 状态码
+	Code    int64 // JSON-RPC 错误码
+	Message string
+}
+
+func (e *evmRpcError) Error() string {
+	if e.Code == 0 && e.Status != http.StatusOK {
+
+		return fmt.Sprintf("%s %s response status %d %s", e.Network, e.Method, e.Status, e.Message)
+	}
+
+	return fmt.Sprintf("%s %s response error %s", e.Network, e.Method, e.Message)
+}
+
 func (e *evm) rpcEndpoint() string {
 
 	return model.Endpoint(model.Network(e.Network))
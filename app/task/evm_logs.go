@@ -0,0 +1,293 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+const (
+	evmLogTopicLimit = 100    // 单次 eth_getLogs 请求携带的地址 topic 数量上限
+	evmLogLimitCode  = -32005 // 查询结果超出节点限制的错误码
+	evmLogRetryMax   = 3      // 限流时的最大重试次数
+	evmLogBackoff    = time.Second
+)
+
+// evmLogSpan eth_getLogs 单次请求的区块跨度，节点返回范围过大时收缩，成功后逐步恢复
+type evmLogSpan struct {
+	mu   sync.Mutex
+	size int64
+	succ int
+}
+
+var evmLogSpans sync.Map
+
+func getEvmLogSpan(network string) *evmLogSpan {
+	v, _ := evmLogSpans.LoadOrStore(network, &evmLogSpan{size: blockParseMaxNum})
+
+	return v.(*evmLogSpan)
+}
+
+func (s *evmLogSpan) get() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// shrink 跨度减半，已经是最小跨度时返回 false
+func (s *evmLogSpan) shrink() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.succ = 0
+	if s.size <= 1 {
+
+		return false
+	}
+
+	s.size /= 2
+
+	return true
+}
+
+// grow 连续成功若干次后跨度翻倍，最大不超过 blockParseMaxNum
+func (s *evmLogSpan) grow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size >= blockParseMaxNum {
+
+		return
+	}
+
+	s.succ++
+	if s.succ >= 10 {
+		s.succ = 0
+		s.size = min(s.size*2, blockParseMaxNum)
+	}
+}
+
+// isLogRangeError 节点对 eth_getLogs 查询范围或结果数量的限制错误，需要收缩跨度；限流错误不在此列
+func isLogRangeError(err error) bool {
+	if isRateLimitError(err) {
+
+		return false
+	}
+
+	var re *evmRpcError
+	if errors.As(err, &re) && re.Code == evmLogLimitCode {
+
+		return true
+	}
+
+	var msg = strings.ToLower(err.Error())
+	for _, k := range []string{"block range", "blocks range", "range too large", "range is too large", "query returned more than", "response size exceeded"} {
+		if strings.Contains(msg, k) {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRateLimitError 节点限流，应等待后重试而不是收缩跨度
+func isRateLimitError(err error) bool {
+	var re *evmRpcError
+	if errors.As(err, &re) && re.Status == http.StatusTooManyRequests {
+
+		return true
+	}
+
+	var msg = strings.ToLower(err.Error())
+	for _, k := range []string{"rate limit", "too many requests", "request limit", "exceeded the quota"} {
+		if strings.Contains(msg, k) {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// logFilterEnabled 是否按钱包地址过滤日志；MQTT 订阅的网络需要全量转账数据，不做过滤
+func (e *evm) logFilterEnabled() bool {
+	if mqttSubscribed(e.Network) {
+
+		return false
+	}
+
+	for _, n := range strings.Split(model.GetC(model.EvmLogFilterNetworks), ",") {
+		if strings.TrimSpace(n) == e.Network {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// getTransferLogs 获取区块范围内的 Transfer 事件日志，按当前跨度分段请求
+func (e *evm) getTransferLogs(ctx context.Context, b evmBlock) ([]gjson.Result, error) {
+	var filters = []string{fmt.Sprintf(`{"topics":["%s"]}`, evmTransferEvent)}
+	if e.logFilterEnabled() {
+		filters = e.watchedLogFilters()
+	}
+
+	var span = getEvmLogSpan(e.Network)
+	var logs = make([]gjson.Result, 0)
+	var seen = make(map[string]struct{})
+	var retry = 0
+	for from := b.From; from <= b.To; {
+		to := min(from+span.get()-1, b.To)
+
+		var items = make([]gjson.Result, 0)
+		var err error
+		for _, f := range filters {
+			var res []gjson.Result
+			res, err = e.getLogs(ctx, from, to, f)
+			if err != nil {
+
+				break
+			}
+
+			items = append(items, res...)
+		}
+
+		if err != nil {
+			if isRateLimitError(err) && retry < evmLogRetryMax {
+				var wait = evmLogBackoff << retry
+				retry++
+				log.Task.Warn(fmt.Sprintf("eth_getLogs 节点限流(%s)，%s 后重试", e.Network, wait))
+
+				select {
+				case <-ctx.Done():
+
+					return nil, ctx.Err()
+				case <-time.After(wait):
+				}
+
+				continue
+			}
+			if isLogRangeError(err) && span.shrink() {
+				log.Task.Warn(fmt.Sprintf("eth_getLogs 查询范围受限(%s)，跨度调整为 %d", e.Network, span.get()))
+
+				continue
+			}
+
+			return nil, err
+		}
+
+		retry = 0
+
+		for _, itm := range items {
+			key := itm.Get("transactionHash").String() + itm.Get("logIndex").String()
+			if _, ok := seen[key]; ok {
+
+				continue
+			}
+
+			seen[key] = struct{}{}
+			logs = append(logs, itm)
+		}
+
+		span.grow()
+		from = to + 1
+	}
+
+	return logs, nil
+}
+
+func (e *evm) getLogs(ctx context.Context, from, to int64, filter string) ([]gjson.Result, error) {
+	var params = fmt.Sprintf(`[%s]`, strings.Replace(filter, "{", fmt.Sprintf(`{"fromBlock":"0x%x","toBlock":"0x%x",`, from, to), 1))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	res, err := e.rpcCall(ctx, "eth_getLogs", params)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return res.Array(), nil
+}
+
+// watchedLogFilters 按合约地址和关注的收付款地址构建日志过滤条件
+func (e *evm) watchedLogFilters() []string {
+	var contracts = model.GetNetworkContracts(model.Network(e.Network))
+	var recv, from = e.watchedAddresses()
+	var filters = make([]string, 0)
+
+	var build = func(addrs []string, index int) {
+		for i := 0; i < len(addrs); i += evmLogTopicLimit {
+			topics := []any{evmTransferEvent, nil, nil}
+			topics[index] = addrs[i:min(i+evmLogTopicLimit, len(addrs))]
+
+			data, _ := json.Marshal(map[string]any{"address": contracts, "topics": topics})
+			filters = append(filters, string(data))
+		}
+	}
+
+	build(recv, 2)
+	build(from, 1)
+
+	return filters
+}
+
+// watchedAddresses 返回需要关注的收款地址和付款地址 topic（32 字节补齐）
+func (e *evm) watchedAddresses() (recv []string, from []string) {
+	var trades = model.GetNetworkTrades(model.Network(e.Network))
+	var recvSet = make(map[string]struct{})
+	var fromSet = make(map[string]struct{})
+
+	var wallets []model.Wallet
+	model.Db.Where("trade_type in (?) and (status = ? or other_notify = ?)", trades, model.WaStatusEnable, model.WaOtherEnable).Find(&wallets)
+	for _, wa := range wallets {
+		recvSet[wa.MatchAddr] = struct{}{}
+		if wa.OtherNotify == model.WaOtherEnable {
+			fromSet[wa.MatchAddr] = struct{}{}
+		}
+	}
+
+	var orders []model.Order
+	model.Db.Where("status in (?) and trade_type in (?)", receivableOrderStatuses(), trades).
+		Where("expired_at > ?", time.Now().Add(model.GetLookbackHour())).
+		Find(&orders)
+	for _, o := range orders {
+		recvSet[orderMatchAddress(o)] = struct{}{}
+	}
+	for _, addr := range model.GetRecentXpubAddresses(trades) {
+		recvSet[addr] = struct{}{}
+	}
+
+	var topic = func(set map[string]struct{}) []string {
+		var list = make([]string, 0, len(set))
+		for addr := range set {
+			addr = strings.ToLower(strings.TrimPrefix(addr, "0x"))
+			if len(addr) != 40 {
+
+				continue
+			}
+
+			list = append(list, "0x000000000000000000000000"+addr)
+		}
+
+		sort.Strings(list) // 顺序固定，便于 WebSocket 判断过滤条件是否变化
+
+		return list
+	}
+
+	return topic(recvSet), topic(fromSet)
+}
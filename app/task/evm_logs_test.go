@@ -0,0 +1,32 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestLogErrorClassify(t *testing.T) {
+	var cases = []struct {
+		err   error
+		rng   bool
+		limit bool
+	}{
+		{&evmRpcError{Code: -32005, Message: `{"code":-32005,"message":"query returned more than 10000 results"}`}, true, false},
+		{&evmRpcError{Code: -32000, Message: `{"code":-32000,"message":"block range is too large"}`}, true, false},
+		{&evmRpcError{Code: -32600, Message: `{"code":-32600,"message":"eth_getLogs is limited to a 10,000 blocks range"}`}, true, false},
+		{&evmRpcError{Status: http.StatusTooManyRequests, Message: "Too Many Requests"}, false, true},
+		{&evmRpcError{Code: -32005, Message: `{"code":-32005,"message":"rate limit exceeded"}`}, false, true},
+		{&evmRpcError{Code: -32000, Message: `{"code":-32000,"message":"too many requests, please slow down"}`}, false, true},
+		{errors.New("context deadline exceeded"), false, false},
+	}
+
+	for _, c := range cases {
+		if got := isLogRangeError(c.err); got != c.rng {
+			t.Errorf("isLogRangeError(%v) = %t, want %t", c.err, got, c.rng)
+		}
+		if got := isRateLimitError(c.err); got != c.limit {
+			t.Errorf("isRateLimitError(%v) = %t, want %t", c.err, got, c.limit)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/notifier"
+	"github.com/v03413/bepusdt/app/utils"
+)
+
+const evmReorgDepth = 128 // 保留最近区块哈希的数量，同时也是区块重组回溯的最大深度
+
+type evmBlockHash struct {
+	Hash   string
+	Parent string
+}
+
+// evmHashes 最近区块哈希记录，用于检测区块重组
+type evmHashes struct {
+	mu     sync.Mutex
+	reorg  sync.Mutex // 重组处理串行执行
+	items  map[int64]evmBlockHash
+	latest int64
+	retry  int64 // 重组处理失败时待重新处理的高度
+}
+
+var evmBlockHashes sync.Map
+
+func getEvmHashes(network string) *evmHashes {
+	v, _ := evmBlockHashes.LoadOrStore(network, &evmHashes{items: make(map[int64]evmBlockHash)})
+
+	return v.(*evmHashes)
+}
+
+// record 记录区块哈希并检查前后区块的父哈希是否连续，返回检测到重组的最低高度，未检测到时返回 0
+func (h *evmHashes) record(blocks map[int64]evmBlockHash) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var height int64
+	var mark = func(n int64) {
+		if height == 0 || n < height {
+			height = n
+		}
+	}
+
+	if h.retry > 0 {
+		mark(h.retry)
+		h.retry = 0
+	}
+
+	for num, b := range blocks {
+		if b.Hash == "" {
+
+			continue
+		}
+
+		if old, ok := h.items[num]; ok && old.Hash != b.Hash {
+			mark(num)
+		}
+		if prev, ok := h.items[num-1]; ok && prev.Hash != b.Parent {
+			mark(num - 1)
+		}
+		if next, ok := h.items[num+1]; ok && next.Parent != b.Hash {
+			mark(num)
+		}
+
+		h.items[num] = b
+		if num > h.latest {
+			h.latest = num
+		}
+	}
+
+	for num := range h.items {
+		if num <= h.latest-evmReorgDepth {
+			delete(h.items, num)
+		}
+	}
+
+	return height
+}
+
+func (h *evmHashes) get(num int64) (evmBlockHash, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b, ok := h.items[num]
+
+	return b, ok
+}
+
+// truncate 清除高于 num 的记录（重扫时重新写入），返回清除前的最新高度
+func (h *evmHashes) truncate(num int64) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var latest = h.latest
+	for n := range h.items {
+		if n > num {
+			delete(h.items, n)
+		}
+	}
+
+	return latest
+}
+
+// retryAt 重组处理未完成时记录高度，下次记录区块哈希时重新触发
+func (h *evmHashes) retryAt(num int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.retry == 0 || num < h.retry {
+		h.retry = num
+	}
+}
+
+func (h *evmHashes) set(num int64, b evmBlockHash) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.items[num] = b
+}
+
+// reorgHandle 区块重组处理：定位分叉点，重扫受影响区块，移除已不在链上的收款并回退相关订单；
+// 任一步骤失败时保留原有哈希记录并等待下次重试，避免重组证据丢失
+func (e *evm) reorgHandle(height int64) {
+	var hashes = getEvmHashes(e.Network)
+
+	hashes.reorg.Lock()
+	defer hashes.reorg.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var start = height
+	var canonical = make(map[int64]evmBlockHash)
+	for n := height; n > height-evmReorgDepth && n > 0; n-- {
+		res, err := e.rpcCall(ctx, "eth_getBlockByNumber", fmt.Sprintf(`["0x%x",false]`, n))
+		if err != nil || !res.IsObject() {
+			log.Task.Warn(fmt.Sprintf("区块重组检查失败(%s) %d：%v", e.Network, n, err))
+			hashes.retryAt(height)
+
+			return
+		}
+
+		b := evmBlockHash{Hash: res.Get("hash").String(), Parent: res.Get("parentHash").String()}
+		stored, ok := hashes.get(n)
+		if ok && stored.Hash == b.Hash && n < height {
+			// 找到分叉点
+
+			break
+		}
+
+		start = n
+		canonical[n] = b
+		if !ok {
+			// 更早的区块没有记录，无法继续比对
+
+			break
+		}
+	}
+
+	var end = hashes.truncate(height)
+	for n, b := range canonical {
+		hashes.set(n, b)
+	}
+	if end < height {
+		end = height
+	}
+
+	log.Task.Warn(fmt.Sprintf("检测到区块重组(%s)：%d → %d，重新扫描受影响区块", e.Network, start, end))
+
+	for from := start; from <= end; from += blockParseMaxNum {
+		to := from + blockParseMaxNum - 1
+		if to > end {
+			to = end
+		}
+
+		e.blockScanQueue.In <- evmBlock{From: from, To: to}
+	}
+
+	if !e.reorgRollback(ctx, start, end) {
+		hashes.retryAt(start)
+	}
+}
+
+// reorgRollback 检查受影响区块内的全部收款交易（包括累计支付的部分付款），移除已不在链上的收款记录；
+// 确认中订单的关联交易消失或累计实收不再达标时回退为等待支付。存在查询失败的交易时返回 false
+func (e *evm) reorgRollback(ctx context.Context, start, end int64) bool {
+	var orders []model.Order
+	model.Db.Where("status in ? and trade_type in ?", []int{model.OrderStatusWaiting, model.OrderStatusConfirming}, model.GetNetworkTrades(model.Network(e.Network))).
+		Where("ref_block_num >= ? or trade_id in (?)", start, model.Db.Model(&model.OrderPayment{}).Select("trade_id").Where("block_num >= ?", start)).
+		Find(&orders)
+
+	var complete = true
+	var blockNums = make(map[string]int64) // 交易哈希 → 当前所在区块，0 表示已不在链上
+	var lookup = func(o model.Order, hash string) (int64, bool) {
+		if num, ok := blockNums[hash]; ok {
+
+			return num, true
+		}
+
+		res, err := e.rpcCall(ctx, "eth_getTransactionReceipt", fmt.Sprintf(`["%s"]`, hash))
+		if err != nil {
+			log.Task.Warn(fmt.Sprintf("区块重组订单检查失败(%s) %s %s：%v", e.Network, o.TradeId, hash, err))
+			complete = false
+
+			return 0, false
+		}
+
+		var num int64
+		if res.IsObject() {
+			num = utils.HexStr2Int(res.Get("blockNumber").String()).Int64()
+		}
+
+		blockNums[hash] = num
+
+		return num, true
+	}
+
+	for _, o := range orders {
+		var removed = 0
+		for _, p := range o.GetPayments() {
+			if int64(p.BlockNum) < start {
+
+				continue
+			}
+			if num, ok := lookup(o, p.TxHash); !ok || num > 0 {
+
+				continue
+			}
+			if err := o.RemovePayment(p.TxHash); err != nil {
+				log.Task.Warn(fmt.Sprintf("收款记录移除失败 %s %s：%v", o.TradeId, p.TxHash, err))
+				complete = false
+
+				continue
+			}
+
+			removed++
+			log.Task.Warn(fmt.Sprintf("区块重组(%s %d → %d)，移除订单 %s 已不在链上的收款 %s %s", e.Network, start, end, o.TradeId, p.TxHash, p.Amount))
+		}
+
+		if o.Status != model.OrderStatusConfirming {
+
+			continue
+		}
+
+		var hash = o.RefHash
+		num, ok := lookup(o, hash)
+		if !ok {
+
+			continue
+		}
+
+		var reason string
+		switch {
+		case num == 0:
+			reason = fmt.Sprintf("区块重组(%s %d → %d)，交易 %s 已不在链上", e.Network, start, end, hash)
+		case removed > 0 && !paymentEnough(o):
+			reason = fmt.Sprintf("区块重组(%s %d → %d)，部分收款已不在链上，累计实收 %s 不足", e.Network, start, end, o.PaidAmount)
+		default:
+			// 交易被重新打包，仅更新区块高度
+			if int(num) != o.RefBlockNum {
+				model.Db.Model(&o).Update("ref_block_num", num)
+			}
+
+			continue
+		}
+
+		if err := o.RollbackWaiting(reason); err != nil {
+			log.Task.Warn(fmt.Sprintf("订单回退失败 %s：%v", o.TradeId, err))
+			complete = false
+
+			continue
+		}
+		if num == 0 {
+			if err := o.RemovePayment(hash); err != nil {
+				log.Task.Warn(fmt.Sprintf("收款记录移除失败 %s %s：%v", o.TradeId, hash, err))
+			}
+		}
+
+		log.Task.Warn(fmt.Sprintf("订单回退为等待支付 %s：%s", o.TradeId, reason))
+		notifier.OrderRollback(o, reason)
+	}
+
+	return complete
+}
+
+// paymentEnough 累计实收是否仍达到订单数额（含误差下限）
+func paymentEnough(o model.Order) bool {
+	paid, err := decimal.NewFromString(o.PaidAmount)
+	if err != nil {
+
+		return false
+	}
+
+	target, err := decimal.NewFromString(o.Amount)
+	if err != nil {
+
+		return false
+	}
+
+	return !paid.LessThan(model.ToleranceFloor(o.TradeType, target))
+}
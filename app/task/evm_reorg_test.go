@@ -0,0 +1,203 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smallnest/chanx"
+	"github.com/tidwall/gjson"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+func TestEvmHashesRecord(t *testing.T) {
+	var chain = func(from, to int64, tag string) map[int64]evmBlockHash {
+		var blocks = make(map[int64]evmBlockHash)
+		for n := from; n <= to; n++ {
+			blocks[n] = evmBlockHash{Hash: tag + string(rune('a'+n%26)), Parent: tag + string(rune('a'+(n-1)%26))}
+		}
+
+		return blocks
+	}
+
+	var cases = []struct {
+		name   string
+		before map[int64]evmBlockHash
+		blocks map[int64]evmBlockHash
+		want   int64
+	}{
+		{"连续区块", chain(1, 10, "x"), chain(11, 12, "x"), 0},
+		{"同高度哈希变化", chain(1, 10, "x"), map[int64]evmBlockHash{10: {Hash: "y", Parent: "xj"}}, 10},
+		{"父哈希不一致", chain(1, 10, "x"), map[int64]evmBlockHash{11: {Hash: "yl", Parent: "yk"}}, 10},
+		{"后一区块的父哈希不一致", chain(1, 10, "x"), map[int64]evmBlockHash{9: {Hash: "yj", Parent: "xi"}}, 9},
+		{"区块间存在空缺不比对", chain(1, 10, "x"), map[int64]evmBlockHash{20: {Hash: "yu", Parent: "yt"}}, 0},
+		{"空哈希忽略", chain(1, 10, "x"), map[int64]evmBlockHash{10: {}}, 0},
+	}
+
+	for _, c := range cases {
+		h := &evmHashes{items: make(map[int64]evmBlockHash)}
+		h.record(c.before)
+		if got := h.record(c.blocks); got != c.want {
+			t.Errorf("%s: record = %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	// 超出保留深度的记录被清除
+	h := &evmHashes{items: make(map[int64]evmBlockHash)}
+	h.record(chain(1, evmReorgDepth+10, "x"))
+	if _, ok := h.get(10); ok {
+		t.Fatal("block beyond depth not pruned")
+	}
+	if _, ok := h.get(11); !ok {
+		t.Fatal("block within depth pruned")
+	}
+
+	// 截断高于指定高度的记录，返回截断前的最新高度
+	if latest := h.truncate(100); latest != evmReorgDepth+10 {
+		t.Fatalf("truncate = %d, want %d", latest, evmReorgDepth+10)
+	}
+	if _, ok := h.get(101); ok {
+		t.Fatal("block above truncate height kept")
+	}
+	if _, ok := h.get(100); !ok {
+		t.Fatal("block at truncate height removed")
+	}
+
+	// 重组处理失败后，下次记录时重新触发
+	h.retryAt(90)
+	h.retryAt(95)
+	if got := h.record(nil); got != 90 {
+		t.Fatalf("retry = %d, want 90", got)
+	}
+	if got := h.record(nil); got != 0 {
+		t.Fatalf("retry not cleared: %d", got)
+	}
+}
+
+func TestEvmReorg(t *testing.T) {
+	db := setupTestDb(t, &model.Conf{}, &model.Order{}, &model.OrderEvent{}, &model.OrderPayment{}, &model.NotifyOutbox{})
+
+	// 0xgone 开头的交易已被重组移除，区块 0x64 之后的区块哈希已变化
+	var fail = false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := gjson.ParseBytes(body)
+		if fail {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"unavailable"}}`))
+
+			return
+		}
+
+		switch req.Get("method").String() {
+		case "eth_getTransactionReceipt":
+			if hash := req.Get("params.0").String(); len(hash) >= 6 && hash[:6] == "0xgone" {
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"status":"0x1","blockNumber":"0x66"}}`))
+		case "eth_getBlockByNumber":
+			num := req.Get("params.0").String()
+			hash := "0xnew" + num
+			if num <= "0x63" {
+				hash = "0xold" + num
+			}
+
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"hash":"` + hash + `","parentHash":"0xp"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	model.SetK(model.RpcEndpointBsc, srv.URL)
+	model.SetK(model.PaymentAccumulate, "1")
+	model.RefreshC()
+	t.Cleanup(func() {
+		model.SetK(model.RpcEndpointBsc, "")
+		model.SetK(model.PaymentAccumulate, "0")
+		model.RefreshC()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := &evm{Network: conf.Bsc, Client: srv.Client(), blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 8)}
+
+	zero := time.Unix(0, 0)
+	var newOrder = func(id, amount string, status int, ref string, refBlock int) model.Order {
+		o := model.Order{TradeId: id, RefHash: ref, RefBlockNum: refBlock, TradeType: model.UsdtBep20, Amount: amount, Status: status, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+		db.Create(&o)
+
+		return o
+	}
+	var addPayment = func(o model.Order, hash, amount string, block int) {
+		db.Create(&model.OrderPayment{TradeId: o.TradeId, TxHash: hash, Amount: amount, BlockNum: block, PaidAt: time.Now()})
+	}
+
+	// 累计支付：较早的部分付款被重组移除，最后一笔仍在链上
+	partial := newOrder("o1", "10", model.OrderStatusConfirming, "0xok1", 102)
+	addPayment(partial, "0xgone1", "4", 101)
+	addPayment(partial, "0xok1", "6", 102)
+
+	// 关联交易被重组移除
+	gone := newOrder("o2", "10", model.OrderStatusConfirming, "0xgone2", 103)
+	addPayment(gone, "0xgone2", "10", 103)
+
+	// 等待支付订单的部分付款被移除
+	waiting := newOrder("o3", "10", model.OrderStatusWaiting, "o3", 0)
+	addPayment(waiting, "0xgone3", "3", 104)
+
+	// 交易被重新打包到其它区块
+	repacked := newOrder("o4", "10", model.OrderStatusConfirming, "0xok4", 105)
+
+	// 获取区块失败时保留哈希记录，等待下次重试
+	hashes := getEvmHashes(e.Network)
+	t.Cleanup(func() { evmBlockHashes.Delete(e.Network) })
+	for n := int64(99); n <= 105; n++ {
+		hashes.set(n, evmBlockHash{Hash: fmt.Sprintf("0xold0x%x", n)})
+	}
+	hashes.latest = 105
+
+	fail = true
+	e.reorgHandle(101)
+	if _, ok := hashes.get(105); !ok {
+		t.Fatal("hash history truncated although the walk failed")
+	}
+	if got := hashes.record(nil); got != 101 {
+		t.Fatalf("retry height = %d, want 101", got)
+	}
+
+	fail = false
+	e.reorgHandle(101)
+	if b, _ := hashes.get(101); b.Hash != "0xnew0x65" {
+		t.Fatalf("canonical hash not stored: %+v", b)
+	}
+	if b := <-e.blockScanQueue.Out; b.From != 100 || b.To != 105 {
+		t.Fatalf("rescan = %+v, want 100 → 105", b)
+	}
+
+	var reload = func(o model.Order) model.Order {
+		var saved model.Order
+		db.Where("trade_id = ?", o.TradeId).First(&saved)
+
+		return saved
+	}
+
+	if o := reload(partial); o.Status != model.OrderStatusWaiting || o.PaidAmount != "6" || len(o.GetPayments()) != 1 {
+		t.Fatalf("partial: status=%d paid=%s payments=%d", o.Status, o.PaidAmount, len(o.GetPayments()))
+	}
+	if o := reload(gone); o.Status != model.OrderStatusWaiting || o.PaidAmount != "0" || len(o.GetPayments()) != 0 {
+		t.Fatalf("gone: status=%d paid=%s payments=%d", o.Status, o.PaidAmount, len(o.GetPayments()))
+	}
+	if o := reload(waiting); o.Status != model.OrderStatusWaiting || o.PaidAmount != "0" {
+		t.Fatalf("waiting: status=%d paid=%s", o.Status, o.PaidAmount)
+	}
+	if o := reload(repacked); o.Status != model.OrderStatusConfirming || o.RefBlockNum != 0x66 {
+		t.Fatalf("repacked: status=%d block=%d", o.Status, o.RefBlockNum)
+	}
+}
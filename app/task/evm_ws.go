@@ -0,0 +1,288 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+	"github.com/v03413/bepusdt/app/log"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/utils"
+	"github.com/v03413/go-cache"
+)
+
+const (
+	evmWsReadTimeout = time.Second * 60 // 超过此时长未收到任何消息视为连接断开
+	evmWsResubscribe = time.Second * 30 // 关注地址变化检查间隔
+	evmWsSubHeads    = 1
+	evmWsSubLogs     = 2
+)
+
+// evmWsSubs WebSocket 连接上的订阅状态，日志订阅按关注地址过滤，地址变化时重新订阅
+type evmWsSubs struct {
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	id      int
+	heads   string              // newHeads 订阅ID
+	sign    string              // 当前日志过滤条件签名
+	pending map[int]struct{}    // 待确认的日志订阅请求ID
+	logs    map[string]struct{} // 生效中的日志订阅ID
+}
+
+func newEvmWsSubs(conn *websocket.Conn) *evmWsSubs {
+
+	return &evmWsSubs{
+		conn:    conn,
+		id:      evmWsSubHeads,
+		pending: make(map[int]struct{}),
+		logs:    make(map[string]struct{}),
+	}
+}
+
+func (s *evmWsSubs) write(id int, method string, params []any) error {
+	data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *evmWsSubs) subscribeHeads() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(evmWsSubHeads, "eth_subscribe", []any{"newHeads"})
+}
+
+// subscribeLogs 过滤条件变化时先订阅新条件再取消旧订阅，切换期间的重复推送不影响区块扫描
+func (s *evmWsSubs) subscribeLogs(filters []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sign = strings.Join(filters, "|")
+	if sign == s.sign {
+
+		return nil
+	}
+
+	var old = s.logs
+	s.logs = make(map[string]struct{})
+	s.pending = make(map[int]struct{})
+	for _, f := range filters {
+		s.id++
+		s.pending[s.id] = struct{}{}
+		if err := s.write(s.id, "eth_subscribe", []any{"logs", json.RawMessage(f)}); err != nil {
+
+			return err
+		}
+	}
+	for sub := range old {
+		s.id++
+		if err := s.write(s.id, "eth_unsubscribe", []any{sub}); err != nil {
+
+			return err
+		}
+	}
+
+	s.sign = sign
+
+	return nil
+}
+
+// confirm 处理请求响应，返回订阅是否全部生效；订阅请求失败时返回错误，取消订阅失败忽略
+func (s *evmWsSubs) confirm(data gjson.Result) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var id = int(data.Get("id").Int())
+	var _, pending = s.pending[id]
+	if data.Get("error").Exists() {
+		if id == evmWsSubHeads || pending {
+
+			return false, fmt.Errorf("%s", data.Get("error").String())
+		}
+
+		return s.heads != "" && len(s.pending) == 0, nil
+	}
+
+	if id == evmWsSubHeads {
+		s.heads = data.Get("result").String()
+	} else if pending {
+		delete(s.pending, id)
+		s.logs[data.Get("result").String()] = struct{}{}
+	}
+
+	return s.heads != "" && len(s.pending) == 0, nil
+}
+
+func (s *evmWsSubs) kind(sub string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub == s.heads {
+
+		return evmWsSubHeads
+	}
+	if _, ok := s.logs[sub]; ok {
+
+		return evmWsSubLogs
+	}
+
+	return 0
+}
+
+// wsWatch WebSocket 订阅 newHeads 与关注地址的 Transfer 日志；连接期间阻塞，断开后由下次调度重连，期间回退为轮询
+func (e *evm) wsWatch(ctx context.Context) {
+	var url = model.WsEndpoint(model.Network(e.Network))
+	if url == "" {
+
+		return
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(dialCtx, url, nil)
+	if err != nil {
+		log.Task.Warn(fmt.Sprintf("WebSocket 连接失败(%s)：%s", e.Network, err.Error()))
+
+		return
+	}
+
+	defer conn.Close()
+	defer e.wsAlive.Store(false)
+
+	var subs = newEvmWsSubs(conn)
+	if err = subs.subscribeHeads(); err == nil {
+		err = subs.subscribeLogs(e.wsLogFilters())
+	}
+	if err != nil {
+		log.Task.Warn(fmt.Sprintf("WebSocket 订阅失败(%s)：%s", e.Network, err.Error()))
+
+		return
+	}
+
+	var done = make(chan struct{})
+	defer close(done)
+	go func() {
+		var ticker = time.NewTicker(evmWsResubscribe)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				_ = conn.Close()
+
+				return
+			case <-done:
+
+				return
+			case <-ticker.C:
+				if err := subs.subscribeLogs(e.wsLogFilters()); err != nil {
+					log.Task.Warn(fmt.Sprintf("WebSocket 重新订阅失败(%s)：%s", e.Network, err.Error()))
+					_ = conn.Close()
+
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(evmWsReadTimeout))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if e.wsAlive.Load() {
+				log.Task.Warn(fmt.Sprintf("WebSocket 连接断开(%s)，回退为轮询扫描：%s", e.Network, err.Error()))
+			}
+
+			return
+		}
+
+		var data = gjson.ParseBytes(msg)
+		if data.Get("id").Int() != 0 {
+			alive, err := subs.confirm(data)
+			if err != nil {
+				log.Task.Warn(fmt.Sprintf("WebSocket 订阅失败(%s)：%s", e.Network, err.Error()))
+
+				return
+			}
+			if alive && !e.wsAlive.Load() {
+				e.wsAlive.Store(true)
+				log.Task.Info(fmt.Sprintf("WebSocket 订阅成功(%s)，暂停轮询扫描", e.Network))
+			}
+
+			continue
+		}
+
+		var params = data.Get("params")
+		switch subs.kind(params.Get("subscription").String()) {
+		case evmWsSubHeads:
+			e.wsHeadHandle(params.Get("result"))
+		case evmWsSubLogs:
+			e.wsLogHandle(params.Get("result"))
+		}
+	}
+}
+
+// wsLogFilters 与 eth_getLogs 过滤模式共用关注地址条件；MQTT 订阅的网络需要全量转账数据，不做过滤
+func (e *evm) wsLogFilters() []string {
+	if mqttSubscribed(e.Network) {
+		data, _ := json.Marshal(map[string]any{
+			"address": model.GetNetworkContracts(model.Network(e.Network)),
+			"topics":  []string{evmTransferEvent},
+		})
+
+		return []string{string(data)}
+	}
+
+	return e.watchedLogFilters()
+}
+
+func (e *evm) wsHeadHandle(head gjson.Result) {
+	if syncBreak(e.Network, e.blockScanQueue.Len()) {
+
+		return
+	}
+
+	e.enqueueForward(utils.HexStr2Int(head.Get("number").String()).Int64() - e.Block.RollDelayOffset)
+}
+
+// wsLogHandle 订阅推送的日志只用于推进区块扫描：转账统一由区块扫描解析，记录区块哈希并遵循 RollDelayOffset，
+// 避免未经重组检测的链头日志直接参与订单匹配；被重组移除的日志立即触发重组处理
+func (e *evm) wsLogHandle(itm gjson.Result) {
+	var num = utils.HexStr2Int(itm.Get("blockNumber").String()).Int64()
+	if num <= 0 {
+
+		return
+	}
+
+	if itm.Get("removed").Bool() {
+		var key = fmt.Sprintf("evm_ws_removed_%s_%s", e.Network, itm.Get("blockHash").String())
+		if _, ok := cache.Get(key); ok { // 同一区块的多条日志只处理一次
+
+			return
+		}
+
+		cache.Set(key, true, time.Hour)
+		go e.reorgHandle(num)
+
+		return
+	}
+
+	// 已推进到的高度由 newHeads 负责扫描，旧区块的日志不能回退扫描高度
+	var now = num - e.Block.RollDelayOffset
+	if last, ok := chainBlockNum.Load(e.Network); ok && now <= last.(int64) {
+
+		return
+	}
+	if syncBreak(e.Network, e.blockScanQueue.Len()) {
+
+		return
+	}
+
+	e.enqueueForward(now)
+}
@@ -0,0 +1,112 @@
+package task
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/chanx"
+	"github.com/tidwall/gjson"
+	"github.com/v03413/go-cache"
+)
+
+func TestEvmWsSubsResubscribe(t *testing.T) {
+	var recv = make(chan string, 16)
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			recv <- string(msg)
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	var subs = newEvmWsSubs(conn)
+	var next = func() gjson.Result { return gjson.Parse(<-recv) }
+
+	_ = subs.subscribeHeads()
+	_ = subs.subscribeLogs([]string{`{"topics":["a"]}`})
+	next()
+	var req = next()
+	if req.Get("params.1.topics.0").String() != "a" {
+		t.Fatalf("logs filter = %s", req.Raw)
+	}
+
+	alive, _ := subs.confirm(gjson.Parse(`{"id":1,"result":"0xh"}`))
+	if alive {
+		t.Fatal("alive before logs subscription confirmed")
+	}
+	alive, _ = subs.confirm(gjson.Parse(`{"id":` + req.Get("id").Raw + `,"result":"0xa"}`))
+	if !alive || subs.kind("0xa") != evmWsSubLogs || subs.kind("0xh") != evmWsSubHeads {
+		t.Fatal("subscriptions not active after confirm")
+	}
+
+	// 过滤条件不变时不重复订阅
+	_ = subs.subscribeLogs([]string{`{"topics":["a"]}`})
+	_ = subs.subscribeLogs([]string{`{"topics":["b"]}`})
+	if req = next(); req.Get("method").String() != "eth_subscribe" || req.Get("params.1.topics.0").String() != "b" {
+		t.Fatalf("want subscribe b, got %s", req.Raw)
+	}
+	if un := next(); un.Get("method").String() != "eth_unsubscribe" || un.Get("params.0").String() != "0xa" {
+		t.Fatalf("want unsubscribe 0xa, got %s", un.Raw)
+	}
+	if subs.kind("0xa") != 0 {
+		t.Fatal("old subscription still routed")
+	}
+}
+
+func TestEvmWsLogHandle(t *testing.T) {
+	var network = "test-ws-log"
+	cache.Set("mqtt_subscribed_"+network, true, time.Minute)
+	chainBlockNum.Store(network, int64(99))
+	t.Cleanup(func() {
+		cache.Delete("mqtt_subscribed_" + network)
+		chainBlockNum.Delete(network)
+		cursors.Delete(network)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := &evm{Network: network, Block: block{RollDelayOffset: 3}, blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 8)}
+
+	// 未达到延迟偏移的链头日志不直接入队
+	e.wsLogHandle(gjson.Parse(`{"blockNumber":"0x64","transactionHash":"0xt","logIndex":"0x0"}`))
+	if n := e.blockScanQueue.Len(); n != 0 {
+		t.Fatalf("queued %d blocks before offset reached", n)
+	}
+	if last, _ := chainBlockNum.Load(network); last.(int64) != 99 {
+		t.Fatalf("scan height moved back to %d", last)
+	}
+
+	// 达到偏移后推进区块扫描，由扫描解析转账并记录区块哈希
+	e.wsLogHandle(gjson.Parse(`{"blockNumber":"0x67","transactionHash":"0xt","logIndex":"0x0"}`))
+	select {
+	case b := <-e.blockScanQueue.Out:
+		if b.From != 100 || b.To != 100 {
+			t.Fatalf("block = %+v, want 100 → 100", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block not queued")
+	}
+}
@@ -0,0 +1,26 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallnest/chanx"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/utils"
+)
+
+func litecoinInit() {
+	ltc := &utxo{
+		Network:         conf.Litecoin,
+		TradeType:       model.LitecoinLtc,
+		ConfirmedOffset: 6,
+		Client:          utils.NewHttpClient(),
+		blockQueue:      chanx.NewUnboundedChan[int](context.Background(), 30),
+	}
+
+	Register(Task{Callback: ltc.blockDispatch})
+	Register(Task{Callback: ltc.syncBlocksForward, Duration: time.Second * 15})
+	Register(Task{Callback: ltc.tradeConfirmHandle, Duration: time.Second * 15})
+	Register(Task{Callback: ltc.lookbackBlocks, Duration: time.Minute})
+}
@@ -2,45 +2,114 @@ package task
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/notifier"
 	"github.com/v03413/bepusdt/app/task/notify"
-	"github.com/v03413/bepusdt/app/utils"
 )
 
 func init() {
-	Register(Task{Duration: time.Second * 3, Callback: notifyRetry})
-	Register(Task{Duration: time.Second * 30, Callback: notifyRoll})
+	Register(Task{Callback: notifyBackfill})
+	Register(Task{Duration: time.Second * 3, Callback: notifyDispatch})
 }
 
-// notifyRetry 回调失败重试
-func notifyRetry(context.Context) {
-	tradeOrders, err := model.GetNotifyFailedTradeOrders()
-	if err != nil {
-		log.Task.Error("待回调订单获取失败", err)
+// notifyBackfill 升级前回调失败的订单补写到发件箱
+func notifyBackfill(context.Context) {
+	model.BackfillNotifyOutbox()
+}
+
+// notifyDispatch 投递发件箱中到期的回调事件，并发数受 notify_workers 限制
+func notifyDispatch(ctx context.Context) {
+	var policy = model.GetNotifyPolicy()
+	var events = model.ClaimDueOutbox(policy.Workers * 4)
+	if len(events) == 0 {
 
 		return
 	}
 
-	for _, order := range tradeOrders {
-		next := utils.CalcNextNotifyTime(*order.ConfirmedAt, order.NotifyNum)
-		if time.Now().Unix() >= next.Unix() {
-			go notify.Handle(order)
+	var wg sync.WaitGroup
+	var sem = make(chan struct{}, policy.Workers)
+	for _, e := range events {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+
+			return
+		case sem <- struct{}{}:
 		}
+
+		wg.Add(1)
+		go func(e model.NotifyOutbox) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			deliverOutbox(e, policy)
+		}(e)
 	}
+
+	wg.Wait()
 }
 
-func notifyRoll(context.Context) {
-	for _, o := range model.GetOrderByStatus(model.OrderStatusWaiting) {
-		notify.Bepusdt(o)
+func deliverOutbox(e model.NotifyOutbox, policy model.NotifyPolicy) {
+	var err = dispatchOutbox(e)
+	if err == nil {
+		if err = e.Done(); err != nil {
+			log.Task.Warn(fmt.Sprintf("回调事件状态更新失败 %d：%v", e.ID, err))
+		}
+
+		return
 	}
+
+	if err2 := e.Fail(err, policy); err2 != nil {
+		log.Task.Warn(fmt.Sprintf("回调事件状态更新失败 %d：%v", e.ID, err2))
+
+		return
+	}
+
+	if e.State == model.OutboxStateDead {
+		log.Task.Warn(fmt.Sprintf("回调事件进入死信 %d：%s %v", e.ID, e.TradeId, err))
+
+		// 重试期间的失败不告警，只在进入死信时通知一次
+		if o, ok := model.GetTradeOrder(e.TradeId); ok {
+			notifier.NotifyFail(o, err.Error())
+		}
+	}
+}
+
+// dispatchOutbox 按事件类型投递；订单状态已发生后续变更的事件视为过期，直接完成
+func dispatchOutbox(e model.NotifyOutbox) error {
+	var order model.Order
+	model.Db.Where("trade_id = ?", e.TradeId).Limit(1).Find(&order)
+	if order.ID == 0 {
+
+		return errors.New("订单不存在")
+	}
+
+	if order.Status != e.OrderStatus {
+
+		return nil
+	}
+
+	switch e.Kind {
+	case model.NotifyKindCallback:
+
+		return notify.Handle(order)
+	case model.NotifyKindStatus:
+
+		return notify.Status(order)
+	}
+
+	return fmt.Errorf("未知的回调事件类型：%s", e.Kind)
 }
 
-// notifyOrderSuccess 统一触发订单成功后的回调与订单通知。
+// notifyOrderSuccess 订单成功后的订单通知，商户回调由发件箱投递。
 func notifyOrderSuccess(order model.Order) {
-	go notify.Handle(order)
 	go notifier.Success(order)
 }
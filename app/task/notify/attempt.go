@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/v03413/bepusdt/app"
+	"github.com/v03413/bepusdt/app/model"
+	"github.com/v03413/bepusdt/app/utils"
+	"gorm.io/gorm"
+)
+
+// send 发送回调请求并记录投递详情；返回的状态码为 0 表示请求未送达
+func send(db *gorm.DB, client *http.Client, req *http.Request, a *model.NotifyAttempt, payload []byte, check func(int, []byte) error) (int, []byte, error) {
+	a.Method = req.Method
+	a.Url = req.URL.String()
+	a.Payload = string(payload)
+
+	var start = time.Now()
+	var status int
+	var body []byte
+
+	resp, err := client.Do(req)
+	if err == nil {
+		status = resp.StatusCode
+		body, err = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+		if err == nil {
+			err = check(status, body)
+		}
+	}
+
+	a.Latency = time.Since(start).Milliseconds()
+	a.HttpStatus = status
+	a.SetResponse(body)
+	a.SetError(err)
+
+	model.SaveNotifyAttempt(db, a)
+
+	return status, body, err
+}
+
+func checkStatusOk(status int, _ []byte) error {
+	if status != 200 {
+
+		return fmt.Errorf("商户系统返回状态码错误：%d（必须是200）", status)
+	}
+
+	return nil
+}
+
+// checkEpayResp 易支付回调要求状态码 200 且响应内容包含 success 或 ok
+func checkEpayResp(status int, body []byte) error {
+	if err := checkStatusOk(status, body); err != nil {
+
+		return err
+	}
+
+	var bodyStr = strings.ToLower(strings.TrimSpace(string(body)))
+	if !strings.Contains(bodyStr, "success") && !strings.Contains(bodyStr, "ok") {
+
+		return fmt.Errorf("商户系统必须响应 success 或 ok 才会认定回调成功，实际响应：%s", string(body))
+	}
+
+	return nil
+}
+
+// Replay 按原请求内容重新投递一次回调；POST 请求会重新生成签名请求头，支付回调重放成功时同步更新订单回调状态
+func Replay(id int64) (model.NotifyAttempt, error) {
+	src, ok := model.GetNotifyAttempt(id)
+	if !ok {
+
+		return model.NotifyAttempt{}, errors.New("投递记录不存在")
+	}
+
+	order, ok := model.GetTradeOrder(src.TradeId)
+	if !ok {
+
+		return model.NotifyAttempt{}, errors.New("订单不存在")
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, src.Method, src.Url, strings.NewReader(src.Payload))
+	if err != nil {
+
+		return model.NotifyAttempt{}, err
+	}
+
+	req.Header.Set("Powered-By", "https://github.com/v03413/bepusdt")
+	if src.Method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "BEpusdt/"+app.Version)
+		utils.SetSignHeaders(req.Header, order.GetMerchant().Secret, []byte(src.Payload))
+	}
+
+	var check = checkStatusOk
+	if order.ApiType == model.OrderApiTypeEpay {
+		check = checkEpayResp
+	}
+
+	var a = model.NotifyAttempt{TradeId: src.TradeId, Kind: model.NotifyKindReplay, ReplayOf: src.ID}
+	_, _, err = send(model.Db, &http.Client{Timeout: time.Second * 5}, req, &a, []byte(src.Payload), check)
+	if err == nil && src.Kind == model.NotifyKindCallback && order.NotifyState != model.OrderNotifyStateSucc {
+		_ = order.SetNotifyState(model.OrderNotifyStateSucc)
+	}
+
+	return a, err
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/v03413/bepusdt/app/model"
+)
+
+func TestSendRecordsAttempt(t *testing.T) {
+	db := newNotifyTestDB(t)
+	initNotifyTestLog(t)
+	if err := db.AutoMigrate(&model.NotifyAttempt{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>fail</html>"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"?out_trade_no=1", nil)
+	status, _, err := send(db, &http.Client{Timeout: time.Second}, req, &model.NotifyAttempt{TradeId: "t1", Kind: model.NotifyKindCallback}, nil, checkEpayResp)
+	if err == nil || status != 200 {
+		t.Fatalf("status = %d, err = %v", status, err)
+	}
+
+	var a model.NotifyAttempt
+	db.Where("trade_id = ?", "t1").First(&a)
+	if a.Success || a.HttpStatus != 200 || a.Response != "<html>fail</html>" || a.Error == "" || a.Url != req.URL.String() {
+		t.Fatalf("unexpected attempt: %+v", a)
+	}
+}
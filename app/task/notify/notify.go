@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -14,26 +13,27 @@ import (
 	"github.com/v03413/bepusdt/app"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
-	"github.com/v03413/bepusdt/app/notifier"
 	"github.com/v03413/bepusdt/app/utils"
-
-	"github.com/v03413/go-cache"
 	"gorm.io/gorm"
 )
 
 type EpNotify struct {
-	TradeId            string  `json:"trade_id"`             //  本地订单号
-	OrderId            string  `json:"order_id"`             //  客户交易id
-	Amount             float64 `json:"amount"`               //  订单金额 CNY
-	ActualAmount       string  `json:"actual_amount"`        //  USDT 交易数额
-	Token              string  `json:"token"`                //  收款钱包地址
-	BlockTransactionId string  `json:"block_transaction_id"` // 区块id
-	Signature          string  `json:"signature"`            // 签名
-	Status             int     `json:"status"`               //  1：等待支付，2：支付成功，3：订单超时
+	Pid                string  `json:"pid,omitempty"`             // 商户号，默认商户不携带
+	TradeId            string  `json:"trade_id"`                  //  本地订单号
+	OrderId            string  `json:"order_id"`                  //  客户交易id
+	Amount             float64 `json:"amount"`                    //  订单金额 CNY
+	ActualAmount       string  `json:"actual_amount"`             //  USDT 交易数额
+	Token              string  `json:"token"`                     //  收款钱包地址
+	BlockTransactionId string  `json:"block_transaction_id"`      // 区块id
+	PaymentHashes      string  `json:"payment_hashes,omitempty"`  // 累计支付的全部交易哈希，逗号分隔
+	ReceivedAmount     string  `json:"received_amount,omitempty"` // 少付/多付时的实收数额
+	DiffAmount         string  `json:"diff_amount,omitempty"`     // 少付/多付时实收与订单数额的差值
+	Signature          string  `json:"signature"`                 // 签名
+	Status             int     `json:"status"`                    //  1：等待支付，2：支付成功，3：订单超时
 }
 
 func Handle(order model.Order) error {
-	if order.Status != model.OrderStatusSuccess {
+	if !order.IsPaid() {
 
 		return errors.New("订单未支付 无法回调")
 	}
@@ -58,7 +58,7 @@ func Handle(order model.Order) error {
 }
 
 func epay(ctx context.Context, order model.Order) error {
-	var client = http.Client{Timeout: time.Second * 5}
+	var client = &http.Client{Timeout: time.Second * 5}
 	var notifyUrl = fmt.Sprintf("%s?%s", order.NotifyUrl, order.BuildNotifyParams())
 
 	postReq, err2 := http.NewRequestWithContext(ctx, "GET", notifyUrl, nil)
@@ -67,34 +67,16 @@ func epay(ctx context.Context, order model.Order) error {
 	}
 
 	postReq.Header.Set("Powered-By", "https://github.com/v03413/bepusdt")
-	resp, err := client.Do(postReq)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		markNotifyFail(order, fmt.Sprintf("resp.StatusCode != 200"))
-
-		return fmt.Errorf("商户系统返回状态码错误：%d（必须是200）", resp.StatusCode)
-	}
 
-	all, err := io.ReadAll(resp.Body)
+	status, _, err := send(model.Db, client, postReq, &model.NotifyAttempt{TradeId: order.TradeId, Kind: model.NotifyKindCallback}, nil, checkEpayResp)
 	if err != nil {
-		markNotifyFail(order, fmt.Sprintf("io.ReadAll(resp.Body) Error: %v", err))
+		if status != 0 {
+			markNotifyFail(order, err.Error())
+		}
 
 		return err
 	}
 
-	var bodyStr = strings.ToLower(strings.TrimSpace(string(all)))
-
-	// 判断是否包含 success 或 ok
-	if !strings.Contains(bodyStr, "success") && !strings.Contains(bodyStr, "ok") {
-		markNotifyFail(order, "商户系统必须响应 success 或 ok 才会认定回调成功")
-
-		return fmt.Errorf("商户系统必须响应 success 或 ok 才会认定回调成功，实际响应：%s", string(all))
-	}
-
 	if err = order.SetNotifyState(model.OrderNotifyStateSucc); err != nil {
 		return err
 	}
@@ -105,12 +87,16 @@ func epay(ctx context.Context, order model.Order) error {
 func epusdt(ctx context.Context, order model.Order) error {
 	var data = make(map[string]interface{})
 	var body = EpNotify{
+		Pid:                merchantPid(order),
 		TradeId:            order.TradeId,
 		OrderId:            order.OrderId,
 		Amount:             cast.ToFloat64(order.Money),
 		ActualAmount:       order.Amount,
 		Token:              order.Address,
 		BlockTransactionId: order.RefHash,
+		PaymentHashes:      paymentHashes(order),
+		ReceivedAmount:     receivedAmount(order),
+		DiffAmount:         diffAmount(order),
 		Status:             order.Status,
 	}
 	var jsonBody, err = json.Marshal(body)
@@ -123,11 +109,13 @@ func epusdt(ctx context.Context, order model.Order) error {
 	}
 
 	// 签名
-	body.Signature = utils.EpusdtSign(data, model.AuthToken())
+	var secret = order.GetMerchant().Secret
+
+	body.Signature = utils.EpusdtSign(data, secret)
 
 	// 再次序列化
 	jsonBody, err = json.Marshal(body)
-	var client = http.Client{Timeout: time.Second * 5}
+	var client = &http.Client{Timeout: time.Second * 5}
 	var postReq, err2 = http.NewRequestWithContext(ctx, "POST", order.NotifyUrl, strings.NewReader(string(jsonBody)))
 	if err2 != nil {
 		markNotifyFail(order, err2.Error())
@@ -138,20 +126,15 @@ func epusdt(ctx context.Context, order model.Order) error {
 	postReq.Header.Set("Content-Type", "application/json")
 	postReq.Header.Set("Powered-By", "https://github.com/v03413/bepusdt")
 	postReq.Header.Set("User-Agent", "BEpusdt/"+app.Version)
-	resp, err := client.Do(postReq)
+	utils.SetSignHeaders(postReq.Header, secret, jsonBody)
+
+	_, _, err = send(model.Db, client, postReq, &model.NotifyAttempt{TradeId: order.TradeId, Kind: model.NotifyKindCallback}, jsonBody, checkStatusOk)
 	if err != nil {
 		markNotifyFail(order, err.Error())
 
 		return err
 	}
 
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		markNotifyFail(order, fmt.Sprintf("商户系统返回状态码错误：%d（必须是200）", resp.StatusCode))
-
-		return fmt.Errorf("商户系统返回状态码错误：%d（必须是200）", resp.StatusCode)
-	}
-
 	if err = order.SetNotifyState(model.OrderNotifyStateSucc); err != nil {
 
 		return err
@@ -160,19 +143,11 @@ func epusdt(ctx context.Context, order model.Order) error {
 	return nil
 }
 
-func Bepusdt(o model.Order) {
-	if o.ApiType != model.OrderApiTypeEpusdt && o.ApiType != model.OrderApiTypeEpusdtOrder {
-
-		return
-	}
-
-	var authToken = model.AuthToken()
+// Status 推送订单状态变更，订单状态已不是 o.Status 时不再推送
+func Status(o model.Order) error {
 	var client = &http.Client{Timeout: time.Second * 5}
-	go func() {
-		if err := deliverBepusdtStatusUpdate(model.Db, client, authToken, o); err != nil {
-			log.Warn("notify BEpusdt Error:", err.Error())
-		}
-	}()
+
+	return deliverBepusdtStatusUpdate(model.Db, client, o.GetMerchant().Secret, o)
 }
 
 func deliverBepusdtStatusUpdate(db *gorm.DB, client *http.Client, authToken string, o model.Order) error {
@@ -189,21 +164,18 @@ func deliverBepusdtStatusUpdate(db *gorm.DB, client *http.Client, authToken stri
 		return nil
 	}
 
-	var key = fmt.Sprintf("bepusdt_notify_%d_%s", current.Status, current.TradeId)
-	if _, ok := cache.Get(key); ok {
-		return nil
-	}
-
-	cache.Set(key, true, time.Minute)
-
 	var data = make(map[string]interface{})
 	var body = EpNotify{
+		Pid:                merchantPid(current),
 		TradeId:            current.TradeId,
 		OrderId:            current.OrderId,
 		Amount:             cast.ToFloat64(current.Money),
 		ActualAmount:       current.Amount,
 		Token:              current.Address,
 		BlockTransactionId: current.RefHash,
+		PaymentHashes:      paymentHashes(current),
+		ReceivedAmount:     receivedAmount(current),
+		DiffAmount:         diffAmount(current),
 		Status:             current.Status,
 	}
 	jsonBody, err := json.Marshal(body)
@@ -225,24 +197,62 @@ func deliverBepusdtStatusUpdate(db *gorm.DB, client *http.Client, authToken stri
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Powered-By", "https://github.com/v03413/BEpusdt")
-	resp, err := client.Do(req)
+	utils.SetSignHeaders(req.Header, authToken, jsonBody)
+
+	_, all, err := send(db, client, req, &model.NotifyAttempt{TradeId: current.TradeId, Kind: model.NotifyKindStatus}, jsonBody, checkStatusOk)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("resp.StatusCode != 200")
-	}
 
-	all, _ := io.ReadAll(resp.Body)
 	log.Info(fmt.Sprintf("订单回调成功[%d]：%s %s", current.Status, current.TradeId, string(all)))
 
 	return nil
 }
 
+// merchantPid 非默认商户的订单回调携带商户号，便于商户侧区分签名密钥
+func merchantPid(o model.Order) string {
+	if o.MerchantId == 0 {
+
+		return ""
+	}
+
+	return o.GetMerchant().Pid
+}
+
+// paymentHashes 累计支付模式下返回订单全部收款交易，未开启时为空，不参与签名
+func paymentHashes(o model.Order) string {
+	if !model.IsPaymentAccumulate() {
+
+		return ""
+	}
+
+	return strings.Join(o.GetPaymentHashes(), ",")
+}
+
+func isSettleDiff(o model.Order) bool {
+
+	return o.Status == model.OrderStatusUnderpaid || o.Status == model.OrderStatusOverpaid
+}
+
+func receivedAmount(o model.Order) string {
+	if !isSettleDiff(o) {
+
+		return ""
+	}
+
+	return o.PaidAmount
+}
+
+func diffAmount(o model.Order) string {
+	if !isSettleDiff(o) {
+
+		return ""
+	}
+
+	return o.GetDiffAmount()
+}
+
+// markNotifyFail 记录回调失败次数；失败告警由发件箱在事件进入死信时发出，避免每次重试都告警
 func markNotifyFail(o model.Order, reason string) {
 	log.Warn(fmt.Sprintf("订单回调失败(%v)：%s %v", o.TradeId, reason, o.SetNotifyState(model.OrderNotifyStateFail)))
-
-	notifier.NotifyFail(o, reason)
 }
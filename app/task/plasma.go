@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallnest/chanx"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
 )
 
@@ -16,12 +17,18 @@ func plasmaInit() {
 		Block: block{
 			ConfirmedOffset: 40,
 		},
+		Native: evmNative{
+			Parse:     true,
+			Decimal:   conf.PlasmaXplDecimals,
+			TradeType: model.PlasmaXpl,
+		},
 		Client:         utils.NewHttpClient(),
 		blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 30),
 	}
 
 	Register(Task{Callback: xpl.blockDispatch})
 	Register(Task{Callback: xpl.syncBlocksForward, Duration: time.Second * 5})
+	Register(Task{Callback: xpl.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: xpl.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: xpl.lookbackBlocks, Duration: time.Second * 15})
 }
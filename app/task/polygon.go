@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallnest/chanx"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
 )
 
@@ -16,12 +17,18 @@ func polygonInit() {
 		Block: block{
 			ConfirmedOffset: 40,
 		},
+		Native: evmNative{
+			Parse:     true,
+			Decimal:   conf.PolygonPolDecimals,
+			TradeType: model.PolygonPol,
+		},
 		Client:         utils.NewHttpClient(),
 		blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 30),
 	}
 
 	Register(Task{Callback: pol.blockDispatch})
 	Register(Task{Callback: pol.syncBlocksForward, Duration: time.Second * 5})
+	Register(Task{Callback: pol.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: pol.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: pol.lookbackBlocks, Duration: time.Second * 15})
 }
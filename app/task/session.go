@@ -0,0 +1,17 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/v03413/bepusdt/app/model"
+)
+
+// init 定期清理已过期的后台登录会话
+func init() {
+	Register(Task{Duration: time.Minute * 10, Callback: sessionClean})
+}
+
+func sessionClean(ctx context.Context) {
+	model.CleanExpiredSessions()
+}
@@ -18,7 +18,6 @@ import (
 	"github.com/spf13/cast"
 	"github.com/tidwall/gjson"
 	"github.com/v03413/bepusdt/app/conf"
-	blockapi "github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
@@ -65,11 +64,13 @@ func (s *solana) syncSlotForward(ctx context.Context) {
 		return
 	}
 
-	req, _ := http.NewRequestWithContext(ctx, "POST", model.Endpoint(conf.Solana), bytes.NewBuffer([]byte(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)))
+	var url = model.Endpoint(conf.Solana)
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)))
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
+		conf.RecordFailure(conf.Solana, url)
 		log.Task.Warn("syncSlotForward Error sending request:", err)
 
 		return
@@ -78,6 +79,7 @@ func (s *solana) syncSlotForward(ctx context.Context) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		conf.RecordFailure(conf.Solana, url)
 		log.Task.Warn("syncSlotForward Error response status code:", resp.StatusCode)
 
 		return
@@ -97,6 +99,11 @@ func (s *solana) syncSlotForward(ctx context.Context) {
 		return
 	}
 
+	var cursor = getCursor(conf.Solana)
+	if s.lastSlotNum == 0 { // 启动后从持久化游标继续扫描
+		s.lastSlotNum = int(cursor.Resume())
+	}
+
 	if now-s.lastSlotNum > cast.ToInt(model.GetC(model.BlockHeightMaxDiff)) { // 区块高度变化过大，强制丢块重扫
 		s.lastSlotNum = now
 	}
@@ -108,7 +115,7 @@ func (s *solana) syncSlotForward(ctx context.Context) {
 
 	for n := s.lastSlotNum + 1; n <= now; n++ {
 		// 待扫描区块入列
-
+		cursor.Push(int64(n), int64(n))
 		s.slotQueue.In <- n
 	}
 
@@ -146,11 +153,12 @@ func (s *solana) slotParse(n any) {
 	slot := n.(int)
 	post := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"getBlock","params":[%d,{"encoding":"json","maxSupportedTransactionVersion":0,"transactionDetails":"full","rewards":false}]}`, slot))
 	network := conf.Solana
+	url := model.Endpoint(conf.Solana)
 
-	conf.RecordSuccess(network, cast.ToString(slot))
-	resp, err := s.client.Post(model.Endpoint(conf.Solana), "application/json", bytes.NewBuffer(post))
+	resp, err := s.client.Post(url, "application/json", bytes.NewBuffer(post))
 	if err != nil {
-		conf.RecordFailure(network)
+		conf.RecordFailure(network, url)
+		s.slotQueue.In <- slot
 		log.Task.Warn("slotParse Error sending request:", err)
 
 		return
@@ -158,7 +166,8 @@ func (s *solana) slotParse(n any) {
 
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		conf.RecordFailure(network)
+		conf.RecordFailure(network, url)
+		s.slotQueue.In <- slot
 		log.Task.Warn("slotParse Error response status code:", resp.StatusCode)
 
 		return
@@ -166,108 +175,128 @@ func (s *solana) slotParse(n any) {
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		conf.RecordFailure(network)
+		conf.RecordFailure(network, url)
 		s.slotQueue.In <- slot
 		log.Task.Warn("slotParse Error reading response body:", err)
 
 		return
 	}
 
+	conf.RecordSuccess(network, url, cast.ToString(slot))
+
 	timestamp := time.Unix(gjson.GetBytes(body, "result.blockTime").Int(), 0)
 
 	for _, trans := range gjson.GetBytes(body, "result.transactions").Array() {
-		hash := trans.Get("transaction.signatures.0").String()
-
-		// 解析账号索引
-		accountKeys := make([]string, 0)
-		for _, key := range trans.Get("transaction.message.accountKeys").Array() {
-			accountKeys = append(accountKeys, key.String())
-		}
-		for _, v := range []string{"readonly", "writable"} {
-			for _, key := range trans.Get("meta.loadedAddresses." + v).Array() {
-				accountKeys = append(accountKeys, key.String())
-			}
+		if result := s.parseTransaction(trans, slot, timestamp); len(result) > 0 {
+			transferQueue.In <- result
 		}
+	}
 
-		// 查找SPL Token索引
-		splTokenIndex := int64(-1)
-		for i, v := range accountKeys {
-			if v == conf.SolSplToken {
-				splTokenIndex = int64(i)
+	getCursor(network).Done(int64(slot), int64(slot))
 
-				break
-			}
-		}
+	log.Task.Info(fmt.Sprintf("区块扫描完成(Solana) %d 成功率：%s", slot, conf.GetSuccessRate(network)))
+}
 
-		// SPL Token的Mint地址，即不包含 Token 交易信息
-		if splTokenIndex == -1 {
+// parseTransaction 解析单笔交易中的转账，链上执行失败的交易（meta.err 非空）不产生任何转账
+func (s *solana) parseTransaction(trans gjson.Result, slot int, timestamp time.Time) []transfer {
+	if solanaTxFailed(trans.Get("meta.err")) {
 
-			continue
-		}
+		return nil
+	}
 
-		// 解析 Token 账户 【Token Wallet => Owner Wallet】
-		tokenAccountMap := make(map[string]solanaTokenOwner)
-		for _, v := range []string{"postTokenBalances", "preTokenBalances"} {
-			for _, itm := range trans.Get("meta." + v).Array() {
-				tradeType, ok := model.GetContractTrade(itm.Get("mint").String())
-				if !ok || itm.Get("programId").String() != conf.SolSplToken {
+	hash := trans.Get("transaction.signatures.0").String()
 
-					continue
-				}
+	// 解析账号索引
+	accountKeys := make([]string, 0)
+	for _, key := range trans.Get("transaction.message.accountKeys").Array() {
+		accountKeys = append(accountKeys, key.String())
+	}
+	for _, v := range []string{"readonly", "writable"} {
+		for _, key := range trans.Get("meta.loadedAddresses." + v).Array() {
+			accountKeys = append(accountKeys, key.String())
+		}
+	}
 
-				tokenAccountMap[accountKeys[itm.Get("accountIndex").Int()]] = solanaTokenOwner{
-					TradeType: tradeType,
-					Address:   itm.Get("owner").String(),
-				}
-			}
+	// 查找SPL Token与System Program索引
+	splTokenIndex, systemIndex := int64(-1), int64(-1)
+	for i, v := range accountKeys {
+		switch v {
+		case conf.SolSplToken:
+			splTokenIndex = int64(i)
+		case conf.SolSystem:
+			systemIndex = int64(i)
 		}
+	}
+
+	// 既不包含 Token 交易，也不包含 SOL 转账
+	if splTokenIndex == -1 && systemIndex == -1 {
 
-		transArr := make([]transfer, 0)
+		return nil
+	}
 
-		// 解析外部指令
-		for _, instr := range trans.Get("transaction.message.instructions").Array() {
-			if instr.Get("programIdIndex").Int() != splTokenIndex {
+	// 解析 Token 账户 【Token Wallet => Owner Wallet】
+	tokenAccountMap := make(map[string]solanaTokenOwner)
+	for _, v := range []string{"postTokenBalances", "preTokenBalances"} {
+		for _, itm := range trans.Get("meta." + v).Array() {
+			tradeType, ok := model.GetContractTrade(conf.Solana, itm.Get("mint").String())
+			if !ok || itm.Get("programId").String() != conf.SolSplToken {
 
 				continue
 			}
 
-			transArr = append(transArr, s.parseTransfer(instr, accountKeys, tokenAccountMap))
+			tokenAccountMap[accountKeys[itm.Get("accountIndex").Int()]] = solanaTokenOwner{
+				TradeType: tradeType,
+				Address:   itm.Get("owner").String(),
+			}
 		}
+	}
 
-		// 解析内部指令
-		for _, itm := range trans.Get("meta.innerInstructions").Array() {
-			for _, instr := range itm.Get("instructions").Array() {
-				if instr.Get("programIdIndex").Int() != splTokenIndex {
-
-					continue
-				}
+	transArr := make([]transfer, 0)
 
-				transArr = append(transArr, s.parseTransfer(instr, accountKeys, tokenAccountMap))
-			}
+	var parseInstr = func(instr gjson.Result) {
+		switch instr.Get("programIdIndex").Int() {
+		case splTokenIndex:
+			transArr = append(transArr, s.parseTransfer(instr, accountKeys, tokenAccountMap))
+		case systemIndex:
+			transArr = append(transArr, s.parseSystemTransfer(instr, accountKeys))
 		}
+	}
 
-		// 过滤无关交易
-		result := make([]transfer, 0)
-		for _, t := range transArr {
-			if t.FromAddress == "" || t.RecvAddress == "" || t.Amount.IsZero() {
+	// 解析外部指令
+	for _, instr := range trans.Get("transaction.message.instructions").Array() {
+		parseInstr(instr)
+	}
 
-				continue
-			}
+	// 解析内部指令
+	for _, itm := range trans.Get("meta.innerInstructions").Array() {
+		for _, instr := range itm.Get("instructions").Array() {
+			parseInstr(instr)
+		}
+	}
 
-			t.TxHash = hash
-			t.Network = conf.Solana
-			t.BlockNum = slot
-			t.Timestamp = timestamp
+	// 过滤无关交易
+	result := make([]transfer, 0)
+	for _, t := range transArr {
+		if t.FromAddress == "" || t.RecvAddress == "" || t.Amount.IsZero() {
 
-			result = append(result, t)
+			continue
 		}
 
-		if len(result) > 0 {
-			transferQueue.In <- result
-		}
+		t.TxHash = hash
+		t.Network = conf.Solana
+		t.BlockNum = slot
+		t.Timestamp = timestamp
+
+		result = append(result, t)
 	}
 
-	log.Task.Info(fmt.Sprintf("区块扫描完成(Solana) %d 成功率：%s", slot, conf.GetSuccessRate(network)))
+	return result
+}
+
+// solanaTxFailed 交易执行失败时 err 为具体错误对象，成功时为 null
+func solanaTxFailed(err gjson.Result) bool {
+
+	return err.Exists() && err.Type != gjson.Null
 }
 
 func (s *solana) parseTransfer(instr gjson.Result, accountKeys []string, tokenAccountMap map[string]solanaTokenOwner) transfer {
@@ -320,6 +349,38 @@ func (s *solana) parseTransfer(instr gjson.Result, accountKeys []string, tokenAc
 	return trans
 }
 
+// parseSystemTransfer 解析 System Program Transfer 指令（SOL 原生转账）
+// 指令数据：4 字节指令序号(2) + 8 字节 lamports，账户：[from, to]
+func (s *solana) parseSystemTransfer(instr gjson.Result, accountKeys []string) transfer {
+	accounts := instr.Get("accounts").Array()
+	trans := transfer{}
+	if len(accounts) < 2 {
+
+		return trans
+	}
+
+	data := base58.Decode(instr.Get("data").String())
+	if len(data) != 12 || binary.LittleEndian.Uint32(data[:4]) != 2 {
+
+		return trans
+	}
+
+	fromIdx, toIdx := accounts[0].Int(), accounts[1].Int()
+	if fromIdx >= int64(len(accountKeys)) || toIdx >= int64(len(accountKeys)) {
+
+		return trans
+	}
+
+	lamports := new(big.Int).SetUint64(binary.LittleEndian.Uint64(data[4:12]))
+
+	trans.FromAddress = accountKeys[fromIdx]
+	trans.RecvAddress = accountKeys[toIdx]
+	trans.TradeType = model.SolanaSol
+	trans.Amount = decimal.NewFromBigInt(lamports, model.GetTradeDecimal(model.SolanaSol))
+
+	return trans
+}
+
 func (s *solana) tradeConfirmHandle(ctx context.Context) {
 	var orders = getConfirmingOrders(model.GetNetworkTrades(conf.Solana))
 	var wg sync.WaitGroup
@@ -365,6 +426,15 @@ func (s *solana) tradeConfirmHandle(ctx context.Context) {
 			return
 		}
 
+		// 链上执行失败的交易同样会被最终确认，不能视为支付成功
+		if solanaTxFailed(data.Get("result.value.0.err")) {
+			log.Task.Warn(fmt.Sprintf("solana 交易执行失败 %s：%s", o.RefHash, data.Get("result.value.0.err").String()))
+			if err := o.SetFailed(); err != nil {
+				log.Task.Warn(fmt.Sprintf("订单确认失败 %s：%v", o.TradeId, err))
+			}
+
+			return
+		}
 		if data.Get("result.value.0.confirmationStatus").String() == "finalized" {
 
 			markFinalConfirmed(o)
@@ -393,7 +463,7 @@ func (s *solana) lookbackSlots(ctx context.Context) {
 		return
 	}
 
-	start, end := blockapi.New().GetBoundaryHeights(startAt, endAt, conf.Solana)
+	start, end := getBoundaryHeights(conf.Solana, s, startAt, endAt)
 	for i := int(start); i <= int(end); i++ {
 		select {
 		case <-ctx.Done():
@@ -407,3 +477,62 @@ func (s *solana) lookbackSlots(ctx context.Context) {
 		time.Sleep(time.Millisecond * 200)
 	}
 }
+
+func (s *solana) latestHeight(ctx context.Context) (int64, error) {
+	res, err := s.rpcCall(ctx, `{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	if err != nil {
+
+		return 0, err
+	}
+
+	return res.Int(), nil
+}
+
+// blockTime 跳过的 Slot 没有区块时间，向后顺延查找；均不可用时返回错误，避免 0 被当作早于目标时间参与二分
+func (s *solana) blockTime(ctx context.Context, height int64) (int64, error) {
+	var err error
+	for slot := height; slot < height+20; slot++ {
+		var res gjson.Result
+		res, err = s.rpcCall(ctx, fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"getBlockTime","params":[%d]}`, slot))
+		if err == nil && res.Int() > 0 {
+
+			return res.Int(), nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("slot %d 之后的区块时间均不可用", height)
+	}
+
+	return 0, err
+}
+
+func (s *solana) rpcCall(ctx context.Context, post string) (gjson.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", model.Endpoint(conf.Solana), bytes.NewBufferString(post))
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+
+		return gjson.Result{}, err
+	}
+
+	data := gjson.ParseBytes(body)
+	if data.Get("error").Exists() {
+
+		return gjson.Result{}, fmt.Errorf("solana rpc error %s", data.Get("error").String())
+	}
+
+	return data.Get("result"), nil
+}
@@ -0,0 +1,50 @@
+package task
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/tidwall/gjson"
+	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+func TestSolanaFailedTransaction(t *testing.T) {
+	var data = make([]byte, 12)
+	binary.LittleEndian.PutUint32(data[:4], 2)
+	binary.LittleEndian.PutUint64(data[4:], 1500000000) // 1.5 SOL
+
+	var fixture = func(metaErr string) gjson.Result {
+		return gjson.Parse(fmt.Sprintf(`{
+			"meta":{"err":%s,"innerInstructions":[],"postTokenBalances":[],"preTokenBalances":[]},
+			"transaction":{"signatures":["sig1"],"message":{
+				"accountKeys":["FromAddr","RecvAddr","%s"],
+				"instructions":[{"programIdIndex":2,"accounts":[0,1],"data":"%s"}]
+			}}
+		}`, metaErr, conf.SolSystem, base58.Encode(data)))
+	}
+
+	s := &solana{}
+	result := s.parseTransaction(fixture("null"), 100, time.Now())
+	if len(result) != 1 || result[0].RecvAddress != "RecvAddr" || result[0].TradeType != model.SolanaSol || result[0].Amount.String() != "1.5" {
+		t.Fatalf("unexpected transfers: %+v", result)
+	}
+
+	// 执行失败的交易只扣手续费，转账并未发生
+	if result = s.parseTransaction(fixture(`{"InstructionError":[0,{"Custom":1}]}`), 100, time.Now()); len(result) != 0 {
+		t.Fatalf("failed transaction parsed as transfer: %+v", result)
+	}
+
+	// getSignatureStatuses 返回的执行失败状态
+	status := gjson.Parse(`{"result":{"value":[{"confirmationStatus":"finalized","err":{"InstructionError":[0,{"Custom":1}]}}]}}`)
+	if !solanaTxFailed(status.Get("result.value.0.err")) {
+		t.Fatal("finalized failed transaction treated as success")
+	}
+	status = gjson.Parse(`{"result":{"value":[{"confirmationStatus":"finalized","err":null}]}}`)
+	if solanaTxFailed(status.Get("result.value.0.err")) {
+		t.Fatal("successful transaction treated as failed")
+	}
+}
@@ -33,6 +33,8 @@ func Init() error {
 	arbitrumInit()
 	xlayerInit()
 	baseInit()
+	bitcoinInit()
+	litecoinInit()
 
 	return nil
 }
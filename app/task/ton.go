@@ -12,10 +12,10 @@ import (
 	"github.com/smallnest/chanx"
 	"github.com/spf13/cast"
 	"github.com/v03413/bepusdt/app/conf"
-	blockapi "github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
+	"github.com/v03413/go-cache"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	tgo "github.com/xssnick/tonutils-go/ton"
@@ -60,6 +60,9 @@ func (t *ton) syncMBSeqnoForward(ctx context.Context) {
 		}
 
 		// 初始化：首次获取当前最新高度作为起点
+		if t.lastBlockSeqno == 0 { // 启动后优先从持久化游标继续扫描
+			t.lastBlockSeqno = uint32(getCursor(conf.Ton).Resume())
+		}
 		if t.lastBlockSeqno == 0 {
 			mb, err := t.client().CurrentMasterchainInfo(ctx)
 			if err != nil {
@@ -94,6 +97,7 @@ func (t *ton) syncMBSeqnoForward(ctx context.Context) {
 
 		// 待扫描区块入列
 		for n := t.lastBlockSeqno + 1; n <= now; n++ {
+			getCursor(conf.Ton).Push(int64(n), int64(n))
 			t.blockScanQueue.In <- n
 		}
 
@@ -134,18 +138,20 @@ func (t *ton) blockParse(n any) {
 
 	mb, err := t.client().LookupBlock(ctx, tonMasterChainID, tonMasterShard, seqno)
 	if err != nil {
-		conf.RecordFailure(conf.Ton)
+		conf.RecordFailure(conf.Ton, "")
+		t.blockScanQueue.In <- seqno
 		log.Task.Warn("Ton LookupBlock ", err)
 
 		return
 	}
 
-	conf.RecordSuccess(conf.Ton, cast.ToString(seqno))
+	conf.RecordSuccess(conf.Ton, "", cast.ToString(seqno))
 
 	// 目前实际来看，basechain 还未分裂，所以 len(shardsTip) == 1
 	shardsTip, err := t.client().GetBlockShardsInfo(ctx, mb)
 	if err != nil {
-		conf.RecordFailure(conf.Ton)
+		conf.RecordFailure(conf.Ton, "")
+		t.blockScanQueue.In <- seqno
 		log.Task.Warn(fmt.Sprintf("get shards info seqno=%d err: %v", seqno, err))
 
 		return
@@ -161,19 +167,21 @@ func (t *ton) blockParse(n any) {
 		for s := start; s <= tip.SeqNo; s++ {
 			shardBlock, err := t.client().LookupBlock(ctx, tip.Workchain, tip.Shard, s)
 			if err != nil {
-				conf.RecordFailure(conf.Ton)
+				conf.RecordFailure(conf.Ton, "")
 				log.Task.Warn(fmt.Sprintf("lookup shard block workchain=%d shard=%d seqno=%d err: %v", tip.Workchain, tip.Shard, s, err))
 
 				continue
 			}
 
 			if err := t.processShard(ctx, shardBlock, seqno); err != nil {
-				conf.RecordFailure(conf.Ton)
+				conf.RecordFailure(conf.Ton, "")
 				log.Task.Warn(fmt.Sprintf("process shard block workchain=%d shard=%d seqno=%d err: %v", tip.Workchain, tip.Shard, s, err))
 			}
 		}
 	}
 
+	getCursor(conf.Ton).Done(int64(seqno), int64(seqno))
+
 	log.Task.Info(fmt.Sprintf("区块扫描完成(Ton): %d 成功率：%s", seqno, conf.GetSuccessRate(conf.Ton)))
 }
 
@@ -189,7 +197,7 @@ func (t *ton) syncBreak() bool {
 	}
 
 	var count int64 = 0
-	trade := []model.TradeType{model.UsdtTon, model.TonGram}
+	trade := model.GetNetworkTrades(conf.Ton)
 	model.Db.Model(&model.Order{}).Where("status = ? and trade_type in (?)", model.OrderStatusWaiting, trade).Count(&count)
 	if count > 0 {
 
@@ -280,20 +288,46 @@ func (t *ton) parseInternalTransfer(shard *tgo.BlockIDExt, tx *tlb.Transaction,
 		fromOwner = address.NewAddress(0, byte(fromOwner.Workchain()), fromOwner.Data())
 	}
 
-	toJetton := address.NewAddress(0, byte(shard.Workchain), tx.AccountAddr)
+	toJetton := address.NewAddress(0, byte(shard.Workchain), tx.AccountAddr).Bounce(false).String()
+	tradeType, ok := t.jettonTrade(toJetton)
+	if !ok {
+		return transfer{}, false
+	}
 
 	return transfer{
 		Network:     conf.Ton,
 		TxHash:      hex.EncodeToString(tx.Hash),
-		Amount:      decimal.NewFromBigInt(amount, conf.UsdtTonDecimals),
+		Amount:      decimal.NewFromBigInt(amount, model.GetTradeDecimal(tradeType)),
 		FromAddress: fromOwner.Bounce(false).String(),
-		RecvAddress: toJetton.Bounce(false).String(),
+		RecvAddress: toJetton,
 		Timestamp:   time.Unix(int64(tx.Now), 0),
-		TradeType:   model.UsdtTon,
+		TradeType:   tradeType,
 		BlockNum:    int(blockNum),
 	}, true
 }
 
+// jettonTrade Jetton Wallet 地址对应的交易类型；未登记的地址无法确定代币种类与精度，不做处理
+func (t *ton) jettonTrade(addr string) (model.TradeType, bool) {
+	var trades map[string]model.TradeType
+	if v, ok := cache.Get("ton_jetton_trades"); ok {
+		trades = v.(map[string]model.TradeType)
+	} else {
+		var wallets []model.Wallet
+		model.Db.Where("trade_type in (?) and trade_type <> ?", model.GetNetworkTrades(conf.Ton), model.TonGram).Find(&wallets)
+
+		trades = make(map[string]model.TradeType)
+		for _, wa := range wallets {
+			trades[wa.MatchAddr] = model.TradeType(wa.TradeType)
+		}
+
+		cache.Set("ton_jetton_trades", trades, time.Second*10)
+	}
+
+	tradeType, ok := trades[addr]
+
+	return tradeType, ok
+}
+
 func (t *ton) parseTonTransfer(tx *tlb.Transaction, blockNum uint32) (transfer, bool) {
 	in := tx.IO.In
 	if in == nil {
@@ -327,7 +361,7 @@ func (t *ton) parseTonTransfer(tx *tlb.Transaction, blockNum uint32) (transfer,
 }
 
 func (t *ton) tradeConfirmHandle(context.Context) {
-	var orders = getConfirmingOrders([]model.TradeType{model.UsdtTon, model.TonGram})
+	var orders = getConfirmingOrders(model.GetNetworkTrades(conf.Ton))
 	var wg sync.WaitGroup
 
 	for _, order := range orders {
@@ -351,6 +385,32 @@ func (t *ton) client() tgo.APIClientWrapped {
 	return t.api
 }
 
+func (t *ton) latestHeight(ctx context.Context) (int64, error) {
+	mb, err := t.client().CurrentMasterchainInfo(ctx)
+	if err != nil {
+
+		return 0, err
+	}
+
+	return int64(mb.SeqNo), nil
+}
+
+func (t *ton) blockTime(ctx context.Context, height int64) (int64, error) {
+	mb, err := t.client().LookupBlock(ctx, tonMasterChainID, tonMasterShard, uint32(height))
+	if err != nil {
+
+		return 0, err
+	}
+
+	data, err := t.client().GetBlockData(ctx, mb)
+	if err != nil {
+
+		return 0, err
+	}
+
+	return int64(data.BlockInfo.GenUtime), nil
+}
+
 func (t *ton) lookbackBlocks(ctx context.Context) {
 	if t.syncBreak() {
 		return
@@ -361,7 +421,7 @@ func (t *ton) lookbackBlocks(ctx context.Context) {
 		return
 	}
 
-	start, end := blockapi.New().GetBoundaryHeights(startAt, endAt, conf.Ton)
+	start, end := getBoundaryHeights(conf.Ton, t, startAt, endAt)
 	for i := start; i <= end; i++ {
 		select {
 		case <-ctx.Done():
@@ -13,7 +13,6 @@ import (
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/notifier"
-	"github.com/v03413/bepusdt/app/task/notify"
 	"github.com/v03413/tronprotocol/core"
 )
 
@@ -108,6 +107,12 @@ func orderTransferHandle(ctx context.Context) {
 						continue
 					}
 
+					if model.IsPaymentAccumulate() {
+						if _, err := o.AddPayment(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount); err != nil {
+							log.Task.Warn("add order payment failed:", err)
+						}
+					}
+
 					// 订单匹配 进入确认流程
 					if err := o.MarkConfirming(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount); err != nil {
 						log.Task.Warn("mark order confirming failed:", err)
@@ -120,6 +125,14 @@ func orderTransferHandle(ctx context.Context) {
 					break
 				}
 
+				if !matched {
+					matched = orderTransferTolerance(orders, key, t)
+				}
+
+				if !matched && model.IsPaymentAccumulate() {
+					matched = orderTransferAccumulate(orders, key, t)
+				}
+
 				if !matched {
 					other = append(other, t)
 				}
@@ -152,6 +165,92 @@ func orderTransferMatch(o model.Order, t transfer) bool {
 	return true
 }
 
+// singleOrderCandidate 查找唯一符合条件的候选订单，存在多个候选时无法判断归属，返回 -1
+func singleOrderCandidate(list []model.Order, t transfer, accept func(o model.Order) bool) int {
+	var index = -1
+	for i, o := range list {
+		if o.AddressLocked || o.TradeType != t.TradeType {
+			continue
+		}
+		if !o.CreatedAt.Before(t.Timestamp) || !o.ExpiredAt.After(t.Timestamp) {
+			continue
+		}
+		if !accept(o) {
+			continue
+		}
+		if index != -1 {
+			return -1
+		}
+
+		index = i
+	}
+
+	return index
+}
+
+// orderTransferTolerance 误差策略，数额在订单数额 ±X% 范围内的转账视为支付，确认后标记为少付或多付
+func orderTransferTolerance(orders map[string][]model.Order, key string, t transfer) bool {
+	var index = singleOrderCandidate(orders[key], t, func(o model.Order) bool {
+		return model.IsWithinTolerance(o.TradeType, t.Amount, o.Amount)
+	})
+	if index == -1 {
+		return false
+	}
+
+	var o = orders[key][index]
+	if model.IsPaymentAccumulate() {
+		if _, err := o.AddPayment(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount); err != nil {
+			log.Task.Warn("add order payment failed:", err)
+		}
+	}
+
+	if err := o.MarkConfirming(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount); err != nil {
+		log.Task.Warn("mark order confirming failed:", err)
+
+		return false
+	}
+
+	orders[key] = append(orders[key][:index], orders[key][index+1:]...)
+
+	return true
+}
+
+// orderTransferAccumulate 累计支付，数额不匹配的转账计入订单已付数额，累计达到订单数额后进入确认流程；
+// 同一地址存在多个候选订单时无法判断归属，不做累计
+func orderTransferAccumulate(orders map[string][]model.Order, key string, t transfer) bool {
+	var index = singleOrderCandidate(orders[key], t, func(model.Order) bool { return true })
+	if index == -1 {
+		return false
+	}
+
+	var o = orders[key][index]
+	paid, err := o.AddPayment(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount)
+	if err != nil {
+		log.Task.Warn("add order payment failed:", err)
+
+		return false
+	}
+
+	target, err := decimal.NewFromString(o.Amount)
+	if err != nil || paid.LessThan(model.ToleranceFloor(o.TradeType, target)) {
+		log.Task.Info(fmt.Sprintf("订单部分支付[%s]：%s / %s", o.TradeId, paid.String(), o.Amount))
+		orders[key][index] = o
+
+		return true
+	}
+
+	// 累计数额达标，以最后一笔交易进入确认流程；之前的交易区块更早，确认数不会更少
+	if err = o.MarkConfirming(t.BlockNum, t.FromAddress, t.TxHash, t.Timestamp, t.Amount); err != nil {
+		log.Task.Warn("mark order confirming failed:", err)
+
+		return true
+	}
+
+	orders[key] = append(orders[key][:index], orders[key][index+1:]...)
+
+	return true
+}
+
 func orderMatchAddress(o model.Order) string {
 	if o.MatchAddress != "" {
 		return o.MatchAddress
@@ -255,10 +354,33 @@ func tronResourceHandle(ctx context.Context) {
 }
 
 func markFinalConfirmed(o model.Order) {
-	o.SetSuccess()
+	if err := o.SetPaid(settleStatus(o)); err != nil {
+		log.Task.Warn(fmt.Sprintf("订单确认失败 %s：%v", o.TradeId, err))
+
+		return
+	}
+
 	notifyOrderSuccess(o)
 }
 
+// settleStatus 按实收数额确定订单最终状态，匹配模式认可的数额视为支付成功
+func settleStatus(o model.Order) int {
+	paid, err := decimal.NewFromString(o.PaidAmount)
+	if o.AddressLocked || err != nil || paid.IsZero() || amountMatch(paid, o.Amount, string(o.TradeType)) {
+		return model.OrderStatusSuccess
+	}
+
+	amount, _ := decimal.NewFromString(o.Amount)
+	if paid.LessThan(amount) {
+		return model.OrderStatusUnderpaid
+	}
+	if paid.GreaterThan(amount) {
+		return model.OrderStatusOverpaid
+	}
+
+	return model.OrderStatusSuccess
+}
+
 func receivableOrderStatuses() []int {
 	return []int{model.OrderStatusWaiting, model.OrderStatusExpired}
 }
@@ -345,8 +467,11 @@ func expireWaitingOrders() {
 			continue
 		}
 
-		t.SetExpired()
-		notify.Bepusdt(t)
+		if err := t.SetExpired(); err != nil {
+			log.Task.Warn(fmt.Sprintf("订单过期失败 %s：%v", t.TradeId, err))
+
+			continue
+		}
 	}
 }
 
@@ -363,8 +488,11 @@ func getConfirmingOrders(tradeType []model.TradeType) []model.Order {
 	for _, order := range orders {
 		if time.Now().Unix() >= order.ExpiredAt.Unix() {
 			if order.ConfirmedAt == nil || order.ConfirmedAt.IsZero() || !order.ConfirmedAt.Before(order.ExpiredAt) {
-				order.SetFailed()
-				notify.Bepusdt(order)
+				if err := order.SetFailed(); err != nil {
+					log.Task.Warn(fmt.Sprintf("订单确认失败 %s：%v", order.TradeId, err))
+
+					continue
+				}
 
 				continue
 			}
@@ -0,0 +1,167 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/v03413/bepusdt/app/model"
+)
+
+func newTestOrder(t *testing.T, id, address, amount string) model.Order {
+	t.Helper()
+
+	zero := time.Unix(0, 0)
+	o := model.Order{TradeId: id, RefHash: id, Address: address, TradeType: model.UsdtTrc20, Amount: amount, Status: model.OrderStatusWaiting, ExpiredAt: time.Now().Add(time.Hour), ConfirmedAt: &zero}
+	if err := model.Db.Create(&o).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+func newTestTransfer(address, hash, amount string) transfer {
+
+	return transfer{
+		TxHash:      hash,
+		Amount:      decimal.RequireFromString(amount),
+		FromAddress: "TFrom",
+		RecvAddress: address,
+		Timestamp:   time.Now().Add(time.Minute),
+		TradeType:   model.UsdtTrc20,
+		BlockNum:    100,
+	}
+}
+
+func TestOrderTransferAccumulate(t *testing.T) {
+	setupTestDb(t, &model.Conf{}, &model.Order{}, &model.OrderEvent{}, &model.OrderPayment{}, &model.NotifyOutbox{})
+	model.SetK(model.PaymentAccumulate, "1")
+	model.RefreshC()
+	t.Cleanup(func() {
+		model.SetK(model.PaymentAccumulate, "0")
+		model.RefreshC()
+	})
+
+	var reload = func(id string) model.Order {
+		var o model.Order
+		model.Db.Where("trade_id = ?", id).First(&o)
+
+		return o
+	}
+
+	o := newTestOrder(t, "a1", "TAddrA", "10")
+	var key = "TAddrA" + string(model.UsdtTrc20)
+	var orders = map[string][]model.Order{key: {o}}
+
+	// 部分支付计入已付数额，订单继续等待
+	if !orderTransferAccumulate(orders, key, newTestTransfer("TAddrA", "0x1", "9.5")) {
+		t.Fatal("partial payment not accepted")
+	}
+	if o = reload("a1"); o.Status != model.OrderStatusWaiting || o.PaidAmount != "9.5" {
+		t.Fatalf("after partial: status=%d paid=%s", o.Status, o.PaidAmount)
+	}
+
+	// 同一笔交易重复扫描不重复累计
+	orderTransferAccumulate(orders, key, newTestTransfer("TAddrA", "0x1", "9.5"))
+	if o = reload("a1"); o.Status != model.OrderStatusWaiting || o.PaidAmount != "9.5" {
+		t.Fatalf("after duplicate: status=%d paid=%s", o.Status, o.PaidAmount)
+	}
+
+	// 累计达到订单数额后进入确认，以最后一笔交易为关联交易
+	if !orderTransferAccumulate(orders, key, newTestTransfer("TAddrA", "0x2", "0.5")) {
+		t.Fatal("top-up not accepted")
+	}
+	if o = reload("a1"); o.Status != model.OrderStatusConfirming || o.PaidAmount != "10" || o.RefHash != "0x2" {
+		t.Fatalf("after top-up: status=%d paid=%s hash=%s", o.Status, o.PaidAmount, o.RefHash)
+	}
+	if len(orders[key]) != 0 {
+		t.Fatal("confirming order still matchable")
+	}
+
+	// 同一地址存在两个等待中的订单时无法判断归属，不做累计
+	b1 := newTestOrder(t, "b1", "TAddrB", "10")
+	b2 := newTestOrder(t, "b2", "TAddrB", "20")
+	key = "TAddrB" + string(model.UsdtTrc20)
+	orders = map[string][]model.Order{key: {b1, b2}}
+	if orderTransferAccumulate(orders, key, newTestTransfer("TAddrB", "0x3", "5")) {
+		t.Fatal("ambiguous payment accumulated")
+	}
+	var num int64
+	model.Db.Model(&model.OrderPayment{}).Where("trade_id in ?", []string{"b1", "b2"}).Count(&num)
+	if num != 0 {
+		t.Fatal("ambiguous payment recorded")
+	}
+}
+
+func TestOrderTransferTolerance(t *testing.T) {
+	setupTestDb(t, &model.Conf{}, &model.Order{}, &model.OrderEvent{}, &model.OrderPayment{}, &model.NotifyOutbox{})
+	model.SetK(model.PaymentTolerance, "usdt.trc20=1")
+	model.SetK(model.PaymentMatchMode, string(model.Classic))
+	model.RefreshC()
+	t.Cleanup(func() {
+		model.SetK(model.PaymentTolerance, "")
+		model.SetK(model.PaymentMatchMode, string(model.Classic))
+		model.RefreshC()
+	})
+
+	var key = "TAddrT" + string(model.UsdtTrc20)
+	var cases = []struct {
+		amount string
+		match  bool
+		status int
+	}{
+		{"9.9", true, model.OrderStatusUnderpaid}, // 恰好在误差下限
+		{"9.899999", false, 0},                    // 低于误差下限 1 个最小单位
+		{"10.1", true, model.OrderStatusOverpaid}, // 恰好在误差上限
+		{"10.100001", false, 0},                   // 超出误差上限
+	}
+
+	for i, c := range cases {
+		o := newTestOrder(t, "t"+string(rune('a'+i)), "TAddrT", "10")
+		var orders = map[string][]model.Order{key: {o}}
+		if got := orderTransferTolerance(orders, key, newTestTransfer("TAddrT", "0xt"+c.amount, c.amount)); got != c.match {
+			t.Errorf("%s: matched = %t, want %t", c.amount, got, c.match)
+		}
+
+		model.Db.Where("trade_id = ?", o.TradeId).First(&o)
+		if !c.match {
+			if o.Status != model.OrderStatusWaiting {
+				t.Errorf("%s: status = %d, want waiting", c.amount, o.Status)
+			}
+
+			continue
+		}
+		if o.Status != model.OrderStatusConfirming || o.PaidAmount != c.amount {
+			t.Errorf("%s: status = %d paid = %s", c.amount, o.Status, o.PaidAmount)
+		}
+		if got := settleStatus(o); got != c.status {
+			t.Errorf("%s: settle = %d, want %d", c.amount, got, c.status)
+		}
+	}
+}
+
+func TestSettleStatus(t *testing.T) {
+	setupTestDb(t, &model.Conf{})
+	model.SetK(model.PaymentMatchMode, string(model.Classic))
+	model.RefreshC()
+
+	var cases = []struct {
+		paid   string
+		locked bool
+		want   int
+	}{
+		{"10", false, model.OrderStatusSuccess},
+		{"9.9", false, model.OrderStatusUnderpaid},
+		{"9.999999", false, model.OrderStatusUnderpaid},
+		{"10.000001", false, model.OrderStatusOverpaid},
+		{"12", true, model.OrderStatusSuccess}, // 独占地址按实收数额结算
+		{"0", false, model.OrderStatusSuccess}, // 未记录实收数额的旧订单
+	}
+
+	for _, c := range cases {
+		o := model.Order{TradeType: model.UsdtTrc20, Amount: "10", PaidAmount: c.paid, AddressLocked: c.locked}
+		if got := settleStatus(o); got != c.want {
+			t.Errorf("settleStatus(%s, locked=%t) = %d, want %d", c.paid, c.locked, got, c.want)
+		}
+	}
+}
@@ -16,7 +16,6 @@ import (
 	"github.com/smallnest/chanx"
 	"github.com/spf13/cast"
 	"github.com/v03413/bepusdt/app/conf"
-	blockapi "github.com/v03413/bepusdt/app/core"
 	"github.com/v03413/bepusdt/app/log"
 	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
@@ -29,8 +28,6 @@ import (
 var gasFreeUsdtTokenAddress = []byte{0xa6, 0x14, 0xf8, 0x03, 0xb6, 0xfd, 0x78, 0x09, 0x86, 0xa4, 0x2c, 0x78, 0xec, 0x9c, 0x7f, 0x77, 0xe6, 0xde, 0xd1, 0x3c}
 var gasFreeOwnerAddress = []byte{0x41, 0x3b, 0x41, 0x50, 0x50, 0xb1, 0xe7, 0x9e, 0x38, 0x50, 0x7c, 0xb6, 0xe4, 0x8d, 0xac, 0xc2, 0x27, 0xaf, 0xfd, 0xd5, 0x0c}
 var gasFreeContractAddress = []byte{0x41, 0x39, 0xdd, 0x12, 0xa5, 0x4e, 0x2b, 0xab, 0x7c, 0x82, 0xaa, 0x14, 0xa1, 0xe1, 0x58, 0xb3, 0x42, 0x63, 0xd2, 0xd5, 0x10}
-var usdtTrc20ContractAddress = []byte{0x41, 0xa6, 0x14, 0xf8, 0x03, 0xb6, 0xfd, 0x78, 0x09, 0x86, 0xa4, 0x2c, 0x78, 0xec, 0x9c, 0x7f, 0x77, 0xe6, 0xde, 0xd1, 0x3c}
-var usdcTrc20ContractAddress = []byte{0x41, 0x34, 0x87, 0xb6, 0x3d, 0x30, 0xb5, 0xb2, 0xc8, 0x7f, 0xb7, 0xff, 0xa8, 0xbc, 0xfa, 0xde, 0x38, 0xea, 0xac, 0x1a, 0xbe}
 
 type tron struct {
 	lastBlockNum         int
@@ -71,7 +68,8 @@ func (t *tron) syncBlocksForward(context.Context) {
 		return
 	}
 
-	conn, err := t.client()
+	var url = model.Endpoint(conf.Tron)
+	conn, err := t.clientFor(url)
 	if err != nil {
 		log.Task.Error("grpc.NewClient", err)
 
@@ -83,12 +81,17 @@ func (t *tron) syncBlocksForward(context.Context) {
 	defer cancel()
 
 	if err1 != nil {
+		conf.RecordFailure(conf.Tron, url)
 		log.Task.Warn("GetNowBlock2 超时：", err1)
 
 		return
 	}
 
 	var now = int(block.BlockHeader.RawData.Number)
+	var cursor = getCursor(conf.Tron)
+	if t.lastBlockNum == 0 { // 启动后从持久化游标继续扫描
+		t.lastBlockNum = int(cursor.Resume())
+	}
 
 	// 区块高度变化过大，强制丢块重扫
 	if now-t.lastBlockNum > cast.ToInt(model.GetC(model.BlockHeightMaxDiff)) {
@@ -103,7 +106,7 @@ func (t *tron) syncBlocksForward(context.Context) {
 
 	// 待扫描区块入列
 	for n := t.lastBlockNum + 1; n <= now; n++ {
-
+		cursor.Push(int64(n), int64(n))
 		t.blockScanQueue.In <- n
 	}
 
@@ -120,7 +123,7 @@ func (t *tron) lookbackBlocks(ctx context.Context) {
 		return
 	}
 
-	start, end := blockapi.New().GetBoundaryHeights(startAt, endAt, conf.Tron)
+	start, end := getBoundaryHeights(conf.Tron, t, startAt, endAt)
 	for i := int(start); i <= int(end); i++ {
 		select {
 		case <-ctx.Done():
@@ -135,6 +138,38 @@ func (t *tron) lookbackBlocks(ctx context.Context) {
 	}
 }
 
+func (t *tron) latestHeight(ctx context.Context) (int64, error) {
+	conn, err := t.client()
+	if err != nil {
+
+		return 0, err
+	}
+
+	block, err := api.NewWalletClient(conn).GetNowBlock2(ctx, nil)
+	if err != nil {
+
+		return 0, err
+	}
+
+	return block.GetBlockHeader().GetRawData().GetNumber(), nil
+}
+
+func (t *tron) blockTime(ctx context.Context, height int64) (int64, error) {
+	conn, err := t.client()
+	if err != nil {
+
+		return 0, err
+	}
+
+	block, err := api.NewWalletClient(conn).GetBlockByNum2(ctx, &api.NumberMessage{Num: height})
+	if err != nil {
+
+		return 0, err
+	}
+
+	return block.GetBlockHeader().GetRawData().GetTimestamp() / 1000, nil
+}
+
 func (t *tron) blockDispatch(ctx context.Context) {
 	p, err := ants.NewPoolWithFunc(2, t.blockParse)
 	if err != nil {
@@ -165,9 +200,10 @@ func (t *tron) blockDispatch(ctx context.Context) {
 func (t *tron) blockParse(n any) {
 	var num = n.(int)
 
+	var url = model.Endpoint(conf.Tron)
 	var conn *grpc.ClientConn
 	var err error
-	if conn, err = t.client(); err != nil {
+	if conn, err = t.clientFor(url); err != nil {
 		log.Task.Error("grpc.NewClient", err)
 
 		return
@@ -177,15 +213,16 @@ func (t *tron) blockParse(n any) {
 	bok, err2 := api.NewWalletClient(conn).GetBlockByNum2(ctx, &api.NumberMessage{Num: int64(num)})
 	cancel()
 	if err2 != nil {
-		conf.RecordFailure(conf.Tron)
+		conf.RecordFailure(conf.Tron, url)
 		t.scheduleBlockRetry(num, 0)
 		log.Task.Warn("GetBlockByNum2 ", err2)
 
 		return
 	}
 
-	conf.RecordSuccess(conf.Tron, cast.ToString(num))
+	conf.RecordSuccess(conf.Tron, url, cast.ToString(num))
 	t.resetBlockRetry(num)
+	defer getCursor(conf.Tron).Done(int64(num), int64(num))
 
 	var resources = make([]resource, 0)
 	var transfers = make([]transfer, 0)
@@ -292,12 +329,8 @@ func (t *tron) blockParse(n any) {
 				}
 
 				// trc20 合约解析
-				var tradeType model.TradeType = "None"
-				if bytes.Equal(foo.GetContractAddress(), usdtTrc20ContractAddress) {
-					tradeType = model.UsdtTrc20
-				} else if bytes.Equal(foo.GetContractAddress(), usdcTrc20ContractAddress) {
-					tradeType = model.UsdcTrc20
-				} else {
+				tradeType, ok := model.GetContractTrade(conf.Tron, t.base58CheckEncode(foo.GetContractAddress()))
+				if !ok {
 					continue
 				}
 
@@ -397,7 +430,7 @@ func (t *tron) gasFreePermitTransfer(data []byte) (string, string, *big.Int) {
 }
 
 func (t *tron) tradeConfirmHandle(ctx context.Context) {
-	var orders = getConfirmingOrders([]model.TradeType{model.TronTrx, model.UsdtTrc20, model.UsdcTrc20})
+	var orders = getConfirmingOrders(model.GetNetworkTrades(conf.Tron))
 
 	var wg sync.WaitGroup
 
@@ -483,7 +516,7 @@ func (t *tron) syncBreak() bool {
 		return false
 	}
 
-	trade := []model.TradeType{model.TronTrx, model.UsdtTrc20, model.UsdcTrc20}
+	trade := model.GetNetworkTrades(conf.Tron)
 	if hasLookbackOrders(trade) {
 
 		return false
@@ -561,7 +594,11 @@ func tronRetryDelay(attempt int) time.Duration {
 }
 
 func (t *tron) client() (*grpc.ClientConn, error) {
-	var endpoint = model.Endpoint(conf.Tron)
+
+	return t.clientFor(model.Endpoint(conf.Tron))
+}
+
+func (t *tron) clientFor(endpoint string) (*grpc.ClientConn, error) {
 
 	t.connMu.RLock()
 	if c, ok := t.conn[endpoint]; ok {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallnest/chanx"
 	"github.com/v03413/bepusdt/app/conf"
+	"github.com/v03413/bepusdt/app/model"
 	"github.com/v03413/bepusdt/app/utils"
 )
 
@@ -18,12 +19,18 @@ func xlayerInit() {
 			RollDelayOffset: 3,
 			ConfirmedOffset: 12,
 		},
+		Native: evmNative{
+			Parse:     true,
+			Decimal:   conf.XlayerOkbDecimals,
+			TradeType: model.XlayerOkb,
+		},
 		Client:         utils.NewHttpClient(),
 		blockScanQueue: chanx.NewUnboundedChan[evmBlock](ctx, 30),
 	}
 
 	Register(Task{Callback: xlayer.blockDispatch})
 	Register(Task{Callback: xlayer.syncBlocksForward, Duration: time.Second * 3})
+	Register(Task{Callback: xlayer.wsWatch, Duration: time.Second * 5})
 	Register(Task{Callback: xlayer.tradeConfirmHandle, Duration: time.Second * 5})
 	Register(Task{Callback: xlayer.lookbackBlocks, Duration: time.Second * 15})
 }
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"golang.org/x/crypto/sha3"
+)
+
+// 参考文档
+//  - https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki
+//  - https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+
+// 只做公钥推导（watch-only），不涉及私钥，secp256k1 运算直接使用 math/big 实现
+
+var (
+	secpP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secpN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secpGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secpGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+)
+
+var xpubVersions = [][]byte{
+	{0x04, 0x88, 0xb2, 0x1e}, // xpub 主网
+	{0x04, 0x35, 0x87, 0xcf}, // tpub 测试网
+}
+
+// ExtendedPubKey BIP32 扩展公钥
+type ExtendedPubKey struct {
+	x, y      *big.Int
+	chainCode []byte
+	depth     uint8
+}
+
+// ParseXpub 解析 BIP32 扩展公钥（xpub/tpub）
+func ParseXpub(xpub string) (ExtendedPubKey, error) {
+	data := base58.Decode(xpub)
+	if len(data) != 82 {
+
+		return ExtendedPubKey{}, errors.New("扩展公钥长度错误")
+	}
+
+	payload, checksum := data[:78], data[78:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+
+		return ExtendedPubKey{}, errors.New("扩展公钥校验和错误")
+	}
+
+	var known bool
+	for _, v := range xpubVersions {
+		if bytes.Equal(payload[:4], v) {
+			known = true
+		}
+	}
+	if !known {
+
+		return ExtendedPubKey{}, errors.New("仅支持 xpub/tpub 格式的扩展公钥")
+	}
+
+	x, y, err := decompressPubKey(payload[45:78])
+	if err != nil {
+
+		return ExtendedPubKey{}, err
+	}
+
+	return ExtendedPubKey{x: x, y: y, chainCode: payload[13:45], depth: payload[4]}, nil
+}
+
+// Child 非强化子公钥推导 CKDpub
+func (k ExtendedPubKey) Child(index uint32) (ExtendedPubKey, error) {
+	if index >= 0x80000000 {
+
+		return ExtendedPubKey{}, errors.New("扩展公钥无法推导强化路径")
+	}
+
+	data := make([]byte, 37)
+	copy(data, compressPubKey(k.x, k.y))
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(secpN) >= 0 {
+
+		return ExtendedPubKey{}, errors.New("无效的推导索引")
+	}
+
+	px, py := secpScalarBaseMult(il)
+	x, y := secpAdd(px, py, k.x, k.y)
+	if x == nil {
+
+		return ExtendedPubKey{}, errors.New("无效的推导索引")
+	}
+
+	return ExtendedPubKey{x: x, y: y, chainCode: sum[32:], depth: k.depth + 1}, nil
+}
+
+// Derive 按路径依次推导，例如 0/5 => Child(0).Child(5)
+func (k ExtendedPubKey) Derive(path ...uint32) (ExtendedPubKey, error) {
+	var err error
+	for _, i := range path {
+		if k, err = k.Child(i); err != nil {
+
+			return ExtendedPubKey{}, err
+		}
+	}
+
+	return k, nil
+}
+
+// EvmAddress 公钥对应的 EVM 地址（小写）
+func (k ExtendedPubKey) EvmAddress() string {
+
+	return "0x" + hex.EncodeToString(k.pubKeyHash())
+}
+
+// TronAddress 公钥对应的 Tron 地址
+func (k ExtendedPubKey) TronAddress() string {
+
+	return base58.CheckEncode(k.pubKeyHash(), 0x41)
+}
+
+// pubKeyHash Keccak256(未压缩公钥 X||Y) 后 20 字节
+func (k ExtendedPubKey) pubKeyHash() []byte {
+	buf := make([]byte, 64)
+	k.x.FillBytes(buf[:32])
+	k.y.FillBytes(buf[32:])
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(buf)
+
+	return h.Sum(nil)[12:]
+}
+
+func compressPubKey(x, y *big.Int) []byte {
+	buf := make([]byte, 33)
+	buf[0] = 0x02 + byte(y.Bit(0))
+	x.FillBytes(buf[1:])
+
+	return buf
+}
+
+func decompressPubKey(data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != 33 || (data[0] != 0x02 && data[0] != 0x03) {
+
+		return nil, nil, errors.New("公钥格式错误")
+	}
+
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(secpP) >= 0 {
+
+		return nil, nil, errors.New("公钥格式错误")
+	}
+
+	// y² = x³ + 7，p ≡ 3 (mod 4) 时平方根为 (y²)^((p+1)/4)
+	y2 := new(big.Int).Exp(x, big.NewInt(3), secpP)
+	y2.Add(y2, big.NewInt(7)).Mod(y2, secpP)
+
+	exp := new(big.Int).Add(secpP, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(y2, exp, secpP)
+	if new(big.Int).Exp(y, big.NewInt(2), secpP).Cmp(y2) != 0 {
+
+		return nil, nil, errors.New("公钥不在曲线上")
+	}
+
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(secpP, y)
+	}
+
+	return x, y, nil
+}
+
+// secpAdd 仿射坐标点加，nil 表示无穷远点
+func secpAdd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+
+		return x2, y2
+	}
+	if x2 == nil {
+
+		return x1, y1
+	}
+
+	var lambda *big.Int
+	if x1.Cmp(x2) == 0 {
+		if new(big.Int).Add(y1, y2).Mod(new(big.Int).Add(y1, y2), secpP).Sign() == 0 {
+
+			return nil, nil
+		}
+
+		// λ = 3x² / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(y1, 1)
+		lambda = num.Mul(num, den.ModInverse(den, secpP))
+	} else {
+		// λ = (y2 - y1) / (x2 - x1)
+		num := new(big.Int).Sub(y2, y1)
+		den := new(big.Int).Sub(x2, x1)
+		den.Mod(den, secpP)
+		lambda = num.Mul(num, den.ModInverse(den, secpP))
+	}
+	lambda.Mod(lambda, secpP)
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1).Sub(x3, x2).Mod(x3, secpP)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda).Sub(y3, y1).Mod(y3, secpP)
+
+	return x3, y3
+}
+
+func secpScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	var qx, qy = secpGx, secpGy
+	for i := 0; i < k.BitLen(); i++ {
+		if k.Bit(i) == 1 {
+			rx, ry = secpAdd(rx, ry, qx, qy)
+		}
+
+		qx, qy = secpAdd(qx, qy, qx, qy)
+	}
+
+	return rx, ry
+}
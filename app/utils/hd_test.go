@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+// BIP32 测试向量 1：m/0H => m/0H/1
+func TestExtendedPubKeyChild(t *testing.T) {
+	parent, err := ParseXpub("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child, err := parent.Child(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ParseXpub("xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if child.x.Cmp(want.x) != 0 || child.y.Cmp(want.y) != 0 || string(child.chainCode) != string(want.chainCode) {
+		t.Fatal("child key mismatch")
+	}
+	if child.EvmAddress() != want.EvmAddress() {
+		t.Fatal("evm address mismatch")
+	}
+
+	if _, err = parent.Child(0x80000000); err == nil {
+		t.Fatal("hardened derivation should fail")
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ParseIpAllowList 解析以逗号、空白或换行分隔的 IP / CIDR 列表，单个 IP 视为 /32 或 /128
+func ParseIpAllowList(raw string) ([]netip.Prefix, error) {
+	var result = make([]netip.Prefix, 0)
+	for _, item := range strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		if strings.Contains(item, "/") {
+			p, err := netip.ParsePrefix(item)
+			if err != nil {
+
+				return nil, fmt.Errorf("无效的 CIDR：%s", item)
+			}
+
+			result = append(result, p.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+
+			return nil, fmt.Errorf("无效的 IP：%s", item)
+		}
+
+		addr = addr.Unmap()
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return result, nil
+}
+
+// IpAllowed 判断 IP 是否命中白名单，白名单为空表示不限制
+func IpAllowed(ip string, list []netip.Prefix) bool {
+	if len(list) == 0 {
+
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, p := range list {
+		if p.Contains(addr) {
+
+			return true
+		}
+	}
+
+	return false
+}
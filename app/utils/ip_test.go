@@ -0,0 +1,33 @@
+package utils
+
+import "testing"
+
+func TestIpAllowed(t *testing.T) {
+	list, err := ParseIpAllowList("10.0.0.0/8, 192.168.1.10\n2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ip, want := range map[string]bool{
+		"10.1.2.3":           true,
+		"192.168.1.10":       true,
+		"192.168.1.11":       false,
+		"::ffff:10.9.9.9":    true,
+		"2001:db8::1":        true,
+		"2001:db9::1":        false,
+		"not-an-ip":          false,
+		"172.16.0.1":         false,
+		"::ffff:192.168.1.1": false,
+	} {
+		if got := IpAllowed(ip, list); got != want {
+			t.Errorf("IpAllowed(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	if !IpAllowed("1.2.3.4", nil) {
+		t.Fatal("empty list should allow all")
+	}
+	if _, err := ParseIpAllowList("10.0.0.0/33"); err == nil {
+		t.Fatal("invalid cidr should be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/v03413/go-cache"
+)
+
+// v2 签名：HMAC-SHA256(secret, timestamp + "\n" + nonce + "\n" + body)，签名与时间戳、随机串通过请求头传递
+
+const (
+	HeaderSignature = "X-Bepusdt-Signature"
+	HeaderTimestamp = "X-Bepusdt-Timestamp"
+	HeaderNonce     = "X-Bepusdt-Nonce"
+
+	SignReplayWindow = time.Minute * 5 // 时间戳允许的偏差，同时也是随机串的去重时长
+)
+
+var nonceMutex sync.Mutex
+
+// HmacSha256 返回小写十六进制的 HMAC-SHA256
+func HmacSha256(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func HmacSign(secret, timestamp, nonce string, body []byte) string {
+
+	return HmacSha256(secret, timestamp+"\n"+nonce+"\n"+string(body))
+}
+
+// SetSignHeaders 为出站请求写入 v2 签名请求头
+func SetSignHeaders(h http.Header, secret string, body []byte) {
+	var ts = strconv.FormatInt(time.Now().Unix(), 10)
+	var buf = make([]byte, 8)
+	_, _ = rand.Read(buf)
+	var nonce = hex.EncodeToString(buf)
+
+	h.Set(HeaderTimestamp, ts)
+	h.Set(HeaderNonce, nonce)
+	h.Set(HeaderSignature, HmacSign(secret, ts, nonce, body))
+}
+
+// VerifySignHeaders 校验入站请求的 v2 签名请求头，包含时间窗口与随机串防重放
+func VerifySignHeaders(h http.Header, secret string, body []byte) error {
+	var ts, nonce, sign = h.Get(HeaderTimestamp), h.Get(HeaderNonce), h.Get(HeaderSignature)
+	if ts == "" || nonce == "" || sign == "" {
+
+		return errors.New("签名请求头缺失")
+	}
+
+	if !hmac.Equal([]byte(HmacSign(secret, ts, nonce, body)), []byte(sign)) {
+
+		return errors.New("签名错误")
+	}
+
+	return CheckReplay(ts, nonce)
+}
+
+// CheckReplay 校验时间戳是否在允许窗口内，且随机串在窗口内未被使用过
+func CheckReplay(timestamp, nonce string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+
+		return errors.New("时间戳格式错误")
+	}
+
+	var diff = time.Since(time.Unix(ts, 0))
+	if diff > SignReplayWindow || diff < -SignReplayWindow {
+
+		return errors.New("请求已过期，请检查服务器时间")
+	}
+
+	if len(nonce) < 8 || len(nonce) > 64 {
+
+		return errors.New("随机串长度必须为 8-64 位")
+	}
+
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	var key = "sign_nonce_" + nonce
+	if _, ok := cache.Get(key); ok {
+
+		return errors.New("重复的请求")
+	}
+
+	cache.Set(key, true, SignReplayWindow*2)
+
+	return nil
+}
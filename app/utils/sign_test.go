@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignHeaders(t *testing.T) {
+	var body = []byte(`{"order_id":"1","amount":1}`)
+	var h = http.Header{}
+
+	SetSignHeaders(h, "secret", body)
+	if err := VerifySignHeaders(h, "secret", body); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignHeaders(h, "secret", body); err == nil {
+		t.Fatal("replayed nonce should be rejected")
+	}
+
+	SetSignHeaders(h, "secret", body)
+	if err := VerifySignHeaders(h, "other", body); err == nil {
+		t.Fatal("wrong secret should be rejected")
+	}
+	if err := VerifySignHeaders(h, "secret", []byte(`{}`)); err == nil {
+		t.Fatal("tampered body should be rejected")
+	}
+
+	var ts = strconv.FormatInt(time.Now().Add(-SignReplayWindow*2).Unix(), 10)
+	h.Set(HeaderTimestamp, ts)
+	h.Set(HeaderNonce, "expired-nonce")
+	h.Set(HeaderSignature, HmacSign("secret", ts, "expired-nonce", body))
+	if err := VerifySignHeaders(h, "secret", body); err == nil {
+		t.Fatal("expired timestamp should be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TotpPeriod = 30 // 时间步长，单位秒
+	TotpDigits = 6
+	totpSkew   = 1 // 前后各允许一个时间步长的时钟偏差
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTotpSecret 生成 160 位随机密钥，Base32 编码
+func NewTotpSecret() string {
+	var b = make([]byte, 20)
+	_, _ = rand.Read(b)
+
+	return totpEncoding.EncodeToString(b)
+}
+
+// TotpCode RFC 6238 动态验证码，HMAC-SHA1、30 秒步长、6 位数字
+func TotpCode(secret string, counter uint64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "=")))
+	if err != nil {
+
+		return "", err
+	}
+
+	var msg = make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TotpDigits, value%1000000), nil
+}
+
+// VerifyTotp 校验验证码，返回匹配的时间步，调用方可据此拒绝同一验证码重复使用
+func VerifyTotp(secret, code string, at time.Time) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TotpDigits {
+
+		return 0, false
+	}
+
+	var step = uint64(at.Unix() / TotpPeriod)
+	for i := -totpSkew; i <= totpSkew; i++ {
+		var counter = step + uint64(i)
+		expect, err := TotpCode(secret, counter)
+		if err != nil {
+
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expect), []byte(code)) == 1 {
+
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// TotpUri 认证器 App 扫码绑定使用的 otpauth 地址
+func TotpUri(issuer, account, secret string) string {
+	var v = url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("period", fmt.Sprint(TotpPeriod))
+	v.Set("digits", fmt.Sprint(TotpDigits))
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(account), v.Encode())
+}
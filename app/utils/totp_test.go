@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 测试向量（SHA1，截取 6 位）
+func TestTotpCode(t *testing.T) {
+	var secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	var cases = map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for ts, want := range cases {
+		got, err := TotpCode(secret, uint64(ts/TotpPeriod))
+		if err != nil || got != want {
+			t.Errorf("TotpCode(%d) = %s, %v, want %s", ts, got, err, want)
+		}
+	}
+
+	code, _ := TotpCode(secret, uint64(time.Now().Unix()/TotpPeriod)-1)
+	if _, ok := VerifyTotp(secret, code, time.Now()); !ok {
+		t.Fatal("previous step should be accepted")
+	}
+	if _, ok := VerifyTotp(secret, "000000x", time.Now()); ok {
+		t.Fatal("malformed code should be rejected")
+	}
+}
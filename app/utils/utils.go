@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"math"
 	"math/big"
 	"net/http"
 	"net/url"
@@ -13,7 +12,6 @@ import (
 	"regexp"
 	"sort"
 	"strings"
-	"time"
 	"unicode"
 
 	"github.com/btcsuite/btcd/btcutil/base58"
@@ -164,11 +162,6 @@ func MaskHash(hash string) string {
 	return hash[:6] + " ***** " + hash[len(hash)-8:]
 }
 
-func CalcNextNotifyTime(base time.Time, num int) time.Time {
-
-	return base.Add(time.Minute * time.Duration(math.Pow(2, float64(num))))
-}
-
 func HexStr2Int(str string) *big.Int {
 	var n = new(big.Int)
 	var val = strings.TrimLeft(strings.TrimPrefix(str, "0x"), "0")
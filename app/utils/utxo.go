@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// utxoParams UTXO 链地址版本参数
+type utxoParams struct {
+	PubKeyHash byte   // P2PKH 地址版本
+	ScriptHash byte   // P2SH 地址版本
+	Bech32Hrp  string // 隔离见证地址前缀
+}
+
+// utxoChainParams [网络][链类型] => 地址参数；chaincfg 参数体积较大，这里只保留地址校验需要的部分
+var utxoChainParams = map[string]map[string]utxoParams{
+	"bitcoin": {
+		"mainnet": {PubKeyHash: 0x00, ScriptHash: 0x05, Bech32Hrp: "bc"},
+		"testnet": {PubKeyHash: 0x6f, ScriptHash: 0xc4, Bech32Hrp: "tb"},
+		"regtest": {PubKeyHash: 0x6f, ScriptHash: 0xc4, Bech32Hrp: "bcrt"},
+	},
+	"litecoin": {
+		"mainnet": {PubKeyHash: 0x30, ScriptHash: 0x32, Bech32Hrp: "ltc"},
+		"testnet": {PubKeyHash: 0x6f, ScriptHash: 0x3a, Bech32Hrp: "tltc"},
+		"regtest": {PubKeyHash: 0x6f, ScriptHash: 0x3a, Bech32Hrp: "rltc"},
+	},
+}
+
+// NormalizeUtxoAddress 校验 UTXO 链地址并返回规范格式（隔离见证地址统一小写）
+func NormalizeUtxoAddress(network, chain, address string) (string, bool) {
+	params, ok := utxoChainParams[network][chain]
+	if !ok {
+
+		return "", false
+	}
+
+	address = strings.TrimSpace(address)
+	if data, ver, err := base58.CheckDecode(address); err == nil {
+		if len(data) == 20 && (ver == params.PubKeyHash || ver == params.ScriptHash) {
+
+			return address, true
+		}
+
+		return "", false
+	}
+
+	hrp, data, version, err := bech32.DecodeGeneric(address)
+	if err != nil || hrp != params.Bech32Hrp || len(data) < 1 {
+
+		return "", false
+	}
+
+	witnessVer := data[0]
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil || witnessVer > 16 || len(program) < 2 || len(program) > 40 {
+
+		return "", false
+	}
+
+	// BIP-350：v0 使用 bech32，v1 及以上使用 bech32m
+	if witnessVer == 0 && (version != bech32.Version0 || (len(program) != 20 && len(program) != 32)) {
+
+		return "", false
+	}
+	if witnessVer != 0 && version != bech32.VersionM {
+
+		return "", false
+	}
+
+	return strings.ToLower(address), true
+}
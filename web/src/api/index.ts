@@ -1,6 +1,7 @@
 import axios from "axios";
 
-import { Message } from "@arco-design/web-vue";
+import { Message, Modal, Input } from "@arco-design/web-vue";
+import { h, ref } from "vue";
 import { useUserInfoStore } from "@/store/modules/user-info";
 import pinia from "@/store/index";
 
@@ -13,6 +14,32 @@ declare module "axios" {
 // 创建axios实例
 const service = axios.create();
 
+// 敏感操作需要动态验证码时弹窗输入，确认后携带验证码重新请求
+const promptTotp = (config: any) => {
+  const code = ref("");
+
+  return new Promise((resolve, reject) => {
+    Modal.open({
+      title: "两步验证",
+      content: () =>
+        h("div", [
+          h("p", { style: "margin-bottom: 12px" }, "该操作需要验证身份，请输入认证器中的动态验证码或恢复码"),
+          h(Input, {
+            modelValue: code.value,
+            "onUpdate:modelValue": (v: string) => (code.value = v),
+            placeholder: "6 位动态验证码",
+            allowClear: true
+          })
+        ]),
+      onOk: () => {
+        config.headers["X-Totp-Code"] = code.value.trim();
+        service(config).then(resolve, reject);
+      },
+      onCancel: () => reject(new Error("已取消两步验证"))
+    });
+  });
+};
+
 // 请求拦截器
 service.interceptors.request.use(
   function (config: any) {
@@ -43,7 +70,24 @@ service.interceptors.response.use(
       return Promise.reject(response.data);
     }
 
+    // 文件下载直接返回原始响应；接口报错时返回的仍是 JSON
+    if (response.config.responseType === "blob") {
+      if (response.data.type && response.data.type.includes("application/json")) {
+        return response.data.text().then((text: string) => {
+          const err = JSON.parse(text);
+          Message.error(err.msg);
+
+          return Promise.reject(err);
+        });
+      }
+
+      return Promise.resolve(response);
+    }
+
     let res = response.data;
+    if (res.code == 428) {
+      return promptTotp(response.config);
+    }
     if (res.code == 400) {
       Message.error(res.msg);
 
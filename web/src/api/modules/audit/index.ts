@@ -0,0 +1,18 @@
+import axios from "@/api";
+
+export const getAuditListAPI = (data: any) => {
+  return axios({
+    url: "/api/audit/list",
+    method: "post",
+    data
+  });
+};
+
+export const exportAuditAPI = (data: any) => {
+  return axios({
+    url: "/api/audit/export",
+    method: "post",
+    responseType: "blob",
+    data
+  });
+};
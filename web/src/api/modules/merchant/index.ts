@@ -0,0 +1,41 @@
+import axios from "@/api";
+
+export const getMerchantListAPI = (data: any) => {
+  return axios({
+    url: "/api/merchant/list",
+    method: "post",
+    data
+  });
+};
+
+export const delMerchantAPI = (data: any) => {
+  return axios({
+    url: "/api/merchant/del",
+    method: "post",
+    data
+  });
+};
+
+export const addMerchantAPI = (data: any) => {
+  return axios({
+    url: "/api/merchant/add",
+    method: "post",
+    data
+  });
+};
+
+export const modMerchantAPI = (data: any) => {
+  return axios({
+    url: "/api/merchant/mod",
+    method: "post",
+    data
+  });
+};
+
+export const resetMerchantSecretAPI = (data: any) => {
+  return axios({
+    url: "/api/merchant/reset_secret",
+    method: "post",
+    data
+  });
+};
@@ -0,0 +1,25 @@
+import axios from "@/api";
+
+export const getNotifyListAPI = (data: any) => {
+  return axios({
+    url: "/api/notify/list",
+    method: "post",
+    data
+  });
+};
+
+export const redriveNotifyAPI = (data: any) => {
+  return axios({
+    url: "/api/notify/redrive",
+    method: "post",
+    data
+  });
+};
+
+export const delNotifyAPI = (data: any) => {
+  return axios({
+    url: "/api/notify/del",
+    method: "post",
+    data
+  });
+};
@@ -55,3 +55,19 @@ export const manualNotifyAPI = (data: any) => {
     data
   });
 };
+
+export const notifyAttemptsAPI = (data: any) => {
+  return axios({
+    url: "/api/order/notify_attempts",
+    method: "post",
+    data
+  });
+};
+
+export const notifyReplayAPI = (data: any) => {
+  return axios({
+    url: "/api/order/notify_replay",
+    method: "post",
+    data
+  });
+};
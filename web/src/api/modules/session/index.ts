@@ -0,0 +1,23 @@
+import axios from "@/api";
+
+export const getSessionListAPI = () => {
+  return axios({
+    url: "/api/session/list",
+    method: "post"
+  });
+};
+
+export const revokeSessionAPI = (data: any) => {
+  return axios({
+    url: "/api/session/revoke",
+    method: "post",
+    data
+  });
+};
+
+export const revokeAllSessionAPI = () => {
+  return axios({
+    url: "/api/session/revoke_all",
+    method: "post"
+  });
+};
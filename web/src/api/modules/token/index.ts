@@ -0,0 +1,33 @@
+import axios from "@/api";
+
+export const getTokenListAPI = (data: any) => {
+  return axios({
+    url: "/api/token/list",
+    method: "post",
+    data
+  });
+};
+
+export const delTokenAPI = (data: any) => {
+  return axios({
+    url: "/api/token/del",
+    method: "post",
+    data
+  });
+};
+
+export const addTokenAPI = (data: any) => {
+  return axios({
+    url: "/api/token/add",
+    method: "post",
+    data
+  });
+};
+
+export const modTokenAPI = (data: any) => {
+  return axios({
+    url: "/api/token/mod",
+    method: "post",
+    data
+  });
+};
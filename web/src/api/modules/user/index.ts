@@ -35,3 +35,60 @@ export const setPasswordAPI = (data: any) => {
     data
   });
 };
+
+// 两步验证
+export const totpSetupAPI = () => {
+  return axios({
+    url: "/api/auth/totp/setup",
+    method: "post"
+  });
+};
+
+export const totpEnableAPI = (data: any) => {
+  return axios({
+    url: "/api/auth/totp/enable",
+    method: "post",
+    data
+  });
+};
+
+export const totpDisableAPI = (data: any) => {
+  return axios({
+    url: "/api/auth/totp/disable",
+    method: "post",
+    data
+  });
+};
+
+// 账号管理
+export const getUserListAPI = (data: any) => {
+  return axios({
+    url: "/api/user/list",
+    method: "post",
+    data
+  });
+};
+
+export const addUserAPI = (data: any) => {
+  return axios({
+    url: "/api/user/add",
+    method: "post",
+    data
+  });
+};
+
+export const modUserAPI = (data: any) => {
+  return axios({
+    url: "/api/user/mod",
+    method: "post",
+    data
+  });
+};
+
+export const delUserAPI = (data: any) => {
+  return axios({
+    url: "/api/user/del",
+    method: "post",
+    data
+  });
+};
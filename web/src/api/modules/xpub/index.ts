@@ -0,0 +1,41 @@
+import axios from "@/api";
+
+export const getXpubListAPI = (data: any) => {
+  return axios({
+    url: "/api/xpub/list",
+    method: "post",
+    data
+  });
+};
+
+export const delXpubAPI = (data: any) => {
+  return axios({
+    url: "/api/xpub/del",
+    method: "post",
+    data
+  });
+};
+
+export const addXpubAPI = (data: any) => {
+  return axios({
+    url: "/api/xpub/add",
+    method: "post",
+    data
+  });
+};
+
+export const modXpubAPI = (data: any) => {
+  return axios({
+    url: "/api/xpub/mod",
+    method: "post",
+    data
+  });
+};
+
+export const getXpubAddressesAPI = (data: any) => {
+  return axios({
+    url: "/api/xpub/addresses",
+    method: "post",
+    data
+  });
+};
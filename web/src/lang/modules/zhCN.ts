@@ -56,6 +56,13 @@ export default {
     ["rate-syntax"]: "汇率配置",
     ["system-base"]: "基本设置",
     ["system-rpc"]: "区块节点",
+    ["system-token"]: "代币管理",
+    ["system-xpub"]: "HD 钱包",
+    ["system-merchant"]: "商户管理",
+    ["system-notify"]: "回调队列",
+    ["system-user"]: "账号管理",
+    ["system-session"]: "登录会话",
+    ["system-audit"]: "操作审计",
     ["create-order"]: "创建订单",
     ["github-api-doc"]: "说明文档",
     ["file"]: "文件管理",
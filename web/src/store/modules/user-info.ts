@@ -36,6 +36,14 @@ const userInfoStore = () => {
       trade_fiat.value = data.data.trade_fiat || [];
       trade_crypto.value = data.data.trade_crypto || [];
       admin_username.value = data.data.admin_username || "";
+      account.value.user = {
+        username: data.data.admin_username,
+        role_text: data.data.role_text,
+        totp_enabled: !!data.data.totp_enabled,
+        recovery_left: data.data.recovery_left || 0
+      };
+      account.value.roles = data.data.role ? [data.data.role] : [];
+      account.value.permissions = data.data.permissions || [];
     }
   }
 
@@ -8,6 +8,7 @@ export interface Detail {
   trade_type: string;
   rate: string;
   amount: string;
+  paid_amount?: string;
   money: number;
   address: string;
   from_address: string;
@@ -25,6 +26,7 @@ export interface Detail {
   created_at?: string;
   updated_at?: string;
   tx_url?: string;
+  events?: any[];
 }
 
 export const useOrderDetail = () => {
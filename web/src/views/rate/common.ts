@@ -17,7 +17,14 @@ export const getCryptoColor = (crypto: string): string => {
     TRX: "red",
     ETH: "purple",
     BNB: "orange",
-    GRAM: "#0088CC"
+    GRAM: "#0088CC",
+    POL: "#8247E5",
+    OKB: "#2D2D2D",
+    XPL: "#0F766E",
+    SOL: "#9945FF",
+    APT: "#06B6D4",
+    BTC: "#F7931A",
+    LTC: "#345D9D"
   };
   return colorMap[crypto] ?? "gray";
 };
@@ -5,6 +5,7 @@ interface List {
   status: number;
   createTime: string;
   trade_type?: string;
+  merchant_id?: number;
   remark?: string;
   other_notify?: number;
 }
@@ -22,6 +23,7 @@ interface AddForm {
   name: string;
   address: string;
   trade_type: string;
+  merchant_id: number;
   remark: string;
   other_notify: number;
 }
@@ -32,6 +34,7 @@ interface ModForm {
   status: number;
   address: string;
   trade_type: string;
+  merchant_id: number;
   remark: string;
   other_notify: number;
 }